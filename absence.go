@@ -0,0 +1,231 @@
+package nightrelcalc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ---------------- absences: who is away ---------------- */
+
+// Absence is a leave of a roster member given in the config, from the first
+// to the last day away (both included), in the server's time zone:
+//
+//	"absences": [{"from": "2026-10-19", "to": "2026-10-23", "what": "vacation"}]
+type Absence struct {
+	From string `json:"from"`
+	To   string `json:"to,omitempty"` // default From, one day
+	What string `json:"what,omitempty"`
+}
+
+func (a Absence) validate() error {
+	from, err := time.Parse(time.DateOnly, a.From)
+	if err != nil {
+		return fmt.Errorf("invalid absence date %q, expected YYYY-MM-DD", a.From)
+	}
+	if a.To == "" {
+		return nil
+	}
+	to, err := time.Parse(time.DateOnly, a.To)
+	if err != nil {
+		return fmt.Errorf("invalid absence date %q, expected YYYY-MM-DD", a.To)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("absence from %s ends before it starts", a.From)
+	}
+	return nil
+}
+
+// leave is a time someone is away, from an absence or their calendar.
+type leave struct {
+	Start, End time.Time
+	What       string
+}
+
+func (a Absence) leave() leave {
+	from, _ := time.ParseInLocation(time.DateOnly, a.From, time.Local)
+	to, _ := time.ParseInLocation(time.DateOnly, orDefault(a.To, a.From), time.Local)
+	return leave{Start: from, End: to.AddDate(0, 0, 1), What: orDefault(a.What, "away")}
+}
+
+// awayDuring is the first leave that overlaps from to to.
+func awayDuring(away []leave, from, to time.Time) (leave, bool) {
+	for _, l := range away {
+		if l.Start.Before(to) && from.Before(l.End) {
+			return l, true
+		}
+	}
+	return leave{}, false
+}
+
+// away is m's leave: their absences and the events of their absence
+// calendar, which is read at most every absenceRefresh.
+func (m RosterMember) away(ctx context.Context) ([]leave, error) {
+	var out []leave
+	for _, a := range m.Absences {
+		out = append(out, a.leave())
+	}
+	if m.AbsenceCalendar == "" {
+		return out, nil
+	}
+	cal, err := absenceCalendars.get(ctx, m.AbsenceCalendar, time.Now())
+	if err != nil {
+		return out, errors.New("absence calendar unavailable")
+	}
+	return append(out, cal...), nil
+}
+
+/* ---------------- absence calendars (ICS) ---------------- */
+
+// absenceRefresh is how long an absence calendar is used before it is read
+// again; absenceTimeout how long one read may take.
+const (
+	absenceRefresh = 15 * time.Minute
+	absenceTimeout = 10 * time.Second
+)
+
+// calendarCache holds the absence calendars read so far, by file or URL.
+type calendarCache struct {
+	mu    sync.Mutex
+	cache map[string]cachedCalendar
+}
+
+type cachedCalendar struct {
+	read  time.Time
+	leave []leave
+	err   error
+}
+
+var absenceCalendars = &calendarCache{cache: map[string]cachedCalendar{}}
+
+// get is the leave in the calendar at src, a file or http(s) URL, read
+// again once absenceRefresh has passed. A failed read is logged (its
+// error may hold the URL, which stays off the pages) and kept as long, so
+// a calendar that is down is not asked on every request. A read cut short
+// by ctx is not kept: it says nothing about the calendar.
+func (c *calendarCache) get(ctx context.Context, src string, now time.Time) ([]leave, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.cache[src]; ok && now.Sub(e.read) < absenceRefresh {
+		return e.leave, e.err
+	}
+	l, err := readAbsenceCalendar(ctx, src)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		log.Printf("absence calendar %s: %v", src, err)
+	}
+	c.cache[src] = cachedCalendar{read: now, leave: l, err: err}
+	return l, err
+}
+
+func readAbsenceCalendar(ctx context.Context, src string) ([]leave, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		ctx, cancel := context.WithTimeout(ctx, absenceTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("GET %s: %s", src, resp.Status)
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		body = f
+	}
+	defer body.Close()
+	return parseICSLeave(body)
+}
+
+// parseICSLeave reads every event of a calendar as leave, all-day events
+// (the usual for vacations) as well as timed ones. Recurring events count
+// once, at their first occurrence.
+func parseICSLeave(r io.Reader) ([]leave, error) {
+	lines, err := icsLines(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out []leave
+		ev  map[string]icsProp
+	)
+	for _, l := range lines {
+		switch strings.ToUpper(l) {
+		case "BEGIN:VEVENT":
+			ev = map[string]icsProp{}
+			continue
+		case "END:VEVENT":
+			if ev == nil {
+				continue
+			}
+			if _, ok := ev["DTSTART"]; ok {
+				lv, err := icsLeave(ev)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, lv)
+			}
+			ev = nil
+			continue
+		}
+		if ev != nil {
+			p := parseICSProp(l)
+			if _, seen := ev[p.Name]; !seen {
+				ev[p.Name] = p
+			}
+		}
+	}
+	return out, nil
+}
+
+// icsLeave is the time an event covers: all-day events from the midnight of
+// their first day to that after their last, timed ones as imported for a
+// batch.
+func icsLeave(ev map[string]icsProp) (leave, error) {
+	what := orDefault(icsUnescape(ev["SUMMARY"].Value), "away")
+	start := ev["DTSTART"]
+	if start.Params["VALUE"] != "DATE" && strings.Contains(start.Value, "T") {
+		e, err := importEvent(ev)
+		if err != nil {
+			return leave{}, err
+		}
+		return leave{Start: e.Start, End: e.Start.Add(e.Length), What: what}, nil
+	}
+	from, err := time.ParseInLocation("20060102", start.Value, time.Local)
+	if err != nil {
+		return leave{}, fmt.Errorf("event %q: DTSTART: invalid date %q", what, start.Value)
+	}
+	to := from.AddDate(0, 0, 1)
+	switch {
+	case ev["DTEND"].Value != "":
+		if to, err = time.ParseInLocation("20060102", ev["DTEND"].Value, time.Local); err != nil {
+			return leave{}, fmt.Errorf("event %q: DTEND: invalid date %q", what, ev["DTEND"].Value)
+		}
+	case ev["DURATION"].Value != "":
+		d, err := icsDuration(ev["DURATION"].Value)
+		if err != nil {
+			return leave{}, fmt.Errorf("event %q: %w", what, err)
+		}
+		to = from.AddDate(0, 0, int(d/(24*time.Hour)))
+	}
+	return leave{Start: from, End: to, What: what}, nil
+}
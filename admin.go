@@ -0,0 +1,238 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How many days / parameter sets the admin page shows, how many parameter
+// sets are counted at most (the form takes free text, so there is no end
+// to them), and how many stored releases of the coming week it lists.
+const (
+	statsDays      = 14
+	statsTopParams = 10
+	statsMaxParams = 1000
+	statsUpcoming  = 20
+)
+
+// webStats counts calculations served by this instance (in memory, reset on restart).
+type webStats struct {
+	mu      sync.Mutex
+	started time.Time
+	total   int
+	errors  int
+	perDay  map[string]int // YYYY-MM-DD -> calculations, the last statsDays
+	params  map[string]int // canonical query -> calculations, at most statsMaxParams
+}
+
+func newWebStats() *webStats {
+	return &webStats{
+		started: time.Now(),
+		perDay:  map[string]int{},
+		params:  map[string]int{},
+	}
+}
+
+// record counts one calculation; query is the canonical parameter string (empty on error).
+func (s *webStats) record(query string, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	now := time.Now()
+	day := now.Format("2006-01-02")
+	if _, ok := s.perDay[day]; !ok {
+		oldest := now.AddDate(0, 0, 1-statsDays).Format("2006-01-02")
+		for d := range s.perDay {
+			if d < oldest {
+				delete(s.perDay, d)
+			}
+		}
+	}
+	s.perDay[day]++
+	if failed {
+		s.errors++
+		return
+	}
+	if query == "" {
+		return
+	}
+	if _, ok := s.params[query]; !ok && len(s.params) >= statsMaxParams {
+		s.evictParams()
+	}
+	s.params[query]++
+}
+
+// evictParams forgets the least counted parameter set, to make room for a
+// new one; of those counted as often, the greatest by query.
+func (s *webStats) evictParams() {
+	least, n := "", 0
+	for q, c := range s.params {
+		if least == "" || c < n || c == n && q > least {
+			least, n = q, c
+		}
+	}
+	delete(s.params, least)
+}
+
+type dayCount struct {
+	Day   string
+	Count int
+}
+
+type paramCount struct {
+	Query string
+	Link  string
+	Count int
+}
+
+type AdminData struct {
+	Version   string
+	CSPNonce  string
+	Started   string
+	Uptime    string
+	Total     int
+	Errors    int
+	ErrorRate string
+	PerDay    []dayCount
+
+	// CacheHitRate is the share of page views served from the result
+	// cache, "" when it is off; CacheEntries the calculations in it.
+	CacheHitRate string
+	CacheEntries int
+
+	TopParams []paramCount
+
+	// Upcoming are the config's stored releases (feed windows and roster
+	// releases) starting in the next week; ShowUpcoming is whether it has
+	// any at all.
+	Upcoming      []upcomingRelease
+	ShowUpcoming  bool
+	UpcomingError string
+}
+
+// snapshot copies the counters; links to parameter sets are prefixed with basePath.
+func (s *webStats) snapshot(basePath string) AdminData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := AdminData{
+		Version:   appVersion,
+		Started:   s.started.Format("2006-01-02 15:04"),
+		Uptime:    time.Since(s.started).Round(time.Minute).String(),
+		Total:     s.total,
+		Errors:    s.errors,
+		ErrorRate: "0.0%",
+	}
+	if s.total > 0 {
+		d.ErrorRate = fmt.Sprintf("%.1f%%", float64(s.errors)*100/float64(s.total))
+	}
+
+	today := time.Now()
+	for i := 0; i < statsDays; i++ {
+		day := today.AddDate(0, 0, -i).Format("2006-01-02")
+		d.PerDay = append(d.PerDay, dayCount{Day: day, Count: s.perDay[day]})
+	}
+
+	for q, n := range s.params {
+		d.TopParams = append(d.TopParams, paramCount{Query: q, Link: basePath + "/?" + q, Count: n})
+	}
+	sort.Slice(d.TopParams, func(i, j int) bool {
+		if d.TopParams[i].Count != d.TopParams[j].Count {
+			return d.TopParams[i].Count > d.TopParams[j].Count
+		}
+		return d.TopParams[i].Query < d.TopParams[j].Query
+	})
+	if len(d.TopParams) > statsTopParams {
+		d.TopParams = d.TopParams[:statsTopParams]
+	}
+	return d
+}
+
+// adminHandler serves the stats page, with the stored releases of cfg
+// coming up this week.
+func adminHandler(stats *webStats, cache *resultCache, cfg Config, pages pageRenderer) http.HandlerFunc {
+	tpl := template.Must(template.New("admin").Parse(adminHTML))
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := stats.snapshot(pages.basePath)
+		data.CSPNonce = cspNonce(r)
+		if data.ShowUpcoming = cfg.hasReleases(); data.ShowUpcoming {
+			now := appClock.Now()
+			upcoming, err := cfg.upcomingReleases(now, statsUpcoming)
+			if err != nil {
+				data.UpcomingError = err.Error()
+			}
+			for _, u := range upcoming {
+				if u.Start.Before(now.AddDate(0, 0, 7)) {
+					data.Upcoming = append(data.Upcoming, u)
+				}
+			}
+		}
+		if cache != nil {
+			hits, misses, entries := cache.counts()
+			data.CacheHitRate, data.CacheEntries = "0.0%", entries
+			if hits+misses > 0 {
+				data.CacheHitRate = fmt.Sprintf("%.1f%%", float64(hits)*100/float64(hits+misses))
+			}
+		}
+		pages.render(w, r, tpl, http.StatusOK, data)
+	}
+}
+
+const adminHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>nightrelcalc admin</title>
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 960px; box-sizing: border-box; }
+    h2 { margin-top: 0; font-weight: 600; }
+    .card { border: 1px solid #e0e0e0; border-radius: 10px; padding: 16px; margin: 16px 0; background: #fafafa; }
+    .mono { font-family: ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, "Liberation Mono", "Courier New", monospace; }
+    table { border-collapse: collapse; width: 100%; margin-top: 10px; }
+    td, th { padding: 8px 10px; border-top: 1px solid #eee; vertical-align: top; text-align: left; }
+    .n { text-align: right; width: 120px; }
+    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
+  </style>
+</head>
+<body>
+  <h2>Instance statistics</h2>
+
+  <div class="card">
+    <div><b>Running since</b>: <span class="mono">{{.Started}}</span> (up <span class="mono">{{.Uptime}}</span>)</div>
+    <div><b>Calculations</b>: <span class="mono">{{.Total}}</span>, <b>Errors</b>: <span class="mono">{{.Errors}}</span> (<span class="mono">{{.ErrorRate}}</span>)</div>
+    {{with .CacheHitRate}}<div><b>Result cache</b>: <span class="mono">{{.}}</span> hits, <span class="mono">{{$.CacheEntries}}</span> cached</div>{{end}}
+  </div>
+
+  <div class="card">
+    <div><b>Calculations per day</b></div>
+    <table>
+      {{range .PerDay}}<tr><td class="mono">{{.Day}}</td><td class="mono n">{{.Count}}</td></tr>{{end}}
+    </table>
+  </div>
+
+  <div class="card">
+    <div><b>Most common parameters</b></div>
+    <table>
+      {{range .TopParams}}<tr><td class="mono"><a href="{{.Link}}">{{.Query}}</a></td><td class="mono n">{{.Count}}</td></tr>
+      {{else}}<tr><td>No calculations yet.</td></tr>{{end}}
+    </table>
+  </div>
+
+  {{if .ShowUpcoming}}
+  <div class="card">
+    <div><b>Stored releases this week</b></div>
+    {{with .UpcomingError}}<div>{{.}}</div>{{end}}
+    <table>
+      {{range .Upcoming}}<tr><td class="mono">{{.Start.Format "Mon 2 Jan 15:04"}} -> {{.End.Format "15:04"}}</td><td>{{if .Feed}}{{.Feed}}{{else}}{{.Member}} ({{.Roster}}){{end}}</td></tr>
+      {{else}}<tr><td>None in the next 7 days.</td></tr>{{end}}
+    </table>
+  </div>
+  {{end}}
+
+  <footer>nightrelcalc v{{.Version}}</footer>
+</body>
+</html>`
@@ -0,0 +1,64 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWebStatsBounded checks that the counters keep the last statsDays and
+// at most statsMaxParams parameter sets, whatever the form is sent.
+func TestWebStatsBounded(t *testing.T) {
+	s := newWebStats()
+	s.perDay["2000-01-01"] = 3
+	for range 5 {
+		s.record("start=22:00&length=3", false)
+	}
+	for i := range statsMaxParams + 50 {
+		s.record(fmt.Sprintf("start=22:00&length=3&team=%d", i), false)
+	}
+	if len(s.params) > statsMaxParams {
+		t.Errorf("%d parameter sets counted, want at most %d", len(s.params), statsMaxParams)
+	}
+	if s.params["start=22:00&length=3"] != 5 {
+		t.Errorf("the most common parameter set was evicted")
+	}
+	if _, ok := s.perDay["2000-01-01"]; ok || len(s.perDay) != 1 {
+		t.Errorf("days kept: %v", s.perDay)
+	}
+}
+
+// TestAdminUpcoming checks that /admin lists the roster's stored releases
+// of the coming week.
+func TestAdminUpcoming(t *testing.T) {
+	opts := DefaultWebOptions()
+	opts.AdminAuth = "admin:secret"
+	soon, later := time.Now().AddDate(0, 0, 2).Format(time.DateOnly), time.Now().AddDate(0, 0, 10).Format(time.DateOnly)
+	opts.Config = Config{Rosters: map[string]RosterConfig{"ops": {Members: []RosterMember{
+		{Name: "Ann", Releases: []StoredRelease{{Date: soon, Start: "22:00", Length: 3}}},
+		{Name: "Bob", Releases: []StoredRelease{{Date: later, Start: "22:00", Length: 3}}},
+	}}}}
+	h, err := NewHandler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	b, _ := io.ReadAll(rec.Result().Body)
+	page := string(b)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, page)
+	}
+	if !strings.Contains(page, "Ann (ops)") {
+		t.Errorf("the release in two days is not listed")
+	}
+	if strings.Contains(page, "Bob (ops)") {
+		t.Errorf("the release in ten days is listed")
+	}
+}
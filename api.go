@@ -0,0 +1,482 @@
+package nightrelcalc
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"nightrelcalc/calc"
+	"nightrelcalc/parse"
+)
+
+// calcRequest is one calculation in the JSON API. Omitted optional fields
+// take the same defaults as the web form.
+type calcRequest struct {
+	Start       string   `json:"start"`
+	Length      float64  `json:"length"`
+	Combine     *float64 `json:"combine,omitempty"`
+	Full        float64  `json:"full,omitempty"`
+	NormalStart string   `json:"normal_start,omitempty"`
+	NormalEnd   string   `json:"normal_end,omitempty"`
+	MinRest     *float64 `json:"min_rest,omitempty"`
+	Commute     *float64 `json:"commute,omitempty"` // each way, around the rest
+	MaxOvertime *float64 `json:"max_overtime,omitempty"`
+	PartTime    string   `json:"part_time,omitempty"`  // "80%" or "30h" a week; default full time
+	CoreHours   string   `json:"core_hours,omitempty"` // HH:MM-HH:MM the next day must start by
+	Commitment  string   `json:"commitment,omitempty"` // "HH:MM label" the next day must start by
+	Scenarios   []int    `json:"scenarios,omitempty"`  // only these, numbered from 1; default all
+
+	Strict bool `json:"strict,omitempty"` // reject inputs that would be adjusted, e.g. a combine longer than the release
+
+	// Profile is the config profile to compute under (see under); "" is
+	// the config's defaults.
+	Profile string `json:"profile,omitempty"`
+
+	policy InputPolicy // the server's --granularity and --strict
+	rules  []calc.Rule // the config's and the profile's extra scenarios, set by under
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiSchemaError is a 400 for a body that breaks schema.json.
+type apiSchemaError struct {
+	Error  string       `json:"error"`
+	Errors schemaErrors `json:"errors"`
+}
+
+// calcRequestFromQuery reads a calcRequest from the same query params the web UI uses.
+// A compact s=<token> share param stands in for all of them.
+func calcRequestFromQuery(q url.Values) (calcRequest, error) {
+	q, err := expandStateToken(q)
+	if err != nil {
+		return calcRequest{}, err
+	}
+	req := calcRequest{
+		Start:       strings.TrimSpace(q.Get("start")),
+		NormalStart: strings.TrimSpace(q.Get("normal_start")),
+		NormalEnd:   strings.TrimSpace(q.Get("normal_end")),
+		PartTime:    strings.TrimSpace(q.Get("part_time")),
+		CoreHours:   strings.TrimSpace(q.Get("core_hours")),
+		Commitment:  strings.TrimSpace(q.Get("commitment")),
+		Profile:     strings.TrimSpace(q.Get("profile")),
+	}
+	num := func(name string) (*float64, error) {
+		s := strings.TrimSpace(q.Get(name))
+		if s == "" {
+			return nil, nil
+		}
+		v, err := calc.ParseHours(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q, expected hours such as 4.5 or 4h30m", name, s)
+		}
+		return &v, nil
+	}
+	var length, full *float64
+	if length, err = num("length"); err != nil {
+		return req, err
+	}
+	if length != nil {
+		req.Length = *length
+	}
+	if full, err = num("full"); err != nil {
+		return req, err
+	}
+	if full != nil {
+		req.Full = *full
+	}
+	if req.Combine, err = num("combine"); err != nil {
+		return req, err
+	}
+	if req.MinRest, err = num("min_rest"); err != nil {
+		return req, err
+	}
+	if req.Commute, err = num("commute"); err != nil {
+		return req, err
+	}
+	if req.MaxOvertime, err = num("max_overtime"); err != nil {
+		return req, err
+	}
+	if req.Scenarios, err = parseScenarioList(strings.Join(q["scenarios"], ",")); err != nil {
+		return req, err
+	}
+	if s := strings.TrimSpace(q.Get("strict")); s != "" {
+		if req.Strict, err = strconv.ParseBool(s); err != nil {
+			return req, fmt.Errorf("invalid strict %q, expected true or false", s)
+		}
+	}
+	return req, nil
+}
+
+// withDefaults fills omitted optional fields with the web form defaults.
+func (req calcRequest) withDefaults() calcRequest {
+	req.NormalStart = orDefault(req.NormalStart, webDefaultNormalStart)
+	req.NormalEnd = orDefault(req.NormalEnd, webDefaultNormalEnd)
+	if req.MinRest == nil {
+		v, _ := parse.Number(webDefaultMinRest)
+		req.MinRest = &v
+	}
+	if req.Commute == nil {
+		v, _ := parse.Number(webDefaultCommute)
+		req.Commute = &v
+	}
+	if req.MaxOvertime == nil {
+		v, _ := parse.Number(webDefaultMaxOvertime)
+		req.MaxOvertime = &v
+	}
+	return req
+}
+
+// under computes req as the page does under its profile in cfg: omitted
+// work day and limit fields take the profile's values, and the config's
+// and the profile's extra scenarios follow the built-in ones, numbered as
+// on the page.
+func (req calcRequest) under(cfg Config) (calcRequest, error) {
+	p, err := cfg.lookupProfile(req.Profile)
+	if err != nil {
+		return req, err
+	}
+	req.NormalStart = orDefault(req.NormalStart, p.NormalStart)
+	req.NormalEnd = orDefault(req.NormalEnd, p.NormalEnd)
+	req.PartTime = orDefault(req.PartTime, p.PartTime)
+	req.CoreHours = orDefault(req.CoreHours, p.CoreHours)
+	req.MinRest = cmp.Or(req.MinRest, p.MinRest)
+	req.Commute = cmp.Or(req.Commute, p.Commute)
+	req.MaxOvertime = cmp.Or(req.MaxOvertime, p.MaxOvertime)
+	req.rules = cfg.rules(p)
+	return req, nil
+}
+
+func (req calcRequest) compute() (*calc.Result, error) {
+	req = req.withDefaults()
+	if strings.TrimSpace(req.Start) == "" {
+		return nil, fmt.Errorf("start is required (HH:MM)")
+	}
+	if req.Length <= 0 {
+		return nil, fmt.Errorf("length must be > 0 (hours)")
+	}
+	if *req.MinRest <= 0 {
+		return nil, fmt.Errorf("min_rest must be > 0 (hours)")
+	}
+	if *req.Commute < 0 {
+		return nil, fmt.Errorf("commute must be >= 0 (hours)")
+	}
+	if *req.MaxOvertime < 0 {
+		return nil, fmt.Errorf("max_overtime must be >= 0 (hours)")
+	}
+	combineH := -1.0
+	if req.Combine != nil {
+		if *req.Combine < 0 {
+			return nil, fmt.Errorf("combine must be >= 0 (hours) or omitted")
+		}
+		combineH = *req.Combine
+	}
+	picks, err := checkScenarioList(req.Scenarios)
+	if err != nil {
+		return nil, err
+	}
+	res, err := calc.Compute(req.Start, req.Length, combineH, req.Full, req.NormalStart, req.NormalEnd, *req.MinRest, *req.Commute, *req.MaxOvertime, req.PartTime, req.rules...)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.checkGrid(req.policy.Granularity); err != nil {
+		return nil, err
+	}
+	if req.Strict || req.policy.Strict {
+		if err := strictAdjustments(res); err != nil {
+			return nil, err
+		}
+	}
+	if err := calc.CheckNextDay(res, req.CoreHours, req.Commitment); err != nil {
+		return nil, err
+	}
+	if err := selectScenarios(res, picks); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// shareQuery is the web UI query ("start=...&length=...") for this request.
+func (req calcRequest) shareQuery() string {
+	opt := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return fmtFloat(*f)
+	}
+	q := strings.TrimPrefix(buildCalcURL(req.Start, fmtFloat(req.Length), opt(req.Combine),
+		req.NormalStart, req.NormalEnd, opt(req.MinRest), opt(req.Commute), opt(req.MaxOvertime), req.PartTime, req.CoreHours, req.Commitment, formatScenarioList(req.Scenarios), "", "", false), "/?")
+	if req.Profile == "" {
+		return q
+	}
+	// buildCalcURL leaves out the built-in defaults, which under a profile
+	// would take its values instead of the ones given
+	v, err := url.ParseQuery(withProfile(q, req.Profile))
+	if err != nil {
+		return withProfile(q, req.Profile)
+	}
+	for _, f := range []struct{ name, val string }{
+		{"normal_start", req.NormalStart}, {"normal_end", req.NormalEnd},
+		{"min_rest", opt(req.MinRest)}, {"commute", opt(req.Commute)}, {"max_overtime", opt(req.MaxOvertime)},
+	} {
+		if f.val != "" && !v.Has(f.name) {
+			v.Set(f.name, f.val)
+		}
+	}
+	return v.Encode()
+}
+
+// withProfile adds the profile param to query q, unless profile is "".
+func withProfile(q, profile string) string {
+	if profile == "" {
+		return q
+	}
+	return q + "&profile=" + url.QueryEscape(profile)
+}
+
+func fmtFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// decodeCalcBody reads a JSON calcRequest body, checked against schema.json.
+func decodeCalcBody(r *http.Request) (calcRequest, error) {
+	var req calcRequest
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = validateJSON("calcRequest", body, "")
+	}
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	return req, err
+}
+
+// writeBodyError answers a POST body that is not JSON or breaks schema.json.
+func writeBodyError(w http.ResponseWriter, err error) {
+	if errs, ok := err.(schemaErrors); ok {
+		writeJSON(w, http.StatusBadRequest, apiSchemaError{Error: errs.Error(), Errors: errs})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, apiError{"invalid JSON body: " + err.Error()})
+}
+
+// apiCalcHandler serves /api/v1/calc: GET with the web UI query params, or
+// POST with a JSON calcRequest body, checked against schema.json first.
+func apiCalcHandler(cfg Config, stats *webStats, policy InputPolicy, hooks NotifyOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req calcRequest
+		switch r.Method {
+		case http.MethodGet:
+			var err error
+			if req, err = calcRequestFromQuery(r.URL.Query()); err != nil {
+				stats.record("", true)
+				writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+				return
+			}
+		case http.MethodPost:
+			var err error
+			if req, err = decodeCalcBody(r); err != nil {
+				stats.record("", true)
+				writeBodyError(w, err)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+
+		req.policy = policy
+		req, err := req.under(cfg)
+		var res *calc.Result
+		if err == nil {
+			res, err = req.compute()
+		}
+		if err != nil {
+			stats.record("", true)
+			writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+			return
+		}
+		stats.record(req.shareQuery(), false)
+		hooks.postAsync(r.Context(), notice{Result: res, Source: "api"})
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+// batchItem is the outcome of one request of a batch: a result or an error.
+type batchItem struct {
+	Index  int          `json:"index"`
+	Result *calc.Result `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors schemaErrors `json:"errors,omitempty"` // when the item breaks schema.json
+}
+
+type batchResponse struct {
+	Items  []batchItem `json:"items"`
+	OK     int         `json:"ok"`
+	Failed int         `json:"failed"`
+}
+
+// apiBatchHandler serves POST /api/v1/calc/batch with a JSON array of up to
+// max calcRequests.
+//
+// The call as a whole either fails or succeeds: a body that is not a JSON
+// array of requests, is empty or has too many items is a 400 (413 for too
+// many) and nothing is computed. Otherwise the answer is 200 with one item
+// per request, in request order, each holding either its result or its
+// error; a bad item never affects the others, so callers check "failed" (or
+// each item's "error") rather than the status code.
+func apiBatchHandler(cfg Config, stats *webStats, max int, policy InputPolicy, hooks NotifyOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+		var reqs []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			msg := "invalid JSON body, expected an array of requests"
+			if _, ok := errors.AsType[*json.UnmarshalTypeError](err); !ok {
+				msg += ": " + err.Error()
+			}
+			writeJSON(w, http.StatusBadRequest, apiError{msg})
+			return
+		}
+		if len(reqs) == 0 {
+			writeJSON(w, http.StatusBadRequest, apiError{"no requests"})
+			return
+		}
+		if len(reqs) > max {
+			writeJSON(w, http.StatusRequestEntityTooLarge, apiError{fmt.Sprintf("too many requests: %d, at most %d per call", len(reqs), max)})
+			return
+		}
+
+		resp := batchResponse{Items: make([]batchItem, len(reqs))}
+		var done []notice
+		for i, raw := range reqs {
+			// A client that gave up or a request past its deadline gets
+			// nothing more computed.
+			if err := r.Context().Err(); err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, apiError{"batch cancelled: " + err.Error()})
+				return
+			}
+			item := batchItem{Index: i}
+			var req calcRequest
+			err := validateJSON("calcRequest", raw, "/"+strconv.Itoa(i))
+			if err == nil {
+				err = json.Unmarshal(raw, &req)
+			}
+			if err == nil {
+				req.policy = policy
+				req, err = req.under(cfg)
+			}
+			if err == nil {
+				item.Result, err = req.compute()
+			}
+			if err != nil {
+				item.Error = err.Error()
+				item.Errors, _ = err.(schemaErrors)
+				resp.Failed++
+				stats.record("", true)
+			} else {
+				resp.OK++
+				stats.record(req.shareQuery(), false)
+				done = append(done, notice{Result: item.Result, Source: "api"})
+			}
+			resp.Items[i] = item
+		}
+		hooks.postAsync(r.Context(), done...)
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+/* ---------------- API keys ---------------- */
+
+type apiKeys []string
+
+// loadAPIKeys merges --api-key values with keys from --api-keys-file (one per line, # comments).
+func loadAPIKeys(keys []string, file string) (apiKeys, error) {
+	out := apiKeys{}
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	if file == "" {
+		return out, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New(file + ": no API keys")
+	}
+	return out, nil
+}
+
+// valid reports whether key matches one of the configured keys.
+func (k apiKeys) valid(key string) bool {
+	ok := false
+	for _, want := range k {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// requestAPIKey reads "Authorization: Bearer <key>" or "X-API-Key: <key>".
+func requestAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if key, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(key)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// requireAPIKey rejects requests without a valid key; with no keys configured the API is open.
+func requireAPIKey(keys apiKeys, h http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !keys.valid(requestAPIKey(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="nightrelcalc api"`)
+			writeJSON(w, http.StatusUnauthorized, apiError{"missing or invalid API key"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
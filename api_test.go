@@ -0,0 +1,113 @@
+package nightrelcalc
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"nightrelcalc/calc"
+)
+
+// get serves path on h and returns the status and body.
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	b, _ := io.ReadAll(rec.Result().Body)
+	return rec.Code, string(b)
+}
+
+// TestPageLinksComputeConfigScenarios follows the result page's links: the
+// views they open number and compute the config's and the profile's
+// scenarios as the page does.
+func TestPageLinksComputeConfigScenarios(t *testing.T) {
+	opts := DefaultWebOptions()
+	opts.Config = Config{
+		Scenarios: []calc.Rule{{Title: "Config rule"}},
+		Profiles: map[string]Profile{
+			"payments": {NormalStart: "08:00", NormalEnd: "16:30", Scenarios: []calc.Rule{{Title: "Payments rule"}}},
+		},
+	}
+	h, err := NewHandler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, query, want string
+	}{
+		{"config", "start=22:00&length=3&scenarios=8", "Config rule"},
+		{"profile", "start=22:00&length=3&scenarios=9&profile=payments", "Payments rule"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			code, page := get(t, h, "/?"+tc.query)
+			if code != http.StatusOK {
+				t.Fatalf("page: status %d", code)
+			}
+			if !strings.Contains(page, tc.want) {
+				t.Fatalf("page does not show %q", tc.want)
+			}
+			for _, label := range []string{"Print view", "Download as HTML", "Compare with…"} {
+				m := regexp.MustCompile(`<a href="([^"]+)"[^>]*>` + regexp.QuoteMeta(label) + `</a>`).FindStringSubmatch(page)
+				if m == nil {
+					t.Fatalf("page has no %q link", label)
+				}
+				link := html.UnescapeString(m[1])
+				if label == "Compare with…" {
+					link += "&b=" + strings.TrimPrefix(link, "/compare?a=")
+				}
+				code, body := get(t, h, link)
+				if code != http.StatusOK {
+					t.Errorf("%s %s: status %d: %s", label, link, code, body)
+					continue
+				}
+				if !strings.Contains(body, tc.want) {
+					t.Errorf("%s %s does not show %q", label, link, tc.want)
+				}
+			}
+		})
+	}
+}
+
+// TestAPICommuteOverridesProfile checks that an explicit commute of 0, in
+// the query or the JSON body, wins over the profile's commute.
+func TestAPICommuteOverridesProfile(t *testing.T) {
+	commute := 1.0
+	opts := DefaultWebOptions()
+	opts.Config = Config{Profiles: map[string]Profile{"far": {Commute: &commute}}}
+	h, err := NewHandler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(body string) (int, string) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calc", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(rec, req)
+		b, _ := io.ReadAll(rec.Result().Body)
+		return rec.Code, string(b)
+	}
+	for _, tc := range []struct {
+		name string
+		call func() (int, string)
+		want bool // a commute in the result
+	}{
+		{"query, profile", func() (int, string) { return get(t, h, "/api/v1/calc?start=22:00&length=3&profile=far") }, true},
+		{"query, commute=0", func() (int, string) { return get(t, h, "/api/v1/calc?start=22:00&length=3&profile=far&commute=0") }, false},
+		{"body, profile", func() (int, string) { return post(`{"start": "22:00", "length": 3, "profile": "far"}`) }, true},
+		{"body, commute 0", func() (int, string) { return post(`{"start": "22:00", "length": 3, "profile": "far", "commute": 0}`) }, false},
+	} {
+		code, body := tc.call()
+		if code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", tc.name, code, body)
+			continue
+		}
+		if got := strings.Contains(body, `"commute": "1h00m"`); got != tc.want {
+			t.Errorf("%s: commute in the result %v, want %v: %s", tc.name, got, tc.want, body)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package nightrelcalc
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+/* ---------------- embedded static assets (/assets/, favicon, robots.txt) ---------------- */
+
+//go:embed assets
+var assetsFS embed.FS
+
+var assetContentTypes = map[string]string{
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".ico":         "image/x-icon",
+	".png":         "image/png",
+	".txt":         "text/plain; charset=utf-8",
+	".webmanifest": "application/manifest+json",
+	".wasm":        "application/wasm",
+}
+
+// embeddedFile is one embedded asset with its validator precomputed.
+type embeddedFile struct {
+	body        []byte
+	contentType string
+	etag        string
+	hash        string // short content hash, used as ?v= in asset URLs
+	cache       string // Cache-Control
+}
+
+func newEmbeddedFile(name string, body []byte, cache string) *embeddedFile {
+	sum := sha256.Sum256(body)
+	return &embeddedFile{
+		body:        body,
+		contentType: assetContentTypes[path.Ext(name)],
+		etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		hash:        hex.EncodeToString(sum[:6]),
+		cache:       cache,
+	}
+}
+
+func (f *embeddedFile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Cache-Control", f.cache)
+	w.Header().Set("ETag", f.etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, f.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write(f.body)
+}
+
+// assetFiles are the files under assets/, by name.
+var assetFiles = func() map[string]*embeddedFile {
+	files := map[string]*embeddedFile{}
+	_ = fs.WalkDir(assetsFS, "assets", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := assetsFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimPrefix(p, "assets/")] = newEmbeddedFile(p, b, "no-cache")
+		return nil
+	})
+	return files
+}()
+
+// assetHash is the ?v= for an asset URL in the templates ({{assetHash "app.css"}}).
+func assetHash(name string) string {
+	if f := assetFiles[name]; f != nil {
+		return f.hash
+	}
+	return ""
+}
+
+// assetsHandler serves /assets/<name>. URLs carrying the current content hash
+// are immutable and cached for a year; anything else revalidates.
+func assetsHandler(pages pageRenderer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f := assetFiles[strings.TrimPrefix(r.URL.Path, "/assets/")]
+		if f == nil {
+			pages.error(w, r, http.StatusNotFound, "There is no asset at "+pages.basePath+r.URL.Path+".")
+			return
+		}
+		if v := r.URL.Query().Get("v"); v != "" && v == f.hash {
+			cached := *f
+			cached.cache = "public, max-age=31536000, immutable"
+			cached.ServeHTTP(w, r)
+			return
+		}
+		f.ServeHTTP(w, r)
+	})
+}
+
+// robotsHandler serves robots.txt. It only takes effect at the host root, so
+// behind --base-path the proxy has to route /robots.txt here.
+func robotsHandler(basePath string) http.HandlerFunc {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, p := range []string{"/admin", "/api/", "/results", "/metrics", "/oembed", "/calc"} {
+		b.WriteString("Disallow: " + basePath + p + "\n")
+	}
+	f := newEmbeddedFile("robots.txt", []byte(b.String()), "public, max-age=86400")
+	return f.ServeHTTP
+}
+
+// registerAssets adds /assets/, /favicon.ico and /robots.txt.
+func registerAssets(mux *http.ServeMux, pages pageRenderer) {
+	mux.Handle("/assets/", assetsHandler(pages))
+	if f := assetFiles["favicon.ico"]; f != nil {
+		ico := *f
+		ico.cache = "public, max-age=604800"
+		mux.Handle("/favicon.ico", &ico)
+	}
+	mux.Handle("/robots.txt", robotsHandler(pages.basePath))
+}
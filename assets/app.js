@@ -0,0 +1,233 @@
+// nightrelcalc page script: time picker, live recalculation, offline compute.
+(function() {
+  var base = document.body.getAttribute('data-base-path') || '';
+  var overlay = document.getElementById('time-picker-overlay');
+  var hourSelect = document.getElementById('tp-hour');
+  var minuteSelect = document.getElementById('tp-minute');
+  var okBtn = document.getElementById('tp-ok');
+  var cancelBtn = document.getElementById('tp-cancel');
+  var targetInput = null;
+
+  function pad2(n) { return (n < 10 ? '0' : '') + n; }
+  function parseTime(s) {
+    if (!s || typeof s !== 'string') return { h: 0, m: 0 };
+    s = s.trim();
+    var m = s.match(/^(\d{1,2}):(\d{2})$/);
+    if (!m) return { h: 0, m: 0 };
+    var h = parseInt(m[1], 10);
+    var min = parseInt(m[2], 10);
+    if (h < 0 || h > 23 || min < 0 || min > 59) return { h: 0, m: 0 };
+    return { h: h, m: min };
+  }
+  function fillDropdowns() {
+    hourSelect.innerHTML = '';
+    for (var i = 0; i < 24; i++) {
+      var o = document.createElement('option');
+      o.value = i;
+      o.textContent = pad2(i);
+      hourSelect.appendChild(o);
+    }
+    minuteSelect.innerHTML = '';
+    var step = parseInt(minuteSelect.getAttribute('data-step'), 10) || 1;
+    for (var j = 0; j < 60; j += step) {
+      var o = document.createElement('option');
+      o.value = j;
+      o.textContent = pad2(j);
+      minuteSelect.appendChild(o);
+    }
+  }
+  fillDropdowns();
+
+  function openPicker(inputId) {
+    targetInput = document.getElementById(inputId);
+    if (!targetInput) return;
+    var val = targetInput.value;
+    var t = parseTime(val);
+    hourSelect.value = t.h;
+    minuteSelect.value = t.m;
+    overlay.classList.add('open');
+    hourSelect.focus();
+  }
+  function closePicker() {
+    overlay.classList.remove('open');
+    targetInput = null;
+  }
+  function applyTime() {
+    if (!targetInput) return;
+    var h = parseInt(hourSelect.value, 10);
+    var m = parseInt(minuteSelect.value, 10);
+    targetInput.value = pad2(h) + ':' + pad2(m);
+    closePicker();
+  }
+
+  document.querySelectorAll('.time-picker-btn').forEach(function(btn) {
+    btn.addEventListener('click', function(e) {
+      e.preventDefault(); // without JS the button submits for the server-side picker
+      openPicker(btn.getAttribute('data-for'));
+    });
+  });
+  okBtn.addEventListener('click', applyTime);
+  cancelBtn.addEventListener('click', closePicker);
+  overlay.addEventListener('click', function(e) {
+    if (e.target === overlay) closePicker();
+  });
+  document.addEventListener('keydown', function(e) {
+    if (!overlay.classList.contains('open')) return;
+    if (e.key === 'Escape') { e.preventDefault(); closePicker(); }
+    if (e.key === 'Enter') { e.preventDefault(); applyTime(); }
+  });
+
+  // Live recalculation: fetch the results partial as the inputs change.
+  var form = document.querySelector('form');
+  var results = document.getElementById('results');
+  var timer = null, seq = 0;
+  function recalc() {
+    if (!form.checkValidity()) return;
+    var params = new URLSearchParams(new FormData(form));
+    params.delete('csrf_token');
+    var mine = ++seq;
+    fetch(form.getAttribute('action').replace(/\/calc$/, '/results') + '?' + params.toString())
+      .then(function(resp) { return resp.text(); })
+      .then(function(html) {
+        if (mine !== seq) return;
+        results.innerHTML = html;
+        syncFieldErrors();
+      })
+      .catch(function() { if (mine === seq) computeOffline(); });
+  }
+  // Move the partial's field errors next to the fields, as on a full page load.
+  function syncFieldErrors() {
+    var list = results.querySelector('[data-field-errors]');
+    var msgs = {};
+    if (list) {
+      list.querySelectorAll('[data-field]').forEach(function(d) {
+        msgs[d.getAttribute('data-field')] = d.getAttribute('data-message');
+      });
+      list.parentNode.removeChild(list);
+    }
+    form.querySelectorAll('.field-error').forEach(function(msg) {
+      var name = msg.id.replace(/-error$/, '');
+      var input = form.elements[name];
+      var m = msgs[name] || '';
+      msg.textContent = m;
+      if (input) input.setAttribute('aria-invalid', m ? 'true' : 'false');
+      msg.closest('.field').classList.toggle('invalid', m !== '');
+    });
+  }
+  form.addEventListener('input', function(e) {
+    if (e.target.name === 'profile') return;
+    clearTimeout(timer);
+    timer = setTimeout(recalc, 300);
+  });
+  okBtn.addEventListener('click', recalc);
+
+  // Picking a profile applies it right away (the Apply button without JS).
+  var profile = form.elements['profile'];
+  if (profile) {
+    profile.addEventListener('change', function() {
+      form.querySelector('.use-profile').click();
+    });
+  }
+
+  // Offline: compute in the browser with the WebAssembly core, when the
+  // binary was built with it (go generate); the server stays the default.
+  var wasm = null;
+  function loadWasm() {
+    if (wasm) return wasm;
+    wasm = new Promise(function(resolve, reject) {
+      var s = document.createElement('script');
+      s.src = base + '/wasm_exec.js';
+      s.onload = function() {
+        var go = new Go();
+        WebAssembly.instantiateStreaming(fetch(base + '/calc.wasm'), go.importObject).then(function(r) {
+          go.run(r.instance);
+          resolve();
+        }, reject);
+      };
+      s.onerror = reject;
+      document.head.appendChild(s);
+    });
+    wasm.catch(function() { wasm = null; });
+    return wasm;
+  }
+  function field(name) {
+    var f = form.elements[name];
+    return (f.value || f.placeholder || '').trim();
+  }
+  // hours reads decimal hours (4.5) or a duration (4h30m), as on the server.
+  function hours(name) {
+    var s = field(name).replace(',', '.');
+    var d = /^(?:(\d+(?:\.\d+)?)h)?(?:(\d+(?:\.\d+)?)m)?$/.exec(s);
+    if (d && (d[1] || d[2])) return parseFloat(d[1] || '0') + parseFloat(d[2] || '0') / 60;
+    return parseFloat(s);
+  }
+  function el(tag, cls, text) {
+    var e = document.createElement(tag);
+    if (cls) e.className = cls;
+    if (text != null) e.textContent = text;
+    return e;
+  }
+  function renderOffline(res) {
+    results.textContent = '';
+    if (res.error) {
+      results.appendChild(el('div', 'err', res.error));
+      return;
+    }
+    var head = el('div', 'card');
+    head.appendChild(el('div', null, 'Release Window: ' + res.release_start + ' → ' + res.release_end + ' (len ' + res.release_len + ')'));
+    head.appendChild(el('div', null, 'Normal day: ' + res.normal_start + ' → ' + res.normal_end + ' (len ' + res.normal_len + ')'));
+    head.appendChild(el('div', null, 'Full day used: ' + res.full_day + ', Min rest: ' + res.min_rest + ', Max overtime (cap): ' + res.max_overtime));
+    head.appendChild(el('div', 'hint', 'Computed offline in your browser.'));
+    results.appendChild(head);
+    res.scenarios.forEach(function(s) {
+      var card = el('div', 'card');
+      var title = el('div');
+      title.appendChild(el('b', null, s.title));
+      card.appendChild(title);
+      var table = el('table');
+      [['Work Hours', s.work_hours], ['Release Window', s.release_window], ['Total Work', s.total_work],
+       ['Release Hours Included in Full', s.release_included], ['Overtime', s.overtime], ['Next Day Hours', s.next_day_hours],
+       ['TOIL Balance', s.toil]].forEach(function(row) {
+        if (row[1] == null) return;
+        var tr = el('tr');
+        tr.appendChild(el('td', 'k', row[0]));
+        tr.appendChild(el('td', 'mono', row[1]));
+        table.appendChild(tr);
+      });
+      card.appendChild(table);
+      if (s.notes) {
+        var notes = el('ol', 'notes');
+        s.notes.forEach(function(n) { notes.appendChild(el('li', null, n)); });
+        card.appendChild(notes);
+      }
+      if (s.warnings) {
+        var warnings = el('ul', 'warnings');
+        s.warnings.forEach(function(n) { warnings.appendChild(el('li', null, n)); });
+        card.appendChild(warnings);
+      }
+      results.appendChild(card);
+    });
+  }
+  function computeOffline() {
+    return loadWasm().then(function() {
+      var req = {
+        start: field('start'), length: hours('length'),
+        normal_start: field('normal_start'), normal_end: field('normal_end'),
+        min_rest: hours('min_rest'), commute: hours('commute'), max_overtime: hours('max_overtime'),
+        part_time: field('part_time'), core_hours: field('core_hours'),
+        commitment: field('commitment')
+      };
+      if (form.elements['combine'].value.trim() !== '') req.combine = hours('combine');
+      renderOffline(JSON.parse(nightrelcalcCompute(JSON.stringify(req))));
+    }, function() {});
+  }
+  form.addEventListener('submit', function(e) {
+    if (navigator.onLine) return;
+    e.preventDefault();
+    computeOffline();
+  });
+
+  if ('serviceWorker' in navigator) {
+    navigator.serviceWorker.register(base + '/sw.js', { scope: base + '/' }).catch(function() {});
+  }
+})();
@@ -0,0 +1,117 @@
+package nightrelcalc
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// credentials maps user names to a password entry: bcrypt ("$2y$..."),
+// "{SHA}base64(sha1)" or plain text, as found in htpasswd files.
+type credentials map[string]string
+
+// parseCredential turns "user:pass" into credentials.
+func parseCredential(s string) (credentials, error) {
+	user, pass, ok := strings.Cut(s, ":")
+	if !ok || user == "" {
+		return nil, fmt.Errorf("invalid credential %q, expected user:pass", s)
+	}
+	return credentials{user: pass}, nil
+}
+
+// loadHtpasswd reads an htpasswd file (bcrypt, {SHA} or plain entries).
+func loadHtpasswd(path string) (credentials, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	creds := credentials{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected user:hash", path, n)
+		}
+		if strings.HasPrefix(hash, "$apr1$") || strings.HasPrefix(hash, "$1$") {
+			return nil, fmt.Errorf("%s:%d: MD5 hashes are not supported, use bcrypt (htpasswd -B)", path, n)
+		}
+		creds[user] = hash
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(creds) == 0 {
+		return nil, fmt.Errorf("%s: no users", path)
+	}
+	return creds, nil
+}
+
+func (c credentials) check(user, pass string) bool {
+	want, ok := c[user]
+	if !ok {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(want, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(want), []byte(pass)) == nil
+	case strings.HasPrefix(want, "{SHA}"):
+		sum := sha1.Sum([]byte(pass))
+		got := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+	default:
+		return subtle.ConstantTimeCompare([]byte(pass), []byte(want)) == 1
+	}
+}
+
+// requireBasicAuth wraps h with HTTP basic auth against creds.
+func requireBasicAuth(creds credentials, realm string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !creds.check(user, pass) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// protectSite puts basic auth in front of everything except paths that do
+// their own auth: /admin, /slack/command, the /feed/ tokens, /api/ when API
+// keys are configured and /metrics with a --metrics-token.
+func protectSite(creds credentials, apiKeyed, metricsKeyed bool, h http.Handler) http.Handler {
+	protected := requireBasicAuth(creds, "nightrelcalc", h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" || (metricsKeyed && r.URL.Path == "/metrics") || r.URL.Path == "/slack/command" || strings.HasPrefix(r.URL.Path, "/feed/") || (apiKeyed && strings.HasPrefix(r.URL.Path, "/api/")) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	})
+}
+
+// siteCredentials loads the --auth / --auth-file credentials (nil when neither is set).
+func siteCredentials(opts WebOptions) (credentials, error) {
+	switch {
+	case opts.Auth != "" && opts.AuthFile != "":
+		return nil, fmt.Errorf("use either --auth or --auth-file, not both")
+	case opts.Auth != "":
+		return parseCredential(opts.Auth)
+	case opts.AuthFile != "":
+		return loadHtpasswd(opts.AuthFile)
+	}
+	return nil, nil
+}
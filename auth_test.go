@@ -0,0 +1,191 @@
+package nightrelcalc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestMetricsAuth checks that /metrics is behind --auth, unless it has a
+// --metrics-token of its own.
+func TestMetricsAuth(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		token      string
+		user, pass string
+		bearer     string
+		want       int
+	}{
+		{"no credentials", "", "", "", "", http.StatusUnauthorized},
+		{"site password", "", "ops", "secret", "", http.StatusOK},
+		{"wrong password", "", "ops", "guess", "", http.StatusUnauthorized},
+		{"token", "scrape-token", "", "", "scrape-token", http.StatusOK},
+		{"wrong token", "scrape-token", "", "", "guess", http.StatusUnauthorized},
+		{"site password, token required", "scrape-token", "ops", "secret", "", http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := DefaultWebOptions()
+			opts.Auth = "ops:secret"
+			opts.MetricsToken = tc.token
+			h, err := NewHandler(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.pass)
+			}
+			if tc.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("status %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestCredentialsCheck(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := credentials{
+		"bcrypt": string(hash),
+		// htpasswd -B writes $2y$, which is the same hash
+		"apache": "$2y$" + strings.TrimPrefix(string(hash), "$2a$"),
+		"sha":    "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", // htpasswd -s, "secret"
+		"plain":  "secret",
+	}
+	for _, tc := range []struct {
+		user, pass string
+		want       bool
+	}{
+		{"bcrypt", "secret", true},
+		{"bcrypt", "Secret", false},
+		{"bcrypt", "", false},
+		{"apache", "secret", true},
+		{"apache", "secret ", false},
+		{"sha", "secret", true},
+		{"sha", "secreT", false},
+		{"plain", "secret", true},
+		{"plain", "secre", false},
+		{"plain", "", false},
+		{"nobody", "secret", false},
+		{"", "", false},
+	} {
+		if got := creds.check(tc.user, tc.pass); got != tc.want {
+			t.Errorf("check(%q, %q) = %v, want %v", tc.user, tc.pass, got, tc.want)
+		}
+	}
+
+	// A tampered hash matches nothing
+	tampered := credentials{"bcrypt": string(hash[:len(hash)-1]) + "x", "sha": "{SHA}5en6G6MezRroT3XKqkdPOmY/BfR="}
+	if tampered.check("bcrypt", "secret") || tampered.check("sha", "secret") {
+		t.Errorf("a tampered hash matched")
+	}
+}
+
+func TestParseCredential(t *testing.T) {
+	for _, tc := range []struct {
+		in string
+		ok bool
+	}{
+		{"ops:secret", true},
+		{"ops:pass:with:colons", true},
+		{"ops:", true}, // an empty password, if that is what was asked for
+		{"ops", false},
+		{":secret", false},
+		{"", false},
+	} {
+		creds, err := parseCredential(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("parseCredential(%q): %v", tc.in, err)
+			continue
+		}
+		if tc.ok {
+			user, pass, _ := strings.Cut(tc.in, ":")
+			if !creds.check(user, pass) {
+				t.Errorf("parseCredential(%q) does not check", tc.in)
+			}
+		}
+	}
+}
+
+func TestLoadHtpasswd(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name, file string
+		users      []string
+		err        string
+	}{
+		{"entries", "# ops\nann:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n\nbob:$2y$05$abcdefghijklmnopqrstuu5v1c4Q2Ihu3nNGyGdrq3A7XPV9eVmjG\n", []string{"ann", "bob"}, ""},
+		{"md5", "ann:$apr1$abc$def\n", nil, "MD5 hashes are not supported"},
+		{"no colon", "ann\n", nil, "expected user:hash"},
+		{"no user", ":secret\n", nil, "expected user:hash"},
+		{"empty", "# nobody\n", nil, "no users"},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		creds, err := loadHtpasswd(path)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		for _, u := range tc.users {
+			if _, ok := creds[u]; !ok {
+				t.Errorf("%s: no user %q", tc.name, u)
+			}
+		}
+	}
+	if _, err := loadHtpasswd(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("a missing file loads")
+	}
+}
+
+func TestRequireBasicAuth(t *testing.T) {
+	h := requireBasicAuth(credentials{"ops": "secret"}, "nightrelcalc", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		name, user, pass string
+		header           string // instead of user and pass
+		want             int
+	}{
+		{"valid", "ops", "secret", "", http.StatusOK},
+		{"wrong password", "ops", "guess", "", http.StatusUnauthorized},
+		{"unknown user", "dev", "secret", "", http.StatusUnauthorized},
+		{"missing", "", "", "", http.StatusUnauthorized},
+		{"not basic", "", "", "Bearer secret", http.StatusUnauthorized},
+		{"malformed", "", "", "Basic not-base64", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		switch {
+		case tc.header != "":
+			req.Header.Set("Authorization", tc.header)
+		case tc.user != "":
+			req.SetBasicAuth(tc.user, tc.pass)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+		if tc.want == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), `Basic realm="nightrelcalc"`) {
+			t.Errorf("%s: WWW-Authenticate %q", tc.name, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package nightrelcalc
+
+import (
+	"html/template"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- /availability: who can take which night ---------------- */
+
+// availabilityDays is the width of the grid: a week.
+const availabilityDays = 7
+
+type availabilityRow struct {
+	Name  string
+	Cells []eligibility // one per day of the grid
+}
+
+type AvailabilityData struct {
+	Rosters []string
+	Roster  string
+	Start   string
+	Length  string
+	From    string // YYYY-MM-DD, the first day of the grid
+
+	Days  []string // column headings, e.g. "Tue 13"
+	Rows  []availabilityRow
+	Error string
+
+	Version  string
+	BasePath string
+	CSPNonce string
+	Brand    Brand
+}
+
+// availabilityHandler serves /availability: for a release at the same time
+// every day of a week, who of a roster (see RosterConfig) can take it and
+// what blocks the others.
+func availabilityHandler(pages pageRenderer, tpl *template.Template, cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			pages.methodNotAllowed(w, r, "GET")
+			return
+		}
+		q := r.URL.Query()
+		data := AvailabilityData{
+			Rosters:  slices.Sorted(maps.Keys(cfg.Rosters)),
+			Roster:   strings.TrimSpace(q.Get("roster")),
+			Start:    orDefault(q.Get("start"), webDefaultStart),
+			Length:   orDefault(q.Get("length"), webDefaultLength),
+			From:     orDefault(q.Get("from"), appClock.Now().Format(time.DateOnly)),
+			Version:  appVersion,
+			BasePath: pages.basePath,
+			CSPNonce: cspNonce(r),
+			Brand:    pages.brand,
+		}
+		if data.Roster == "" && len(data.Rosters) > 0 {
+			data.Roster = data.Rosters[0]
+		}
+		roster, ok := cfg.Rosters[data.Roster]
+		if !ok {
+			data.Error = "unknown roster " + data.Roster
+			pages.render(w, r, tpl, http.StatusNotFound, data)
+			return
+		}
+		rs, err := calc.ParseClock(data.Start)
+		if err != nil {
+			data.Error = "start: " + err.Error()
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
+		}
+		lengthH, err := calc.ParseHours(data.Length)
+		if err != nil || lengthH <= 0 {
+			data.Error = "length must be > 0 hours, e.g. 4 or 4h30m"
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
+		}
+		from, err := time.ParseInLocation(time.DateOnly, data.From, time.Local)
+		if err != nil {
+			data.Error = "from: expected a date YYYY-MM-DD"
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
+		}
+
+		length := calc.Hours(lengthH)
+		rules := roster.rules(cfg)
+		for i := range availabilityDays {
+			data.Days = append(data.Days, from.AddDate(0, 0, i).Format("Mon 02"))
+		}
+		for _, m := range roster.Members {
+			row := availabilityRow{Name: m.Name}
+			for i := range availabilityDays {
+				day := from.AddDate(0, 0, i)
+				start := calc.DayOf(day).Time(rs)
+				row.Cells = append(row.Cells, m.eligible(r.Context(), start, length, rules))
+			}
+			data.Rows = append(data.Rows, row)
+		}
+		pages.render(w, r, tpl, http.StatusOK, data)
+	}
+}
+
+const availabilityHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Brand.Name}} - availability</title>
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 1200px; box-sizing: border-box; }
+    * { box-sizing: border-box; }
+    h2 { margin-top: 0; font-weight: 600; }
+    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
+    .hint { color: #666; font-size: 0.9em; }
+    form { display: flex; flex-wrap: wrap; gap: 12px; align-items: end; margin-bottom: 16px; }
+    label { display: block; font-size: 0.9em; margin-bottom: 4px; }
+    input, select { padding: 8px 10px; font-size: 1em; border: 1px solid #ccc; border-radius: 6px; }
+    button { padding: 9px 20px; background: #1976d2; color: #fff; border: none; border-radius: 6px; cursor: pointer; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { padding: 6px 8px; border: 1px solid #eee; text-align: left; vertical-align: top; font-size: 0.9em; }
+    td.ok { background: #e8f5e9; }
+    td.assigned { background: #e3f2fd; }
+    td.blocked { background: #ffebee; color: #b00020; }
+    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
+  </style>
+</head>
+<body>
+  <h2>Team availability</h2>
+  <form method="GET" action="{{.BasePath}}/availability">
+    <div><label for="roster">Roster</label><select id="roster" name="roster">{{range .Rosters}}<option{{if eq . $.Roster}} selected{{end}}>{{.}}</option>{{end}}</select></div>
+    <div><label for="start">Release start</label><input id="start" name="start" value="{{.Start}}" size="6"></div>
+    <div><label for="length">Length (hours)</label><input id="length" name="length" value="{{.Length}}" size="6"></div>
+    <div><label for="from">From</label><input id="from" name="from" type="date" value="{{.From}}"></div>
+    <button type="submit">Show</button>
+  </form>
+
+  {{if .Error}}<div class="err">{{.Error}}</div>{{end}}
+
+  {{if .Rows}}
+    <div class="hint">A release at {{.Start}} for {{.Length}}h each night, checked against everyone's stored releases and leave, the rest and the consecutive-night limit.</div>
+    <table>
+      <tr><th>Who</th>{{range .Days}}<th>{{.}}</th>{{end}}</tr>
+      {{range .Rows}}
+        <tr><td>{{.Name}}</td>{{range .Cells}}{{if .OK}}<td class="ok">free</td>{{else if .Assigned}}<td class="assigned">{{.Reason}}</td>{{else}}<td class="blocked">{{.Reason}}</td>{{end}}{{end}}</tr>
+      {{end}}
+    </table>
+  {{end}}
+
+  <footer>{{with .Brand.Footer}}<div>{{.}}</div>{{end}}nightrelcalc v{{.Version}}</footer>
+</body>
+</html>`
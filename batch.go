@@ -0,0 +1,404 @@
+package nightrelcalc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- batch: many releases from a file ---------------- */
+
+// batchColumns are the CSV columns a batch file may have, in any order; only
+// start is required. Empty cells take the flag (or profile) value.
+var batchColumns = []string{"date", "start", "length", "combine", "full", "normal_start", "normal_end", "min_rest", "max_overtime"}
+
+func newBatchCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts calcOptions
+		in   string
+		jobs int
+	)
+	cmd := &cobra.Command{
+		Use:   "batch [FILE|-]",
+		Short: "Calculate many releases from a CSV, JSON, NDJSON or ICS file",
+		Long: "Calculate one result per row of a CSV file with a header row. Columns:\n" +
+			"  " + strings.Join(batchColumns, ", ") + "\n" +
+			"start is required; date (YYYY-MM-DD) labels the row; empty cells take the\n" +
+			"value of the matching flag, e.g. --length for every row without one.\n\n" +
+			"A JSON array of /api/v1/calc requests (each with an optional date) is read\n" +
+			"as well, e.g. cat plan.json | nightrelcalc batch -, and the results are\n" +
+			"written as a JSON array unless --output says otherwise. NDJSON, one request\n" +
+			"per line, is read the same way and written as NDJSON.\n\n" +
+			"An iCalendar (.ics) file or http(s) URL gives one release per timed event:\n" +
+			"the event start is the release start and its duration the release length.\n\n" +
+			"Rows are computed --jobs at a time and the results written in the order of\n" +
+			"the input as they are done, so a batch of any length is read, computed and\n" +
+			"written in the same memory; --output ndjson gives one JSON object per line,\n" +
+			"for piping large batches into other tools.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			if err := opts.prepare(cmd.Flags(), cfg, *profileName); err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				if in != "" {
+					return fmt.Errorf("give the file as an argument or with --in, not both")
+				}
+				in = args[0]
+			}
+			if in == "" {
+				return fmt.Errorf("a CSV, JSON, NDJSON or ICS file is required (or - for stdin)")
+			}
+			var src io.Reader
+			switch {
+			case in == "-":
+				src = os.Stdin
+			case strings.HasPrefix(in, "http://") || strings.HasPrefix(in, "https://"):
+				body, err := fetchURL(cmd.Context(), in)
+				if err != nil {
+					return err
+				}
+				defer body.Close()
+				src = body
+			default:
+				f, err := os.Open(in)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				src = f
+			}
+
+			// Sniff the format from the start of the input; the rest is read row
+			// by row and each result written as soon as it is computed.
+			br := bufio.NewReader(src)
+			head, _ := br.Peek(512)
+			read, output := batchReader(readBatchCSV), ""
+			switch {
+			case bytes.HasPrefix(bytes.TrimSpace(head), []byte("[")):
+				read, output = readBatchJSON, "json"
+			case bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")):
+				read, output = readBatchNDJSON, "ndjson"
+			case isICS(head):
+				read = readBatchICS
+			}
+			if output != "" && !cmd.Flags().Changed("output") {
+				opts.Output = output
+			}
+			out := newBatchWriter(os.Stdout, opts)
+			emit := func(r datedResult) error {
+				// Stop between rows once the command is cancelled.
+				if err := cmd.Context().Err(); err != nil {
+					return err
+				}
+				if err := opts.checkNights(r); err != nil {
+					return err
+				}
+				return out.write(r)
+			}
+			if jobs <= 0 {
+				jobs = runtime.GOMAXPROCS(0)
+			}
+			if err := runBatch(cmd.Context(), jobs, br, read, opts, emit); err != nil {
+				return fmt.Errorf("%s: %w", in, err)
+			}
+			return out.close()
+		},
+	}
+	cmd.Flags().StringVar(&in, "in", "", "CSV, JSON, NDJSON or ICS file (or http(s) URL) with one release per row or event (- for stdin)")
+	cmd.Flags().IntVar(&jobs, "jobs", 0, "Rows to compute at once (0 = one per CPU)")
+	_ = cmd.MarkFlagFilename("in", "csv", "json", "ndjson", "ics")
+	opts.addFlags(cmd.Flags())
+	return cmd
+}
+
+// readBatchCSV reads every row of r on top of defaults and passes it to
+// add, stopping at the first bad row.
+func readBatchCSV(r io.Reader, defaults calcOptions, add func(batchRow) error) error {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("empty file, expected a header row")
+		}
+		return err
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(h)), "-", "_")
+		if !slices.Contains(batchColumns, name) {
+			return fmt.Errorf("unknown column %q (have: %s)", h, strings.Join(batchColumns, ", "))
+		}
+		cols[name] = i
+	}
+	if _, ok := cols["start"]; !ok {
+		return errors.New("missing the start column")
+	}
+	cr.FieldsPerRecord = len(header)
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		cell := func(name string) string {
+			if i, ok := cols[name]; ok {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		row := defaults
+		row.Start = cell("start")
+		num := func(name string, dst *float64) error {
+			s := cell(name)
+			if s == "" {
+				return nil
+			}
+			v, err := calc.ParseHours(s)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q, expected hours such as 4.5 or 4h30m", name, s)
+			}
+			*dst = v
+			return nil
+		}
+		for _, err := range []error{
+			num("length", &row.Length),
+			num("combine", &row.Combine),
+			num("full", &row.Full),
+			num("min_rest", &row.MinRest),
+			num("max_overtime", &row.MaxOvertime),
+		} {
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		row.NormalStart = orDefault(cell("normal_start"), row.NormalStart)
+		row.NormalEnd = orDefault(cell("normal_end"), row.NormalEnd)
+		date := cell("date")
+		if date != "" {
+			if _, err := time.Parse(time.DateOnly, date); err != nil {
+				return fmt.Errorf("line %d: invalid date %q, expected YYYY-MM-DD", line, date)
+			}
+		}
+		if row.Start == "" {
+			return fmt.Errorf("line %d: start is required (HH:MM)", line)
+		}
+		if err := add(batchRow{where: fmt.Sprintf("line %d", line), compute: func() (datedResult, error) {
+			res, err := row.compute()
+			return datedResult{Date: date, Result: res, req: row.request()}, err
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchRequest is one item of a JSON batch: an API request with a label.
+type batchRequest struct {
+	Date string `json:"date,omitempty"`
+	calcRequest
+}
+
+// readBatchJSON reads every request of a JSON array; omitted fields take
+// the flag (or profile) values, as empty CSV cells do.
+func readBatchJSON(r io.Reader, defaults calcOptions, add func(batchRow) error) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return errors.New("invalid JSON: expected an array of requests")
+	}
+	for i := 0; dec.More(); i++ {
+		var req batchRequest
+		if err := dec.Decode(&req); err != nil {
+			return fmt.Errorf("item %d: invalid JSON: %w", i, err)
+		}
+		row, err := req.row(fmt.Sprintf("item %d", i), defaults)
+		if err != nil {
+			return err
+		}
+		if err := add(row); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+// readBatchNDJSON reads one request per line, as in a JSON batch; blank
+// lines are skipped.
+func readBatchNDJSON(r io.Reader, defaults calcOptions, add func(batchRow) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxNDJSONLine)
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var req batchRequest
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			return fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		if dec.More() {
+			return fmt.Errorf("line %d: invalid JSON: more than one request", line)
+		}
+		row, err := req.row(fmt.Sprintf("line %d", line), defaults)
+		if err != nil {
+			return err
+		}
+		if err := add(row); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// maxNDJSONLine bounds one line of an NDJSON batch, and so what reading
+// it holds in memory.
+const maxNDJSONLine = 1 << 20
+
+// row is req with the omitted fields taken from defaults, computed later;
+// where labels its errors.
+func (req batchRequest) row(where string, defaults calcOptions) (batchRow, error) {
+	if req.Date != "" {
+		if _, err := time.Parse(time.DateOnly, req.Date); err != nil {
+			return batchRow{}, fmt.Errorf("%s: invalid date %q, expected YYYY-MM-DD", where, req.Date)
+		}
+	}
+	if req.Profile != "" {
+		return batchRow{}, fmt.Errorf("%s: profile is not a field of a row, give --profile for the whole batch", where)
+	}
+	if req.Length == 0 {
+		req.Length = defaults.Length
+	}
+	if req.Combine == nil && defaults.Combine >= 0 {
+		req.Combine = &defaults.Combine
+	}
+	if req.Full == 0 {
+		req.Full = defaults.Full
+	}
+	req.NormalStart = orDefault(req.NormalStart, defaults.NormalStart)
+	req.NormalEnd = orDefault(req.NormalEnd, defaults.NormalEnd)
+	if req.MinRest == nil {
+		req.MinRest = &defaults.MinRest
+	}
+	if req.MaxOvertime == nil {
+		req.MaxOvertime = &defaults.MaxOvertime
+	}
+	req.policy = InputPolicy{Granularity: defaults.Granularity, Strict: defaults.Strict}
+	req.rules = defaults.rules
+	return batchRow{where: where, compute: func() (datedResult, error) {
+		res, err := req.compute()
+		return datedResult{Date: req.Date, Result: res, req: req.calcRequest}, err
+	}}, nil
+}
+
+/* ---------------- batch pipeline ---------------- */
+
+// batchRow is one row of a batch input, read but not yet computed; where
+// labels its errors, e.g. "line 3".
+type batchRow struct {
+	where   string
+	compute func() (datedResult, error)
+}
+
+// batchReader reads the rows of one input format on top of defaults and
+// passes them to add in order, stopping at the first bad row or when add
+// fails.
+type batchReader func(r io.Reader, defaults calcOptions, add func(batchRow) error) error
+
+// runBatch reads src with read and computes its rows on jobs goroutines,
+// passing the results to emit in input order. At most a few rows per job
+// are read ahead of emit, so a batch takes the same memory however long
+// it is. It stops at the first row that fails, with the error of the
+// earliest, or when ctx is cancelled.
+func runBatch(ctx context.Context, jobs int, src io.Reader, read batchReader, defaults calcOptions, emit func(datedResult) error) error {
+	jobs = max(jobs, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		r   datedResult
+		err error
+	}
+	work := make(chan func(), jobs)
+	order := make(chan chan outcome, 2*jobs) // the rows read, in order
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Go(func() {
+			for f := range work {
+				f()
+			}
+		})
+	}
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(work)
+		defer close(order)
+		readErr <- read(src, defaults, func(row batchRow) error {
+			done := make(chan outcome, 1)
+			select {
+			case order <- done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			work <- func() {
+				r, err := row.compute()
+				if err != nil {
+					err = fmt.Errorf("%s: %w", row.where, err)
+				}
+				done <- outcome{r, err}
+			}
+			return nil
+		})
+	}()
+
+	var err error
+	for done := range order {
+		o := <-done
+		if err = o.err; err == nil {
+			err = emit(o.r)
+		}
+		if err != nil {
+			cancel()
+			break
+		}
+	}
+	// Let the reader see the cancel and the workers finish what they have.
+	for range order {
+	}
+	rerr := <-readErr
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return rerr
+}
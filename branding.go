@@ -0,0 +1,87 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+/* ---------------- operator branding ---------------- */
+
+// BrandingOptions are the operator's branding of the site, the --title,
+// --logo, --footer-text and --accent-color flags; see Brand for how the
+// pages show them.
+type BrandingOptions struct {
+	Title  string // page header and browser tab title
+	Logo   string // image file (served at /brand/logo) or http(s) URL
+	Footer string // text in the page footer, e.g. a legal notice
+	Accent string // #rgb or #rrggbb
+}
+
+func (o *BrandingOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Title, "title", "", "Instance title for the page header and browser tab (default nightrelcalc, no header)")
+	fs.StringVar(&o.Logo, "logo", "", "Logo image: a file (served at /brand/logo) or http(s) URL (other hosts need img-src in --csp)")
+	fs.StringVar(&o.Footer, "footer-text", "", "Text shown in the page footer (e.g. a mandatory legal notice)")
+	fs.StringVar(&o.Accent, "accent-color", "", "Accent color for buttons and highlights, #rgb or #rrggbb")
+}
+
+// Brand is the operator branding as the templates see it.
+type Brand struct {
+	Title  string // "" = no header, "nightrelcalc" in the tab
+	Logo   string // image URL
+	Footer string
+	Accent string // CSS color, "" = the built-in blue
+}
+
+// Name is the instance title, or nightrelcalc.
+func (b Brand) Name() string {
+	if b.Title != "" {
+		return b.Title
+	}
+	return "nightrelcalc"
+}
+
+var hexColorRe = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// loadBranding validates the options; a logo file is read once and served
+// from /brand/logo.
+func loadBranding(o BrandingOptions, basePath string, mux *http.ServeMux) (Brand, error) {
+	b := Brand{
+		Title:  strings.TrimSpace(o.Title),
+		Footer: strings.TrimSpace(o.Footer),
+		Accent: strings.TrimSpace(o.Accent),
+	}
+	if b.Accent != "" && !hexColorRe.MatchString(b.Accent) {
+		return b, fmt.Errorf("invalid --accent-color %q, expected #rgb or #rrggbb", o.Accent)
+	}
+
+	logo := strings.TrimSpace(o.Logo)
+	switch {
+	case logo == "":
+	case strings.HasPrefix(logo, "https://"), strings.HasPrefix(logo, "http://"):
+		b.Logo = logo
+	default:
+		body, err := os.ReadFile(logo)
+		if err != nil {
+			return b, fmt.Errorf("--logo: %w", err)
+		}
+		ct := mime.TypeByExtension(filepath.Ext(logo))
+		if ct == "" {
+			ct = http.DetectContentType(body)
+		}
+		if !strings.HasPrefix(ct, "image/") {
+			return b, fmt.Errorf("--logo %s: not an image (%s)", logo, ct)
+		}
+		f := newEmbeddedFile(logo, body, "public, max-age=86400")
+		f.contentType = ct
+		mux.Handle("/brand/logo", f)
+		b.Logo = basePath + "/brand/logo?v=" + f.hash
+	}
+	return b, nil
+}
@@ -0,0 +1,220 @@
+package nightrelcalc
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"nightrelcalc/calc"
+	"nightrelcalc/parse"
+)
+
+/* ---------------- bulk calculation from an uploaded CSV ---------------- */
+
+// Rows per upload, so one request cannot keep the server busy for long.
+const maxBulkRows = 500
+
+type BulkData struct {
+	CSV   string // the submitted CSV, carried along for the downloads
+	Rows  []datedResult
+	Error string
+
+	Version   string
+	BasePath  string
+	CSRFToken string
+	CSPNonce  string
+	Brand     Brand
+}
+
+// webBatchDefaults are the web form defaults for cells left empty, with
+// the config's extra scenarios (see Config.rules).
+func webBatchDefaults(rules []calc.Rule) calcOptions {
+	num := func(s string) float64 {
+		v, _ := parse.Number(s)
+		return v
+	}
+	return calcOptions{
+		Length:      num(webDefaultLength),
+		Combine:     -1,
+		NormalStart: webDefaultNormalStart,
+		NormalEnd:   webDefaultNormalEnd,
+		MinRest:     num(webDefaultMinRest),
+		MaxOvertime: num(webDefaultMaxOvertime),
+		rules:       rules,
+	}
+}
+
+// bulkCSV is the uploaded file, or else the pasted text.
+func bulkCSV(r *http.Request) (string, error) {
+	if f, _, err := r.FormFile("file"); err == nil {
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+		if len(strings.TrimSpace(string(b))) > 0 {
+			return string(b), nil
+		}
+	} else if !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart) {
+		return "", err
+	}
+	return r.PostFormValue("csv"), nil
+}
+
+// bulkHandler serves /bulk: GET shows the upload form; POST computes every
+// row and shows the results, or with format=csv|ics downloads them. Uploads
+// are limited as any request body, to maxBody bytes (--max-body-bytes; 0
+// for no limit), which the error for a larger one names.
+func bulkHandler(pages pageRenderer, tpl *template.Template, signer *cookieSigner, cfg Config, maxBody int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := BulkData{
+			Version:   appVersion,
+			BasePath:  pages.basePath,
+			CSRFToken: signer.ensureCSRF(w, r),
+			CSPNonce:  cspNonce(r),
+			Brand:     pages.brand,
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			pages.render(w, r, tpl, http.StatusOK, data)
+			return
+		case http.MethodPost:
+		default:
+			pages.methodNotAllowed(w, r, "GET, POST")
+			return
+		}
+
+		// The body is already capped, so it is all kept in memory up to the cap
+		if err := r.ParseMultipartForm(cmp.Or(maxBody, 32<<20)); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			if tooLarge := new(http.MaxBytesError); errors.As(err, &tooLarge) {
+				pages.error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("The upload is larger than the %d bytes this server takes (--max-body-bytes).", tooLarge.Limit))
+				return
+			}
+			pages.error(w, r, http.StatusBadRequest, "The upload could not be read: "+err.Error())
+			return
+		}
+		if !signer.checkCSRF(r) {
+			data.Error = "the form expired or was submitted from another site, please submit it again"
+			pages.render(w, r, tpl, http.StatusForbidden, data)
+			return
+		}
+		csvText, err := bulkCSV(r)
+		if err != nil {
+			pages.error(w, r, http.StatusBadRequest, "The upload could not be read: "+err.Error())
+			return
+		}
+		data.CSV = csvText
+		if strings.TrimSpace(csvText) == "" {
+			data.Error = "choose a CSV file or paste its contents"
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
+		}
+		var rows []datedResult
+		ctx := r.Context()
+		err = runBatch(ctx, 1, strings.NewReader(csvText), readBatchCSV, webBatchDefaults(cfg.rules(cfg.Defaults)), func(r datedResult) error {
+			if len(rows) == maxBulkRows {
+				return errors.New("too many rows, at most 500 per upload")
+			}
+			rows = append(rows, r)
+			return ctx.Err()
+		})
+		if ctx.Err() != nil {
+			pages.error(w, r, http.StatusServiceUnavailable, "The upload took too long to compute, try fewer rows.")
+			return
+		}
+		if err != nil {
+			data.Error = err.Error()
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
+		}
+		data.Rows = rows
+
+		switch r.PostFormValue("format") {
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="nightrelcalc-plans.csv"`)
+			_ = writeCSV(w, rows)
+		case "ics":
+			var b strings.Builder
+			if err := writeICS(&b, rows, appClock.Now()); err != nil {
+				data.Error = err.Error()
+				pages.render(w, r, tpl, http.StatusBadRequest, data)
+				return
+			}
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="nightrelcalc-plans.ics"`)
+			_, _ = io.WriteString(w, b.String())
+		default:
+			pages.render(w, r, tpl, http.StatusOK, data)
+		}
+	}
+}
+
+const bulkHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Brand.Name}} - bulk</title>
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 1200px; box-sizing: border-box; }
+    * { box-sizing: border-box; }
+    h2 { margin-top: 0; font-weight: 600; }
+    .mono { font-family: ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, "Liberation Mono", "Courier New", monospace; }
+    .card { border: 1px solid #ddd; border-radius: 8px; padding: 12px; margin: 12px 0; }
+    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
+    .hint { color: #666; font-size: 0.9em; }
+    label { display: block; font-size: 0.9em; margin: 8px 0 4px; }
+    textarea { width: 100%; min-height: 8em; padding: 8px 10px; border: 1px solid #ccc; border-radius: 6px; }
+    button { margin-top: 10px; padding: 9px 20px; background: #1976d2; color: #fff; border: none; border-radius: 6px; cursor: pointer; }
+    table { border-collapse: collapse; width: 100%; margin-top: 8px; }
+    th, td { padding: 4px 8px; border-bottom: 1px solid #eee; text-align: left; vertical-align: top; }
+    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
+  </style>
+</head>
+<body>
+  <h2>Bulk calculation</h2>
+  <form method="POST" action="{{.BasePath}}/bulk" enctype="multipart/form-data">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <div class="hint">A CSV with a header row; columns date, start, length, combine, full, normal_start, normal_end, min_rest, max_overtime (only start is required, empty cells take the form defaults).</div>
+    <label for="file">CSV file</label>
+    <input id="file" name="file" type="file" accept=".csv,text/csv">
+    <label for="csv">or paste it</label>
+    <textarea id="csv" name="csv" class="mono" placeholder="date,start,length&#10;2026-11-02,21:00,4">{{.CSV}}</textarea>
+    <button type="submit">Calculate</button>
+  </form>
+
+  {{if .Error}}<div class="err">{{.Error}}</div>{{end}}
+
+  {{if .Rows}}
+    <div class="card">
+      <table>
+        <tr><th>Date</th><th>Release</th><th>Scenario</th><th>Work</th><th>Overtime</th><th>Next day</th></tr>
+        {{range .Rows}}{{$r := .}}
+          {{range $i, $s := .Scenarios}}
+            <tr>
+              <td class="mono">{{if eq $i 0}}{{$r.Date}}{{end}}</td>
+              <td class="mono">{{if eq $i 0}}{{$r.ReleaseStart}} -> {{$r.ReleaseEnd}}{{end}}</td>
+              <td>{{$s.Title}}</td>
+              <td class="mono">{{$s.WorkHours}}</td>
+              <td class="mono">{{$s.Overtime}}</td>
+              <td class="mono">{{$s.NextDayHours}}</td>
+            </tr>
+          {{end}}
+        {{end}}
+      </table>
+    </div>
+    <form method="POST" action="{{.BasePath}}/bulk">
+      <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+      <textarea name="csv" hidden>{{.CSV}}</textarea>
+      <button type="submit" name="format" value="csv">Download CSV</button>
+      <button type="submit" name="format" value="ics">Download calendar (ICS)</button>
+    </form>
+  {{end}}
+
+  <footer>{{with .Brand.Footer}}<div>{{.}}</div>{{end}}nightrelcalc v{{.Version}}</footer>
+</body>
+</html>`
@@ -0,0 +1,35 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestBulkUploadLimit checks that an upload over --max-body-bytes is told
+// the limit.
+func TestBulkUploadLimit(t *testing.T) {
+	opts := DefaultWebOptions()
+	h, err := NewHandler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	f, _ := mw.CreateFormFile("file", "releases.csv")
+	io.WriteString(f, "date,start,length\n"+strings.Repeat("2026-10-20,22:00,3\n", 5000))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/bulk", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	page, _ := io.ReadAll(rec.Result().Body)
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(string(page), "65536 bytes") {
+		t.Errorf("status %d: %s", rec.Code, page)
+	}
+}
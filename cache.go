@@ -0,0 +1,140 @@
+package nightrelcalc
+
+import (
+	"container/list"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- result cache: shared URLs computed once ---------------- */
+
+// cachedPage is what the page shows for one canonical query: the outcome of
+// its calculation and, once rendered, its results partial (see
+// resultsTemplateName) for the page and for /results. Everything in it is
+// shared between requests and never changed after it is added.
+type cachedPage struct {
+	Error           string
+	Result          *calc.Result
+	ScenarioChoices []scenarioChoice
+	Scenarios       string
+	TeamResults     []teamResult
+	Handoffs        []handoffSegment
+
+	mu       sync.Mutex
+	fragment [2]template.HTML // by Partial; "" until rendered
+}
+
+func (p *cachedPage) html(partial bool) template.HTML {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fragment[boolIndex(partial)]
+}
+
+func (p *cachedPage) setHTML(partial bool, h template.HTML) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fragment[boolIndex(partial)] = h
+}
+
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// cachedResultsHTML is the results partial of d rendered with tpl, once per
+// cached calculation and then reused; "" when d has no calculation or tpl is
+// nil. A failed render is logged and left to the page to render again.
+func cachedResultsHTML(r *http.Request, tpl *template.Template, d PageData) template.HTML {
+	if d.cached == nil || tpl == nil {
+		return ""
+	}
+	if h := d.cached.html(d.Partial); h != "" {
+		return h
+	}
+	var b strings.Builder
+	if err := tpl.Execute(&b, d); err != nil {
+		log.Printf("render %s %s: %v", tpl.Name(), r.URL.Path, err)
+		return ""
+	}
+	h := template.HTML(b.String())
+	d.cached.setHTML(d.Partial, h)
+	return h
+}
+
+// resultCache keeps the size most recently used pages by key, the canonical
+// query with the profile it is computed under. A nil or zero-size cache
+// keeps nothing.
+type resultCache struct {
+	mu     sync.Mutex
+	size   int
+	order  *list.List // of *cacheEntry, most recently used first
+	byKey  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	key  string
+	page *cachedPage
+}
+
+func newResultCache(size int) *resultCache {
+	if size <= 0 {
+		return nil
+	}
+	return &resultCache{size: size, order: list.New(), byKey: map[string]*list.Element{}}
+}
+
+// get is the page cached under key, counting the hit or miss.
+func (c *resultCache) get(key string) (*cachedPage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byKey[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).page, true
+}
+
+// add caches p under key, dropping the least recently used page when full.
+func (c *resultCache) add(key string, p *cachedPage) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.byKey[key]; ok {
+		e.Value.(*cacheEntry).page = p
+		c.order.MoveToFront(e)
+		return
+	}
+	c.byKey[key] = c.order.PushFront(&cacheEntry{key: key, page: p})
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.byKey, last.Value.(*cacheEntry).key)
+	}
+}
+
+// counts are the hits and misses so far and the pages cached now.
+func (c *resultCache) counts() (hits, misses uint64, entries int) {
+	if c == nil {
+		return 0, 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, c.order.Len()
+}
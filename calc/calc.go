@@ -0,0 +1,708 @@
+// Package calc is the night release calculation: clock math only, no I/O,
+// so it also builds for GOOS=js GOARCH=wasm (see calc/wasm).
+package calc
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"nightrelcalc/parse"
+)
+
+// Scenario is one way to schedule the release day, formatted for display.
+type Scenario struct {
+	Title string `json:"title"`
+
+	WorkHours     string `json:"work_hours"`     // Start -> End (regular)
+	ReleaseWindow string `json:"release_window"` // Start -> End (release)
+	TotalWork     string `json:"total_work"`     // Start -> End (regular + overtime)
+
+	ReleaseIncluded string `json:"release_included"` // e.g. 4h00m
+	Overtime        string `json:"overtime"`         // e.g. 0h00m
+
+	NextDayHours string `json:"next_day_hours"` // Start -> End (normal window length)
+
+	TOIL string `json:"toil,omitempty"` // time off in lieu banked, e.g. 1h00m or -0h30m (owed); "" if none
+
+	// Notes are the limits that shaped the scenario, e.g. a work start
+	// pulled later by the overtime cap; shown as footnotes.
+	Notes []string `json:"notes,omitempty"`
+	// Warnings are checks the scenario fails, e.g. CheckCoreHours.
+	Warnings []string `json:"warnings,omitempty"`
+
+	Timeline Timeline `json:"-"`
+}
+
+// Timeline is a scenario's blocks as offsets from 00:00 of the release day
+// (see Offset; an Anchor places them on a date).
+type Timeline struct {
+	WorkStart, WorkEnd       Offset
+	ReleaseStart, ReleaseEnd Offset
+	NextStart, NextEnd       Offset
+	RestEnd                  Offset // the mandatory rest after the release is over
+}
+
+// Result is a calculation: the release, the work day used and its scenarios.
+type Result struct {
+	ReleaseStart string `json:"release_start"`
+	ReleaseEnd   string `json:"release_end"`
+	ReleaseLen   string `json:"release_len"`
+
+	FullDay string `json:"full_day"`
+
+	NormalStart string `json:"normal_start"`
+	NormalEnd   string `json:"normal_end"`
+	NormalLen   string `json:"normal_len"`
+
+	MinRest     string `json:"min_rest"`
+	Commute     string `json:"commute,omitempty"` // each way, around the rest; "" if none
+	MaxOvertime string `json:"max_overtime"`
+	PartTime    string `json:"part_time,omitempty"` // e.g. "80%, 34h00m a week"; "" for full time
+
+	// Adjustments are inputs changed to fit, e.g. a combine longer than the
+	// release; they are also notes of the scenarios concerned.
+	Adjustments []string `json:"adjustments,omitempty"`
+
+	Scenarios []Scenario `json:"scenarios"`
+}
+
+// Rule is an extra scenario described by its parameters instead of code,
+// e.g. from a config file; it is computed after the built-in ones.
+type Rule struct {
+	Title string `json:"title"`
+
+	// Include is how many hours of the release count towards the full day,
+	// at most the release and the full day; nil includes as much as fits.
+	Include *float64 `json:"include,omitempty"`
+	// Split keeps the normal start: the rest of the full day is worked
+	// from there and the gap until the release is unpaid. Otherwise the
+	// work day shifts to run straight into the release.
+	Split bool `json:"split,omitempty"`
+	// IgnoreOvertimeCap allows more overtime than the maximum instead of
+	// including more of the release to stay within it.
+	IgnoreOvertimeCap bool `json:"ignore_overtime_cap,omitempty"`
+}
+
+// Validate reports a rule that cannot be computed.
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Title) == "" {
+		return fmt.Errorf("scenario title is required")
+	}
+	if r.Include != nil && *r.Include < 0 {
+		return fmt.Errorf("scenario %q: include must be >= 0", r.Title)
+	}
+	return nil
+}
+
+// Compute returns the scenarios for a release starting at startStr (HH:MM)
+// lasting lengthH hours. combineH < 0 omits the combine scenario and
+// fullH <= 0 derives the full day from the normal day; commuteH is the
+// travel on each side of the rest, which does not count as rest. partTime
+// ("80%" or "30h" a week, see ParsePartTime; "" for full time) pro-rates the
+// full day, the next day and the overtime cap. Rules add scenarios after the
+// built-in ones.
+func Compute(startStr string, lengthH, combineH, fullH float64, normalStartStr, normalEndStr string, minRestH, commuteH, maxOvertimeH float64, partTime string, rules ...Rule) (*Result, error) {
+	rs, err := ParseClock(startStr)
+	if err != nil {
+		return nil, err
+	}
+	if lengthH <= 0 {
+		return nil, fmt.Errorf("length must be > 0")
+	}
+
+	ns, err := ParseClock(normalStartStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --normal-start: %w", err)
+	}
+	ne, err := ParseClock(normalEndStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --normal-end: %w", err)
+	}
+	normalLen := ne.Sub(ns)
+	if normalLen <= 0 {
+		return nil, fmt.Errorf("normal day must be within same day and end after start (e.g. 09:00 -> 17:30)")
+	}
+
+	minRest := Hours(minRestH)
+	if minRest <= 0 {
+		return nil, fmt.Errorf("min rest must be > 0")
+	}
+
+	commute := Hours(commuteH)
+	if commute < 0 {
+		return nil, fmt.Errorf("commute must be >= 0")
+	}
+	// The earliest next start is after the way home, the rest and the way in
+	rest := minRest + 2*commute
+
+	maxOvertime := Hours(maxOvertimeH)
+	if maxOvertime < 0 {
+		return nil, fmt.Errorf("max overtime must be >= 0")
+	}
+
+	releaseLen := Hours(lengthH)
+
+	// Full day: derive from normal day unless explicitly provided and >0
+	fullDay := normalLen
+	if fullH > 0 {
+		fullDay = Hours(fullH)
+	}
+
+	// Part time: a share of the full-time day, week and overtime cap
+	percent, weeklyH, err := ParsePartTime(partTime)
+	if err != nil {
+		return nil, err
+	}
+	nextLen := normalLen
+	partTimeText := ""
+	if percent > 0 || weeklyH > 0 {
+		fullWeek := WorkWeekDays * fullDay
+		if weeklyH > 0 {
+			percent = float64(Hours(weeklyH)) * 100 / float64(fullWeek)
+		}
+		if percent > 100 {
+			return nil, fmt.Errorf("part time must be at most full time (%s a week)", FmtDuration(fullWeek))
+		}
+		share := percent / 100
+		fullDay = scale(fullDay, share)
+		nextLen = scale(normalLen, share)
+		maxOvertime = scale(maxOvertime, share)
+		partTimeText = fmt.Sprintf("%s%%, %s a week", strconv.FormatFloat(math.Round(percent*10)/10, 'f', -1, 64),
+			FmtDuration(scale(fullWeek, share)))
+	}
+
+	reEnd := rs.Add(releaseLen)
+	releaseWindow := FmtRange(rs, reEnd)
+
+	// Next-day: start = max(next day normal-start, releaseEnd+minRest)
+	// end = start + normal day length
+	nextStart := nextDayStart(reEnd, ns, rest)
+	nextEnd := nextStart.Add(nextLen)
+	nextDayHours := FmtRange(nextStart, nextEnd)
+
+	// notes collects the non-empty notes of a scenario, plus how the next
+	// day's start was set when the rest, not the normal start, decided it.
+	restText := FmtDuration(minRest) + " rest"
+	if commute > 0 {
+		restText += " and 2 x " + FmtDuration(commute) + " commute"
+	}
+	nextNote := ""
+	if baseline := At(reEnd.Day()+1, ns); nextStart > baseline {
+		nextNote = fmt.Sprintf("next day starts at %s, set by the %s, not by the %s normal start",
+			nextStart, restText, ns)
+	}
+	notes := func(ns ...string) []string {
+		var out []string
+		for _, n := range ns {
+			if n != "" {
+				out = append(out, n)
+			}
+		}
+		return out
+	}
+	overCap := func(ot time.Duration) string {
+		if ot <= maxOvertime {
+			return ""
+		}
+		return fmt.Sprintf("overtime is %s over the %s cap", FmtDuration(ot-maxOvertime), FmtDuration(maxOvertime))
+	}
+	pulledLater := func(from, to time.Duration) string {
+		return fmt.Sprintf("%s of the release counted as work instead of %s, so the work start is %s later, to respect the %s overtime cap",
+			FmtDuration(to), FmtDuration(from), FmtDuration(to-from), FmtDuration(maxOvertime))
+	}
+
+	scenarios := make([]Scenario, 0, 8+len(rules))
+	var adjustments []string
+
+	// 1) Full day (release included as much as possible)
+	// Legal cap: include at least (releaseLen - maxOvertime) so OT <= maxOvertime; pull work start later if needed
+	requiredIncluded := max(0, releaseLen-maxOvertime)
+	inc := min(fullDay, max(requiredIncluded, min(releaseLen, fullDay)))
+	pre := fullDay - inc
+	workStart := rs.Add(-pre)
+	workEnd := rs.Add(inc)
+	ot := max(releaseLen-inc, 0)
+
+	scenarios = append(scenarios, Scenario{
+		Title:           "Full day (release included) - No Overtime",
+		WorkHours:       FmtRange(workStart, workEnd),
+		ReleaseWindow:   releaseWindow,
+		TotalWork:       FmtRange(workStart, reEnd),
+		ReleaseIncluded: FmtDuration(inc),
+		Overtime:        FmtDuration(ot),
+		NextDayHours:    nextDayHours,
+		Timeline:        Timeline{WorkStart: workStart, WorkEnd: workEnd, ReleaseStart: rs, ReleaseEnd: reEnd, NextStart: nextStart, NextEnd: nextEnd, RestEnd: reEnd.Add(rest)},
+		Notes:           notes(overCap(ot), nextNote),
+	})
+
+	// 2) Full day + release (all overtime) — cap OT at max by pulling work start later
+	ot2 := releaseLen
+	workStart2 := rs.Add(-fullDay)
+	workEnd2 := rs
+	capNote2 := ""
+	if ot2 > maxOvertime {
+		capNote2 = fmt.Sprintf("work ends at %s instead of at the release start, %s later, to respect the %s overtime cap",
+			reEnd.Add(-maxOvertime), FmtDuration(ot2-maxOvertime), FmtDuration(maxOvertime))
+		// End work (releaseEnd - maxOvertime) so only maxOvertime is OT after work
+		workEnd2 = reEnd.Add(-maxOvertime)
+		workStart2 = workEnd2.Add(-fullDay)
+		ot2 = maxOvertime
+	}
+	scenarios = append(scenarios, Scenario{
+		Title:           "Full day + release (Overtime)",
+		WorkHours:       FmtRange(workStart2, workEnd2),
+		ReleaseWindow:   releaseWindow,
+		TotalWork:       FmtRange(workStart2, reEnd),
+		ReleaseIncluded: FmtDuration(0),
+		Overtime:        FmtDuration(ot2),
+		NextDayHours:    nextDayHours,
+		Timeline:        Timeline{WorkStart: workStart2, WorkEnd: workEnd2, ReleaseStart: rs, ReleaseEnd: reEnd, NextStart: nextStart, NextEnd: nextEnd, RestEnd: reEnd.Add(rest)},
+		Notes:           notes(capNote2, nextNote),
+	})
+
+	// 3) Full day + combine + rest (only if combine set)
+	if combineH >= 0 {
+		x := min(Hours(combineH), releaseLen, fullDay)
+		combineNote := ""
+		if asked := Hours(combineH); x < asked {
+			limit := "release length"
+			if x < releaseLen {
+				limit = "full day"
+			}
+			combineNote = fmt.Sprintf("combine %s reduced to the %s %s", FmtDuration(asked), limit, FmtDuration(x))
+			adjustments = append(adjustments, combineNote)
+		}
+		combined := x // the title's split, before the overtime cap moves it
+
+		pre3 := fullDay - x
+		workStart3 := rs.Add(-pre3)
+		workEnd3 := rs.Add(x)
+		ot3 := releaseLen - x
+		capNote3 := ""
+		if ot3 > maxOvertime {
+			// Pull work start later: include more of release so OT <= max
+			asked := x
+			x = max(releaseLen-maxOvertime, 0)
+			x = min(x, fullDay)
+			pre3 = fullDay - x
+			workStart3 = rs.Add(-pre3)
+			workEnd3 = rs.Add(x)
+			ot3 = releaseLen - x
+			capNote3 = pulledLater(asked, x)
+		}
+
+		scenarios = append(scenarios, Scenario{
+			Title:           fmt.Sprintf("Full day + %.2fh + %.2fh", combined.Hours(), (releaseLen - combined).Hours()),
+			WorkHours:       FmtRange(workStart3, workEnd3),
+			ReleaseWindow:   releaseWindow,
+			TotalWork:       FmtRange(workStart3, reEnd),
+			ReleaseIncluded: FmtDuration(x),
+			Overtime:        FmtDuration(ot3),
+			NextDayHours:    nextDayHours,
+			Timeline:        Timeline{WorkStart: workStart3, WorkEnd: workEnd3, ReleaseStart: rs, ReleaseEnd: reEnd, NextStart: nextStart, NextEnd: nextEnd, RestEnd: reEnd.Add(rest)},
+			Notes:           notes(combineNote, capNote3, overCap(ot3), nextNote),
+		})
+	}
+
+	// rule computes r like the combine scenario for a work day of day;
+	// split reports whether the day was split around the gap (r.Split and
+	// the morning ends in time).
+	rule := func(r Rule, day time.Duration) (sc Scenario, split bool) {
+		x := min(releaseLen, day)
+		if r.Include != nil {
+			x = min(Hours(*r.Include), x)
+		}
+		capNote := ""
+		if !r.IgnoreOvertimeCap && releaseLen-x > maxOvertime {
+			asked := x
+			x = min(max(releaseLen-maxOvertime, 0), day)
+			if x > asked {
+				capNote = pulledLater(asked, x)
+			}
+		}
+		pre := day - x
+		workStart, workEnd := rs.Add(-pre), rs.Add(x)
+		workHours := FmtRange(workStart, workEnd)
+		// Only split when there is a morning part and it ends before the release starts
+		if split = r.Split && pre > 0 && ns.Add(pre) < rs; split {
+			workStart, workEnd = ns, ns.Add(pre)
+			workHours = FmtRange(workStart, workEnd)
+			if x > 0 {
+				workHours += " + " + FmtRange(rs, rs.Add(x))
+			}
+		}
+		return Scenario{
+			Title:           r.Title,
+			WorkHours:       workHours,
+			ReleaseWindow:   releaseWindow,
+			TotalWork:       FmtRange(workStart, reEnd),
+			ReleaseIncluded: FmtDuration(x),
+			Overtime:        FmtDuration(releaseLen - x),
+			NextDayHours:    nextDayHours,
+			Timeline:        Timeline{WorkStart: workStart, WorkEnd: workEnd, ReleaseStart: rs, ReleaseEnd: reEnd, NextStart: nextStart, NextEnd: nextEnd, RestEnd: reEnd.Add(rest)},
+			Notes:           notes(capNote, overCap(releaseLen-x), nextNote),
+		}, split
+	}
+
+	// 4) Split shift: the morning of the normal day, the release as the rest
+	// of the full day; the gap in between is unpaid. Without a gap it is the
+	// unsplit day, noted as such, so that the scenarios after it keep their
+	// numbers whatever the inputs
+	split, isSplit := rule(Rule{Split: true}, fullDay)
+	if isSplit {
+		split.Title = fmt.Sprintf("Split shift (%s unpaid gap before the release)", FmtDuration(split.Timeline.ReleaseStart.Sub(split.Timeline.WorkEnd)))
+	} else {
+		split.Title = "Split shift (not applicable)"
+		why := fmt.Sprintf("not applicable: a morning from the %s normal start would run into the release, so the day is not split", ns)
+		if split.Timeline.WorkStart == rs {
+			why = "not applicable: the release takes up the whole full day, so there is no morning to split off"
+		}
+		split.Notes = append([]string{why}, split.Notes...)
+	}
+	scenarios = append(scenarios, split)
+
+	// 5) Half day: half the full day right before the release, the other half
+	// taken as leave; the release is overtime (or TOIL) up to the cap
+	halfDay := (fullDay / 2).Truncate(time.Minute)
+	none := 0.0
+	half, _ := rule(Rule{Include: &none}, halfDay)
+	half.Title = fmt.Sprintf("Half day (%s leave) + release (Overtime/TOIL)", FmtDuration(fullDay-halfDay))
+	scenarios = append(scenarios, half)
+
+	// 6) Rest day: no regular work before the release; it is all overtime,
+	// but for what the cap does not allow, which counts as shifted hours
+	shifted := min(max(releaseLen-maxOvertime, 0), fullDay)
+	restDay, _ := rule(Rule{}, shifted)
+	restDay.Title = "Rest day + release (Overtime)"
+	if shifted == 0 {
+		restDay.WorkHours = "none (day off)"
+	} else {
+		restDay.Title = fmt.Sprintf("Rest day + release (%s shifted hours, rest Overtime)", FmtDuration(shifted))
+		restDay.Notes = append([]string{fmt.Sprintf("%s of the release are shifted work hours, to respect the %s overtime cap",
+			FmtDuration(shifted), FmtDuration(maxOvertime))}, restDay.Notes...)
+	}
+	scenarios = append(scenarios, restDay)
+
+	// 7) Full day + release (Overtime) paid back the next day: it starts as
+	// usual (or when the rest is over) and is the next day's length, less
+	// the overtime
+	short := scenarios[1]
+	short.Timeline.NextEnd = max(nextEnd.Add(-ot2), nextStart)
+	short.NextDayHours = FmtRange(nextStart, short.Timeline.NextEnd)
+	short.Title = fmt.Sprintf("Full day + release (Overtime), next day %s shorter", FmtDuration(ot2))
+	scenarios = append(scenarios, short)
+
+	// 8) Full day + release (Overtime), then sleep in: the next day, as for
+	// the others, work starts when the rest is over and ends when a next
+	// day's length from the normal start would. The morning missed is paid from the overtime;
+	// what is left is banked as TOIL (or owed, if the rest took more)
+	sleep := scenarios[1]
+	sleep.Notes = notes(capNote2)
+	restEnd := reEnd.Add(rest)
+	sleepStart := At(reEnd.Day()+1, ns)
+	sleep.Timeline.NextStart = max(sleepStart, restEnd)
+	sleep.Timeline.NextEnd = max(sleepStart.Add(nextLen), sleep.Timeline.NextStart)
+	sleep.NextDayHours = FmtRange(sleep.Timeline.NextStart, sleep.Timeline.NextEnd)
+	sleepIn := sleep.Timeline.NextStart.Sub(sleepStart)
+	if sleepIn > 0 {
+		sleep.Notes = append(sleep.Notes, fmt.Sprintf("next day starts at %s, %s after the %s normal start, when the %s is over",
+			sleep.Timeline.NextStart, FmtDuration(sleepIn), ns, restText))
+	}
+	toil := ot2 - sleepIn
+	sleep.TOIL = FmtDuration(toil)
+	if toil < 0 {
+		sleep.TOIL = "-" + sleep.TOIL
+	}
+	sleep.Title = fmt.Sprintf("Full day + release (Overtime), sleep in, TOIL balance %s", sleep.TOIL)
+	scenarios = append(scenarios, sleep)
+
+	// 9) Rules from the caller
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		sc, _ := rule(r, fullDay)
+		scenarios = append(scenarios, sc)
+	}
+
+	commuteText := ""
+	if commute > 0 {
+		commuteText = FmtDuration(commute)
+	}
+
+	return &Result{
+		ReleaseStart: rs.String(),
+		ReleaseEnd:   reEnd.String(),
+		ReleaseLen:   FmtDuration(releaseLen),
+
+		FullDay: FmtDuration(fullDay),
+
+		NormalStart: ns.String(),
+		NormalEnd:   ne.String(),
+		NormalLen:   FmtDuration(normalLen),
+
+		MinRest:     FmtDuration(minRest),
+		Commute:     commuteText,
+		MaxOvertime: FmtDuration(maxOvertime),
+		PartTime:    partTimeText,
+		Adjustments: adjustments,
+
+		Scenarios: scenarios,
+	}, nil
+}
+
+/* ---------------- part time ---------------- */
+
+// WorkWeekDays are the days of the full-time week that weekly part-time
+// hours are spread over.
+const WorkWeekDays = 5
+
+// ParsePartTime reads a part-time schedule: a percentage of full time
+// ("80%") or hours a week ("30h"). "" is full time (both 0).
+func ParsePartTime(s string) (percent, weeklyH float64, err error) {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return 0, 0, nil
+	}
+	var v float64
+	if num, ok := strings.CutSuffix(t, "%"); ok {
+		v, err = parse.Number(num)
+		percent = v
+	} else if num, ok := strings.CutSuffix(t, "h"); ok {
+		v, err = parse.Number(num)
+		weeklyH = v
+	} else {
+		err = fmt.Errorf("no unit")
+	}
+	if err != nil || v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, 0, fmt.Errorf("invalid part time %q, expected a percentage such as 80%% or weekly hours such as 30h", s)
+	}
+	if percent > 100 {
+		return 0, 0, fmt.Errorf("invalid part time %q, at most 100%%", s)
+	}
+	return percent, weeklyH, nil
+}
+
+/* ---------------- core hours ---------------- */
+
+// ParseClockRange parses "HH:MM-HH:MM" within one day.
+func ParseClockRange(s string) (start, end Offset, err error) {
+	a, b, err := parse.ClockRange(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	start, end = Offset(a), Offset(b)
+	if end <= start {
+		return 0, 0, fmt.Errorf("invalid range %q, the end must be after the start", s)
+	}
+	return start, end, nil
+}
+
+// CheckCoreHours warns on every scenario of res whose next day starts after
+// the core hours (HH:MM-HH:MM, e.g. 10:00-15:00) of that day begin, with the
+// alternatives that would make it in time.
+func CheckCoreHours(res *Result, core string) error {
+	cs, ce, err := ParseClockRange(core)
+	if err != nil {
+		return fmt.Errorf("core hours: %w", err)
+	}
+	for i := range res.Scenarios {
+		s := &res.Scenarios[i]
+		tl := s.Timeline
+		from, to := At(tl.NextStart.Day(), cs), At(tl.NextStart.Day(), ce)
+		late := tl.NextStart.Sub(from)
+		if late <= 0 {
+			continue
+		}
+		w := fmt.Sprintf("next day starts at %s, %s into the %s core hours", tl.NextStart, FmtDuration(late), FmtRange(from, to))
+		if tl.NextStart >= to {
+			w = fmt.Sprintf("next day starts at %s, after the %s core hours", tl.NextStart, FmtRange(from, to))
+		}
+		s.Warnings = append(s.Warnings, w+"; consider "+startByAlternatives(tl, from))
+	}
+	return nil
+}
+
+// ParseCommitment reads a next-day commitment: "HH:MM" and an optional
+// label, e.g. "10:00 customer call". The label defaults to "commitment".
+func ParseCommitment(s string) (at Offset, label string, err error) {
+	c, label, err := parse.LeadingClock(s)
+	if err != nil {
+		return 0, "", fmt.Errorf("commitment: %w", err)
+	}
+	if at = Offset(c); label == "" {
+		label = "commitment"
+	}
+	return at, label, nil
+}
+
+// CheckCommitment warns on every scenario of res whose next day starts
+// after an immovable commitment of that day (see ParseCommitment), with
+// the longest release that would still make it.
+func CheckCommitment(res *Result, commitment string) error {
+	at, label, err := ParseCommitment(commitment)
+	if err != nil {
+		return err
+	}
+	for i := range res.Scenarios {
+		s := &res.Scenarios[i]
+		tl := s.Timeline
+		due := At(tl.NextStart.Day(), at)
+		late := tl.NextStart.Sub(due)
+		if late <= 0 {
+			continue
+		}
+		w := fmt.Sprintf("next day starts at %s, %s after the %s at %s", tl.NextStart, FmtDuration(late), label, due)
+		s.Warnings = append(s.Warnings, w+"; consider "+startByAlternatives(tl, due))
+	}
+	return nil
+}
+
+// CheckMaxDay warns on every scenario of res whose day, the work and the
+// release together, is longer than maxDayH hours, e.g. a medical limit of
+// one person's.
+func CheckMaxDay(res *Result, maxDayH float64) error {
+	limit := Hours(maxDayH)
+	if limit <= 0 {
+		return fmt.Errorf("max day must be > 0")
+	}
+	for i := range res.Scenarios {
+		s := &res.Scenarios[i]
+		tl := s.Timeline
+		overlap := max(0, min(tl.WorkEnd, tl.ReleaseEnd).Sub(max(tl.WorkStart, tl.ReleaseStart)))
+		day := tl.WorkEnd.Sub(tl.WorkStart) + tl.ReleaseEnd.Sub(tl.ReleaseStart) - overlap
+		if day > limit {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("the day is %s of work and release, %s over the %s limit", FmtDuration(day), FmtDuration(day-limit), FmtDuration(limit)))
+		}
+	}
+	return nil
+}
+
+// CheckNextDay runs CheckCoreHours and CheckCommitment for those of
+// coreHours and commitment that are set.
+func CheckNextDay(res *Result, coreHours, commitment string) error {
+	if coreHours != "" {
+		if err := CheckCoreHours(res, coreHours); err != nil {
+			return err
+		}
+	}
+	if commitment != "" {
+		return CheckCommitment(res, commitment)
+	}
+	return nil
+}
+
+// startByAlternatives are the changes that let the next day of tl start by
+// deadline: the rest (and commute) must be over by then.
+func startByAlternatives(tl Timeline, deadline Offset) string {
+	if tl.RestEnd <= deadline {
+		return fmt.Sprintf("starting the next day early, the rest is over at %s", tl.RestEnd)
+	}
+	late := tl.RestEnd.Sub(deadline)
+	var alts []string
+	if maxLen := deadline.Sub(tl.ReleaseStart) - tl.RestEnd.Sub(tl.ReleaseEnd); maxLen > 0 {
+		alts = append(alts, "a release of at most "+FmtDuration(maxLen))
+	}
+	if earlier := tl.ReleaseStart.Add(-late); earlier >= 0 {
+		alts = append(alts, fmt.Sprintf("starting the release at %s (%s earlier)", earlier, FmtDuration(late)))
+	}
+	alts = append(alts, "someone else doing the release")
+	return strings.Join(alts, ", or ")
+}
+
+/* ---------------- night work ---------------- */
+
+// NightStart and NightEnd bound the night: work between them is night work.
+const (
+	NightStart = Offset(23 * time.Hour)
+	NightEnd   = Offset(6 * time.Hour)
+)
+
+// IsNightWork reports whether the release of tl overlaps a night.
+func IsNightWork(tl Timeline) bool {
+	for day := tl.ReleaseStart.Day() - 1; day <= tl.ReleaseEnd.Day(); day++ {
+		from, to := At(day, NightStart), At(day+1, NightEnd)
+		if tl.ReleaseStart < to && tl.ReleaseEnd > from {
+			return true
+		}
+	}
+	return false
+}
+
+/* ---------------- granularity ---------------- */
+
+// Granularities are the supported time steps in minutes; 1 (any minute) is
+// the default.
+var Granularities = []int{1, 5, 10, 15}
+
+// CheckGranularity reports a step (in minutes) not in Granularities.
+func CheckGranularity(stepMin int) error {
+	if !slices.Contains(Granularities, stepMin) {
+		return fmt.Errorf("unsupported granularity %dm, expected 1m, 5m, 10m or 15m", stepMin)
+	}
+	return nil
+}
+
+// ClockOnGrid reports a clock time (HH:MM) that is not a multiple of stepMin
+// minutes.
+func ClockOnGrid(clock string, stepMin int) error {
+	at, err := ParseClock(clock)
+	if err != nil {
+		return err
+	}
+	if stepMin > 1 && time.Duration(at)%(time.Duration(stepMin)*time.Minute) != 0 {
+		return fmt.Errorf("%s is not a multiple of %dm", at, stepMin)
+	}
+	return nil
+}
+
+// HoursOnGrid reports a duration in hours that is not a whole number of
+// minutes, or not a multiple of stepMin of them.
+func HoursOnGrid(h float64, stepMin int) error {
+	d := Hours(h)
+	if math.Abs(h*60-d.Minutes()) > 1e-6 {
+		return fmt.Errorf("%s hours is not a whole number of minutes", strconv.FormatFloat(h, 'f', -1, 64))
+	}
+	if stepMin > 1 && d%(time.Duration(stepMin)*time.Minute) != 0 {
+		return fmt.Errorf("%s is not a multiple of %dm", FmtDuration(d), stepMin)
+	}
+	return nil
+}
+
+// nextDayStart is the later of the normal start of the day after the
+// release ends and the end of the rest after it.
+func nextDayStart(releaseEnd, normalStart Offset, rest time.Duration) Offset {
+	return max(At(releaseEnd.Day()+1, normalStart), releaseEnd.Add(rest))
+}
+
+/* ---------------- helpers ---------------- */
+
+// FmtRange formats two offsets as "HH:MM -> HH:MM (+Nd)".
+func FmtRange(a, b Offset) string {
+	return a.String() + " -> " + b.String()
+}
+
+// ParseHours reads a duration as decimal hours ("4.5", also "4,5") or in
+// time.ParseDuration style ("4h30m", "11h", "90m"); see parse.Hours.
+func ParseHours(s string) (float64, error) {
+	return parse.Hours(s)
+}
+
+// ParseClock parses a time of day ("21:30", "2130", "9:30pm"; see
+// parse.Clock) into a time of the release day.
+func ParseClock(s string) (Offset, error) {
+	c, err := parse.Clock(s)
+	return Offset(c), err
+}
+
+// FmtDuration formats d as "4h30m" in whole minutes, ignoring the sign.
+func FmtDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	return fmt.Sprintf("%dh%02dm", d/time.Hour, d%time.Hour/time.Minute)
+}
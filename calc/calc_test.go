@@ -0,0 +1,41 @@
+package calc
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPartTimeNextDay checks that the scenarios moving the next day keep
+// its part-time length: at 50% the 09:00-17:30 day is 4h15m.
+func TestPartTimeNextDay(t *testing.T) {
+	res, err := Compute("22:00", 2, -1, 0, "09:00", "17:30", 11, 0, 4, "50%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const nextLen = 4*time.Hour + 15*time.Minute
+
+	short, sleep := res.Scenarios[5].Timeline, res.Scenarios[6].Timeline
+	if got, want := short.NextEnd.Sub(short.NextStart), nextLen-2*time.Hour; got != want {
+		t.Errorf("next day 2h00m shorter: %s -> %s is %s, want %s", short.NextStart, short.NextEnd, FmtDuration(got), FmtDuration(want))
+	}
+	if got, want := sleep.NextEnd.Sub(At(sleep.NextStart.Day(), 9*Offset(time.Hour))), nextLen; got != want {
+		t.Errorf("sleep in: %s -> %s ends %s after the normal start, want %s", sleep.NextStart, sleep.NextEnd, FmtDuration(got), FmtDuration(want))
+	}
+}
+
+// TestSleepInAfterMidnight checks that a release after midnight is slept
+// off on the next day, as the other scenarios have it, not on the release
+// day the rest ends on.
+func TestSleepInAfterMidnight(t *testing.T) {
+	res, err := Compute("01:00", 1, -1, 0, "09:00", "17:30", 11, 0, 4, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, sleep := res.Scenarios[0], res.Scenarios[6]
+	if sleep.NextDayHours != full.NextDayHours {
+		t.Errorf("sleep in: next day %s, want %s as in %q", sleep.NextDayHours, full.NextDayHours, full.Title)
+	}
+	if sleep.TOIL != res.Scenarios[1].Overtime {
+		t.Errorf("sleep in: TOIL %s, want the %s overtime", sleep.TOIL, res.Scenarios[1].Overtime)
+	}
+}
@@ -0,0 +1,106 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/* ---------------- time model ---------------- */
+
+// Day is the length of a calendar day on the release's clock.
+const Day = 24 * time.Hour
+
+// Offset is a point on the release's calendar: the wall-clock time since
+// 00:00 of the release day, from Day on on the days after it and negative
+// on those before. An Anchor puts it on a date.
+type Offset time.Duration
+
+// At is the offset of clock (a time of day) on the day days after the
+// release day.
+func At(days int, clock Offset) Offset {
+	return Offset(time.Duration(days)*Day) + clock
+}
+
+// Day is the day o is on: 0 for the release day, 1 for the next.
+func (o Offset) Day() int {
+	d := o / Offset(Day)
+	if o < 0 && o%Offset(Day) != 0 {
+		d--
+	}
+	return int(d)
+}
+
+// Clock is the time of day of o, in [0, Day).
+func (o Offset) Clock() Offset {
+	return o - At(o.Day(), 0)
+}
+
+// Midnight is the start of o's day.
+func (o Offset) Midnight() Offset {
+	return At(o.Day(), 0)
+}
+
+func (o Offset) Add(d time.Duration) Offset { return o + Offset(d) }
+
+// Sub is the time from p to o.
+func (o Offset) Sub(p Offset) time.Duration { return time.Duration(o - p) }
+
+// String formats o as "HH:MM", with a " (+Nd)" suffix on the other days.
+func (o Offset) String() string {
+	c := time.Duration(o.Clock())
+	h, m := int(c/time.Hour), int(c%time.Hour/time.Minute)
+	if days := o.Day(); days != 0 {
+		return fmt.Sprintf("%02d:%02d (+%dd)", h, m, days)
+	}
+	return fmt.Sprintf("%02d:%02d", h, m)
+}
+
+// Anchor is the release day in a time zone, the date Offsets are counted
+// from. Offsets are wall-clock times: across a DST change the clock times
+// stay and the hours between them do not.
+type Anchor struct {
+	year  int
+	month time.Month
+	day   int
+	loc   *time.Location
+}
+
+// DayOf is the anchor of the day t is on, in t's time zone.
+func DayOf(t time.Time) Anchor {
+	y, m, d := t.Date()
+	return Anchor{year: y, month: m, day: d, loc: t.Location()}
+}
+
+// Date is the midnight the anchor counts from.
+func (a Anchor) Date() time.Time {
+	return time.Date(a.year, a.month, a.day, 0, 0, 0, 0, a.loc)
+}
+
+// Time is when o is on the wall clock of the anchor's time zone. A clock
+// time a DST change skips comes out as time.Date normalizes it.
+func (a Anchor) Time(o Offset) time.Time {
+	c := time.Duration(o.Clock())
+	return time.Date(a.year, a.month, a.day+o.Day(), int(c/time.Hour), int(c%time.Hour/time.Minute), int(c%time.Minute/time.Second), 0, a.loc)
+}
+
+// Offset is t as an offset from the anchor, on the wall clock of its time
+// zone; the inverse of Time.
+func (a Anchor) Offset(t time.Time) Offset {
+	t = t.In(a.loc)
+	y, m, d := t.Date()
+	days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(time.Date(a.year, a.month, a.day, 0, 0, 0, 0, time.UTC)) / Day
+	h, mi, s := t.Clock()
+	return At(int(days), Offset(time.Duration(h)*time.Hour+time.Duration(mi)*time.Minute+time.Duration(s)*time.Second))
+}
+
+// Hours is h hours rounded to the minute, the resolution of the
+// calculation.
+func Hours(h float64) time.Duration {
+	return time.Duration(math.Round(h*60)) * time.Minute
+}
+
+// scale is d times f, rounded to the minute.
+func scale(d time.Duration, f float64) time.Duration {
+	return time.Duration(math.Round(d.Minutes()*f)) * time.Minute
+}
@@ -0,0 +1,121 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+	_ "time/tzdata" // the DST zones below, wherever the tests run
+)
+
+// TestOffsetDayClock checks that every minute of three days on each side
+// of the release day is its day and clock, and formats as them.
+func TestOffsetDayClock(t *testing.T) {
+	for o := At(-3, 0); o < At(4, 0); o = o.Add(time.Minute) {
+		day, clock := o.Day(), o.Clock()
+		if clock < 0 || clock >= Offset(Day) {
+			t.Fatalf("%d: clock %d out of [0, Day)", o, clock)
+		}
+		if At(day, clock) != o {
+			t.Fatalf("%d: At(%d, %d) = %d", o, day, clock, At(day, clock))
+		}
+		if o.Midnight() != At(day, 0) {
+			t.Fatalf("%d: midnight %d, want %d", o, o.Midnight(), At(day, 0))
+		}
+
+		// String is the clock, which parses back, and the day
+		s := o.String()
+		hhmm, suffix, _ := strings.Cut(s, " ")
+		if c, err := ParseClock(hhmm); err != nil || c != clock {
+			t.Fatalf("%d: %q parses to %d, %v; want %d", o, s, c, err, clock)
+		}
+		want := ""
+		if day != 0 {
+			want = fmt.Sprintf("(+%dd)", day)
+		}
+		if suffix != want {
+			t.Fatalf("%d: %q, day suffix %q, want %q", o, s, suffix, want)
+		}
+	}
+}
+
+// TestOffsetAddSub checks that Add and Sub undo each other.
+func TestOffsetAddSub(t *testing.T) {
+	// Minutes within a century either way, so that the sums stay well
+	// inside the some 292 years of an int64 of nanoseconds
+	const century = 100 * 365 * 24 * 60
+	addSub := func(o, p, d int32) bool {
+		a, b, dur := Offset(o%century)*Offset(time.Minute), Offset(p%century)*Offset(time.Minute), time.Duration(d%century)*time.Minute
+		return a.Add(dur).Sub(a) == dur && a.Add(b.Sub(a)) == b && b.Sub(a) == -a.Sub(b) &&
+			a.Add(dur).Add(-dur) == a
+	}
+	if err := quick.Check(addSub, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestAnchorTimeOffset checks that Time and Offset are inverses on the
+// days around DST changes: each wall-clock time there is comes back as
+// itself, and each instant as its wall-clock time.
+func TestAnchorTimeOffset(t *testing.T) {
+	for _, tc := range []struct {
+		zone string
+		day  string // the day of a DST change, or not
+	}{
+		{"UTC", "2026-03-29"},
+		{"Europe/Berlin", "2026-03-29"},
+		{"Europe/Berlin", "2026-10-25"},
+		{"America/New_York", "2026-03-08"},
+		{"America/New_York", "2026-11-01"},
+		{"Australia/Lord_Howe", "2026-10-04"}, // a 30 minute change
+	} {
+		t.Run(tc.zone+" "+tc.day, func(t *testing.T) {
+			loc, err := time.LoadLocation(tc.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			change, err := time.ParseInLocation(time.DateOnly, tc.day, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Anchored the day before the change, on it and after it
+			for days := -1; days <= 1; days++ {
+				a := DayOf(change.AddDate(0, 0, days))
+				if !a.Date().Equal(a.Time(0)) {
+					t.Fatalf("%s: date %s, Time(0) %s", tc.day, a.Date(), a.Time(0))
+				}
+
+				for o := At(-1, 0); o < At(3, 0); o = o.Add(time.Minute) {
+					tm := a.Time(o)
+					h, m, _ := tm.Clock()
+					if Offset(time.Duration(h)*time.Hour+time.Duration(m)*time.Minute) != o.Clock() {
+						continue // skipped by the change: normalized to another clock time
+					}
+					if got := a.Offset(tm); got != o {
+						t.Fatalf("anchor %s: Offset(Time(%s)) = %s (%s)", a.Date().Format(time.DateOnly), o, got, tm)
+					}
+				}
+
+				for tm := a.Date().Add(-Day); tm.Before(a.Date().Add(3 * Day)); tm = tm.Add(time.Minute) {
+					back := a.Time(a.Offset(tm))
+					if !back.Equal(tm) && back.Format("2006-01-02 15:04") != tm.Format("2006-01-02 15:04") {
+						t.Fatalf("anchor %s: Time(Offset(%s)) = %s", a.Date().Format(time.DateOnly), tm, back)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCompute is a calculation as the page makes it, with the combine
+// scenario and a config rule.
+func BenchmarkCompute(b *testing.B) {
+	include := 2.0
+	rule := Rule{Title: "Full day + first 2h of the release", Include: &include}
+	for b.Loop() {
+		if _, err := Compute("22:00", 3, 1.5, 0, "09:00", "17:30", 11, 0.5, 4, "80%", rule); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+//go:build js && wasm
+
+// Command wasm exposes calc.Compute to the web form for offline use:
+//
+//	nightrelcalcCompute(JSON.stringify({start: "21:00", length: 4, ...})) -> JSON string
+//
+// The input fields are those of the /api/v1/calc JSON body, with every field
+// set (the page fills in the form defaults); omit combine for no combine
+// scenario. The result is the API's JSON, or {"error": "..."}.
+//
+// Built by go generate into pwa/calc.wasm.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"nightrelcalc/calc"
+)
+
+type request struct {
+	Start       string   `json:"start"`
+	Length      float64  `json:"length"`
+	Combine     *float64 `json:"combine"`
+	Full        float64  `json:"full"`
+	NormalStart string   `json:"normal_start"`
+	NormalEnd   string   `json:"normal_end"`
+	MinRest     float64  `json:"min_rest"`
+	Commute     float64  `json:"commute"`
+	MaxOvertime float64  `json:"max_overtime"`
+	PartTime    string   `json:"part_time"`
+	CoreHours   string   `json:"core_hours"`
+	Commitment  string   `json:"commitment"`
+}
+
+func compute(in string) string {
+	var req request
+	if err := json.Unmarshal([]byte(in), &req); err != nil {
+		return errorJSON("invalid request: " + err.Error())
+	}
+	combineH := -1.0
+	if req.Combine != nil {
+		combineH = *req.Combine
+	}
+	res, err := calc.Compute(req.Start, req.Length, combineH, req.Full, req.NormalStart, req.NormalEnd, req.MinRest, req.Commute, req.MaxOvertime, req.PartTime)
+	if err == nil {
+		err = calc.CheckNextDay(res, req.CoreHours, req.Commitment)
+	}
+	if err != nil {
+		return errorJSON(err.Error())
+	}
+	b, _ := json.Marshal(res)
+	return string(b)
+}
+
+func errorJSON(msg string) string {
+	b, _ := json.Marshal(map[string]string{"error": msg})
+	return string(b)
+}
+
+func main() {
+	js.Global().Set("nightrelcalcCompute", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 1 {
+			return errorJSON("expected one JSON argument")
+		}
+		return compute(args[0].String())
+	}))
+	select {}
+}
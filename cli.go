@@ -0,0 +1,271 @@
+package nightrelcalc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- CLI calculation ---------------- */
+
+// calcOptions are the calculation flags shared by the root, now and batch commands.
+type calcOptions struct {
+	Start   string
+	Length  float64
+	Combine float64 // < 0: no combine scenario
+	Full    float64 // 0: derive from the normal day
+
+	NormalStart, NormalEnd string
+	MinRest, MaxOvertime   float64
+	Commute                float64       // each way, around the rest
+	PartTime               string        // "80%" or "30h" a week; "" for full time
+	CoreHours              string        // HH:MM-HH:MM the next day must start by; "" for none
+	Commitment             string        // "HH:MM label" the next day must start by; "" for none
+	Scenarios              []int         // only these, numbered from 1; nil for all
+	Granularity            time.Duration // time inputs must be multiples of this; 0 for any minute
+	Strict                 bool          // reject inputs that would be adjusted to fit
+	MaxNights              int           // consecutive nights with night work before a warning; 0 for no limit
+	NightHistory           []string      // earlier release dates with night work, YYYY-MM-DD
+
+	Output   string // text, json, ndjson, csv or tsv
+	NoHeader bool   // csv and tsv without the header row
+	DryRun   bool   // report the inputs and rules instead of the result
+
+	Watch  bool
+	Print  string // one field only, e.g. next_day_start or overtime[1]
+	Notify NotifyOptions
+
+	rules  []calc.Rule // the config's extra scenarios, set by prepare
+	nights nightLog    // NightHistory and the nights checked since, set by prepare
+}
+
+// addFlags registers the calculation and --output flags; not --start, which
+// each command gets its own way.
+func (o *calcOptions) addFlags(fs *pflag.FlagSet) {
+	fs.Var(newHoursValue(&o.Length, 0), "length", "Release length in hours or as a duration (e.g. 4, 3.5, 4h30m)")
+	fs.Var(newHoursValue(&o.Combine, -1), "combine", "Hours of release included in full day (optional)")
+
+	// Full is optional: 0 means "derive from normal day".
+	fs.Var(newHoursValue(&o.Full, 0), "full", "Full workday hours (0 = derive from normal-start/normal-end)")
+
+	fs.StringVar(&o.NormalStart, "normal-start", "09:00", "Normal work start time (HH:MM)")
+	fs.StringVar(&o.NormalEnd, "normal-end", "17:30", "Normal work end time (HH:MM)")
+	fs.Var(newHoursValue(&o.MinRest, 11), "min-rest", "Minimum rest after release end in hours or as a duration (e.g. 11h)")
+	fs.Var(newHoursValue(&o.Commute, 0), "commute", "Travel in hours on each side of the rest (home after the release, in the next morning); not rest")
+	fs.StringVar(&o.PartTime, "part-time", "", "Part-time schedule: a percentage of full time (80%) or hours a week (30h); pro-rates the full day and the overtime cap")
+	fs.StringVar(&o.CoreHours, "core-hours", "", "Core hours the next day must start by, HH:MM-HH:MM (e.g. 10:00-15:00); scenarios starting later are flagged")
+	fs.StringVar(&o.Commitment, "commitment", "", `Immovable next-day commitment, HH:MM and a label (e.g. "10:00 customer call"); scenarios starting later are flagged`)
+	fs.Var(newHoursValue(&o.MaxOvertime, 4), "max-overtime", "Maximum allowed overtime in hours (legal cap)")
+	fs.DurationVar(&o.Granularity, "granularity", 0, "Time step inputs must be multiples of: 5m, 10m or 15m (0 = any minute); also the step of the time picker and now --round")
+	fs.BoolVar(&o.Strict, "strict", false, "Reject inputs that would be adjusted to fit (e.g. a combine longer than the release) instead of noting the adjustment")
+	fs.IntVar(&o.MaxNights, "max-consecutive-nights", 0, "Flag a night release that makes more than this many nights with night work (23:00-06:00) in a row (0 = no limit)")
+	fs.StringSliceVar(&o.NightHistory, "night-history", nil, "Earlier release dates with night work, YYYY-MM-DD (e.g. 2026-10-12,2026-10-13), for --max-consecutive-nights")
+	fs.IntSliceVar(&o.Scenarios, "scenarios", nil, "Only show these scenarios, by number (e.g. 1,3; default all)")
+
+	fs.StringVarP(&o.Output, "output", "o", "text", "Output format: "+strings.Join(outputFormats, ", "))
+	fs.BoolVar(&o.NoHeader, "no-header", false, "Leave out the header row of csv and tsv output")
+	fs.BoolVar(&o.DryRun, "dry-run", false, "Only validate: report what would be computed and which rules apply")
+}
+
+// hoursValue is a flag in hours, given as decimal hours (4.5) or as a
+// duration (4h30m); see calc.ParseHours.
+type hoursValue float64
+
+func newHoursValue(p *float64, def float64) *hoursValue {
+	*p = def
+	return (*hoursValue)(p)
+}
+
+func (h *hoursValue) Set(s string) error {
+	v, err := calc.ParseHours(s)
+	if err != nil {
+		return err
+	}
+	*h = hoursValue(v)
+	return nil
+}
+
+// String is "" for 0, so the usage leaves out a zero default.
+func (h *hoursValue) String() string {
+	if *h == 0 {
+		return ""
+	}
+	return fmtFloat(float64(*h))
+}
+
+func (h *hoursValue) Type() string { return "hours" }
+
+// addSingleFlags registers the flags that only make sense for one release.
+func (o *calcOptions) addSingleFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Print, "print", "", "Print only this field, undecorated (e.g. next_day_start, overtime[0], work_start[1])")
+	fs.BoolVar(&o.Watch, "watch", false, "Keep a countdown to the release, each scenario's work start and the end of the mandatory rest, updated every minute")
+	o.Notify.addFlags(fs)
+}
+
+// run applies the profile to the flags not given on fs, then computes and
+// prints (or watches) the result and posts it to the --notify-* targets.
+// Cancelling ctx stops the watch and the posting.
+func (o *calcOptions) run(ctx context.Context, fs *pflag.FlagSet, cfg Config, profileName string) error {
+	if err := o.prepare(fs, cfg, profileName); err != nil {
+		return err
+	}
+	res, err := o.compute()
+	if err != nil {
+		return err
+	}
+	if len(res.Scenarios) > 0 {
+		date := releaseDay(appClock.Now(), res.Scenarios[0].Timeline).Date().Format(time.DateOnly)
+		if err := o.checkNights(datedResult{Date: date, Result: res}); err != nil {
+			return err
+		}
+	}
+	if o.DryRun {
+		return writeDryRunResult(os.Stdout, o.Output, newDryRun(datedResult{Result: res, req: o.request()}))
+	}
+	if o.Watch {
+		if err := o.notify(ctx, res); err != nil {
+			return err
+		}
+		return watchRelease(ctx, res)
+	}
+	if o.Print != "" {
+		v, err := resultField(res, o.Print)
+		if err != nil {
+			return err
+		}
+		fmt.Println(v)
+	} else if err := writeResult(os.Stdout, *o, res); err != nil {
+		return err
+	}
+	return o.notify(ctx, res)
+}
+
+// notify posts res to the --notify-* targets, if any.
+func (o *calcOptions) notify(ctx context.Context, res *calc.Result) error {
+	if !o.Notify.enabled() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	return o.Notify.post(ctx, notice{Result: res, Source: "cli"})
+}
+
+// prepare applies the profile to the flags not given on fs and checks --output.
+func (o *calcOptions) prepare(fs *pflag.FlagSet, cfg Config, profileName string) error {
+	p, err := cfg.lookupProfile(profileName)
+	if err != nil {
+		return err
+	}
+	if err := p.applyFlags(fs); err != nil {
+		return err
+	}
+	o.rules = cfg.rules(p)
+	if _, _, err := calc.ParsePartTime(o.PartTime); err != nil {
+		return fmt.Errorf("--part-time: %w", err)
+	}
+	if o.CoreHours != "" {
+		if _, _, err := calc.ParseClockRange(o.CoreHours); err != nil {
+			return fmt.Errorf("--core-hours: %w", err)
+		}
+	}
+	if o.Commitment != "" {
+		if _, _, err := calc.ParseCommitment(o.Commitment); err != nil {
+			return fmt.Errorf("--commitment: %w", err)
+		}
+	}
+	if _, err := gridMinutes(o.Granularity); err != nil {
+		return fmt.Errorf("--granularity: %w", err)
+	}
+	if o.MaxNights < 0 {
+		return fmt.Errorf("--max-consecutive-nights must be >= 0")
+	}
+	if o.nights, err = parseNightDates(o.NightHistory); err != nil {
+		return fmt.Errorf("--night-history: %w", err)
+	}
+	if o.Scenarios, err = checkScenarioList(o.Scenarios); err != nil {
+		return fmt.Errorf("--scenarios: %w", err)
+	}
+	o.Notify = o.Notify.orElse(p.Notify)
+	o.Notify.smtp = cfg.SMTP
+	if err := o.Notify.check(); err != nil {
+		return err
+	}
+	if o.DryRun && (o.Output == "csv" || o.Output == "tsv" || o.Output == "markdown") {
+		return fmt.Errorf("--dry-run reports as text, json or ndjson, not %s", o.Output)
+	}
+	return checkOutputFormat(o.Output)
+}
+
+func (o *calcOptions) compute() (*calc.Result, error) {
+	if o.Length <= 0 {
+		return nil, fmt.Errorf("--length must be > 0")
+	}
+	if o.MinRest <= 0 {
+		return nil, fmt.Errorf("--min-rest must be > 0")
+	}
+	res, err := calc.Compute(o.Start, o.Length, o.Combine, o.Full, o.NormalStart, o.NormalEnd, o.MinRest, o.Commute, o.MaxOvertime, o.PartTime, o.rules...)
+	if err != nil {
+		return nil, err
+	}
+	if field, err := offGrid(o.Granularity, o.request().withDefaults().gridValues()...); err != nil {
+		return nil, fmt.Errorf("%s: %w", flagName(field), err)
+	}
+	if o.Strict {
+		if err := strictAdjustments(res); err != nil {
+			return nil, err
+		}
+	}
+	if err := calc.CheckNextDay(res, o.CoreHours, o.Commitment); err != nil {
+		return nil, err
+	}
+	if err := selectScenarios(res, o.Scenarios); err != nil {
+		return nil, fmt.Errorf("--scenarios: %w", err)
+	}
+	return res, nil
+}
+
+// writeCLI writes the plain-text result, as printed by the CLI.
+func writeCLI(w io.Writer, res *calc.Result) {
+	fmt.Fprintf(w, "Release Window: %s -> %s (len %s)\n", res.ReleaseStart, res.ReleaseEnd, res.ReleaseLen)
+	fmt.Fprintf(w, "Normal day: %s -> %s (len %s)\n", res.NormalStart, res.NormalEnd, res.NormalLen)
+	rest := res.MinRest
+	if res.Commute != "" {
+		rest += " (+ " + res.Commute + " commute each way)"
+	}
+	fmt.Fprintf(w, "Full day used: %s, Min rest: %s, Max overtime (cap): %s\n", res.FullDay, rest, res.MaxOvertime)
+	if res.PartTime != "" {
+		fmt.Fprintf(w, "Part time: %s\n", res.PartTime)
+	}
+	fmt.Fprintln(w)
+
+	for _, s := range res.Scenarios {
+		writeScenario(w, s)
+		fmt.Fprintln(w)
+	}
+}
+
+func writeScenario(w io.Writer, s calc.Scenario) {
+	fmt.Fprintln(w, s.Title)
+	fmt.Fprintf(w, "  Work Hours:                    %s\n", s.WorkHours)
+	fmt.Fprintf(w, "  Release Window:                %s\n", s.ReleaseWindow)
+	fmt.Fprintf(w, "  Total Work:                    %s\n", s.TotalWork)
+	fmt.Fprintf(w, "  Release Hours Included in Full %s\n", s.ReleaseIncluded)
+	fmt.Fprintf(w, "  Overtime:                      %s\n", s.Overtime)
+	fmt.Fprintf(w, "  Next Day Hours:                %s\n", s.NextDayHours)
+	if s.TOIL != "" {
+		fmt.Fprintf(w, "  TOIL Balance:                  %s\n", s.TOIL)
+	}
+	for i, n := range s.Notes {
+		fmt.Fprintf(w, "  [%d] %s\n", i+1, n)
+	}
+	for _, n := range s.Warnings {
+		fmt.Fprintf(w, "  [!] %s\n", n)
+	}
+}
@@ -0,0 +1,47 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"time"
+)
+
+/* ---------------- the current time, and --now ---------------- */
+
+// clock is where the current time comes from.
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// shiftedClock runs on from a time set with --now, as fast as the system's.
+type shiftedClock struct {
+	offset time.Duration
+}
+
+func (c shiftedClock) Now() time.Time { return time.Now().Add(c.offset) }
+
+// appClock is the current time of everything a user sees: releases placed
+// today or tomorrow, date defaults, countdowns and calendar stamps. Request
+// timing, rate limits, caches, cookies and signatures keep the system's.
+var appClock clock = systemClock{}
+
+// nowLayouts are the --now formats; those without a zone are local time.
+var nowLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly}
+
+// setNow sets appClock to run on from s, for reproducible runs in bug
+// reports and CI; "" leaves the system clock.
+func setNow(s string) error {
+	if s == "" {
+		return nil
+	}
+	for _, layout := range nowLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			appClock = shiftedClock{offset: time.Until(t)}
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --now %q, expected e.g. 2026-10-14T21:30 or RFC 3339", s)
+}
@@ -0,0 +1,10 @@
+// Command nightrelcalc is the night release calculator (CLI or web); see
+// package nightrelcalc, which also serves the web UI for other programs
+// (NewHandler).
+package main
+
+import "nightrelcalc"
+
+func main() {
+	nightrelcalc.Main()
+}
@@ -0,0 +1,216 @@
+package nightrelcalc
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- side-by-side comparison ---------------- */
+
+type compareRow struct {
+	Label string
+	A, B  string
+	Diff  bool
+}
+
+type compareSide struct {
+	Params string // as given in the query (a= / b=)
+	Link   string // result page for this side
+	Error  string
+	Result *calc.Result
+}
+
+type compareScenario struct {
+	TitleA, TitleB string
+	Timeline       [2]*calc.Scenario
+	Rows           []compareRow
+}
+
+type CompareData struct {
+	A, B      compareSide
+	Summary   []compareRow
+	Scenarios []compareScenario
+
+	Version  string
+	BasePath string
+	CSPNonce string
+	Brand    Brand
+}
+
+// compareSideFrom computes one side from encoded params; a pasted result URL
+// works too (everything after "?" is used).
+func compareSideFrom(cfg Config, params, basePath string) compareSide {
+	side := compareSide{Params: params}
+	if i := strings.IndexByte(params, '?'); i >= 0 {
+		params = params[i+1:]
+	}
+	q, err := url.ParseQuery(params)
+	if err != nil {
+		side.Error = "invalid params: " + err.Error()
+		return side
+	}
+	req, err := calcRequestFromQuery(q)
+	if err == nil {
+		req, err = req.under(cfg)
+	}
+	if err != nil {
+		side.Error = err.Error()
+		return side
+	}
+	if side.Result, err = req.compute(); err != nil {
+		side.Error = err.Error()
+		return side
+	}
+	side.Link = basePath + "/?" + req.shareQuery()
+	return side
+}
+
+func compareRows(labels, a, b []string) []compareRow {
+	rows := make([]compareRow, len(labels))
+	for i, l := range labels {
+		rows[i] = compareRow{Label: l, A: a[i], B: b[i], Diff: a[i] != b[i]}
+	}
+	return rows
+}
+
+var scenarioLabels = []string{"Work Hours", "Release Window", "Total Work", "Release Hours Included in Full", "Overtime", "Next Day Hours"}
+
+func scenarioValues(s *calc.Scenario) []string {
+	if s == nil {
+		return []string{"-", "-", "-", "-", "-", "-"}
+	}
+	return []string{s.WorkHours, s.ReleaseWindow, s.TotalWork, s.ReleaseIncluded, s.Overtime, s.NextDayHours}
+}
+
+// pairScenarios lines up the scenarios of both results by position.
+func pairScenarios(a, b *calc.Result) []compareScenario {
+	var out []compareScenario
+	for i := 0; i < max(len(a.Scenarios), len(b.Scenarios)); i++ {
+		var cs compareScenario
+		var sa, sb *calc.Scenario
+		if i < len(a.Scenarios) {
+			sa = &a.Scenarios[i]
+			cs.TitleA = sa.Title
+		}
+		if i < len(b.Scenarios) {
+			sb = &b.Scenarios[i]
+			cs.TitleB = sb.Title
+		}
+		cs.Timeline = [2]*calc.Scenario{sa, sb}
+		cs.Rows = compareRows(scenarioLabels, scenarioValues(sa), scenarioValues(sb))
+		out = append(out, cs)
+	}
+	return out
+}
+
+// compareHandler serves /compare?a=<encoded params>&b=<encoded params>.
+func compareHandler(pages pageRenderer, tpl *template.Template, cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			pages.methodNotAllowed(w, r, "GET")
+			return
+		}
+		q := r.URL.Query()
+		data := CompareData{
+			Version:  appVersion,
+			BasePath: pages.basePath,
+			CSPNonce: cspNonce(r),
+			Brand:    pages.brand,
+		}
+		status := http.StatusOK
+		pa, pb := strings.TrimSpace(q.Get("a")), strings.TrimSpace(q.Get("b"))
+		if pa != "" || pb != "" {
+			data.A = compareSideFrom(cfg, pa, pages.basePath)
+			data.B = compareSideFrom(cfg, pb, pages.basePath)
+			if data.A.Error != "" || data.B.Error != "" {
+				status = http.StatusBadRequest
+			} else {
+				a, b := data.A.Result, data.B.Result
+				data.Summary = compareRows(
+					[]string{"Release Window", "Normal day", "Full day used", "Min rest", "Max overtime (cap)"},
+					[]string{a.ReleaseStart + " -> " + a.ReleaseEnd, a.NormalStart + " -> " + a.NormalEnd, a.FullDay, a.MinRest, a.MaxOvertime},
+					[]string{b.ReleaseStart + " -> " + b.ReleaseEnd, b.NormalStart + " -> " + b.NormalEnd, b.FullDay, b.MinRest, b.MaxOvertime},
+				)
+				data.Scenarios = pairScenarios(a, b)
+			}
+		}
+		pages.render(w, r, tpl, status, data)
+	}
+}
+
+const compareHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Brand.Name}} - compare</title>
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 1200px; box-sizing: border-box; }
+    * { box-sizing: border-box; }
+    h2 { margin-top: 0; font-weight: 600; }
+    .mono { font-family: ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, "Liberation Mono", "Courier New", monospace; }
+    .card { border: 1px solid #ddd; border-radius: 8px; padding: 12px; margin: 12px 0; }
+    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
+    form { display: flex; gap: 12px; flex-wrap: wrap; align-items: flex-end; }
+    form .field { flex: 1; min-width: 240px; }
+    label { display: block; font-size: 0.9em; margin-bottom: 4px; }
+    input { width: 100%; padding: 8px 10px; border: 1px solid #ccc; border-radius: 6px; }
+    button { padding: 9px 20px; background: #1976d2; color: #fff; border: none; border-radius: 6px; cursor: pointer; }
+    table { border-collapse: collapse; width: 100%; margin-top: 8px; }
+    th, td { padding: 4px 8px; border-bottom: 1px solid #eee; text-align: left; vertical-align: top; }
+    td.k { width: 28%; }
+    td.diff { background: #fff3e0; font-weight: 600; }
+    .cols { display: flex; gap: 12px; }
+    .cols > div { flex: 1; min-width: 0; }
+    .timeline { display: block; margin-top: 6px; }
+    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
+  </style>
+</head>
+<body>
+  <h2>Compare two plans</h2>
+  <form method="GET" action="{{.BasePath}}/compare">
+    <div class="field">
+      <label for="a">A (params or result URL)</label>
+      <input id="a" name="a" value="{{.A.Params}}" placeholder="start=21:00&length=4">
+    </div>
+    <div class="field">
+      <label for="b">B (params or result URL)</label>
+      <input id="b" name="b" value="{{.B.Params}}" placeholder="start=22:00&length=4">
+    </div>
+    <button type="submit">Compare</button>
+  </form>
+
+  {{if .A.Error}}<div class="err">A: {{.A.Error}}</div>{{end}}
+  {{if .B.Error}}<div class="err">B: {{.B.Error}}</div>{{end}}
+
+  {{if .Summary}}
+    <div class="card">
+      <table>
+        <tr><th></th><th><a href="{{.A.Link}}">A</a></th><th><a href="{{.B.Link}}">B</a></th></tr>
+        {{range .Summary}}
+          <tr><td class="k">{{.Label}}</td><td class="mono{{if .Diff}} diff{{end}}">{{.A}}</td><td class="mono{{if .Diff}} diff{{end}}">{{.B}}</td></tr>
+        {{end}}
+      </table>
+    </div>
+
+    {{range .Scenarios}}
+      <div class="card">
+        <div class="cols">
+          <div><b>{{or .TitleA "-"}}</b>{{with index .Timeline 0}}{{timeline .}}{{end}}</div>
+          <div><b>{{or .TitleB "-"}}</b>{{with index .Timeline 1}}{{timeline .}}{{end}}</div>
+        </div>
+        <table>
+          {{range .Rows}}
+            <tr><td class="k">{{.Label}}</td><td class="mono{{if .Diff}} diff{{end}}">{{.A}}</td><td class="mono{{if .Diff}} diff{{end}}">{{.B}}</td></tr>
+          {{end}}
+        </table>
+      </div>
+    {{end}}
+  {{end}}
+
+  <footer>{{with .Brand.Footer}}<div>{{.}}</div>{{end}}nightrelcalc v{{.Version}}</footer>
+</body>
+</html>`
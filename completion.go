@@ -0,0 +1,80 @@
+package nightrelcalc
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- shell completion suggestions ---------------- */
+
+// flagSuggestions are the common values offered for a flag; the shell still
+// accepts anything else.
+var flagSuggestions = map[string][]string{
+	"start":        {"18:00\tafter hours", "19:00", "20:00", "21:00", "22:00\tlate evening", "23:00", "00:00\tmidnight", "02:00\tovernight"},
+	"normal-start": {"07:00", "08:00", "08:30", "09:00\tdefault", "10:00"},
+	"normal-end":   {"16:00", "16:30", "17:00", "17:30\tdefault", "18:00"},
+	"length":       {"1", "2", "3", "4", "6", "8"},
+	"combine":      {"0", "1", "2", "4"},
+	"full":         {"0\tderive from the normal day", "7.5", "8", "8.5"},
+	"min-rest":     {"11\tdefault (EU working time directive)", "12"},
+	"max-overtime": {"2", "4\tdefault"},
+	"round":        {"0\tto the minute", "5m\tdefault", "15m", "30m"},
+	"output":       outputFormats,
+}
+
+// suggest completes from values, keeping the ones that start with what is
+// typed so far.
+func suggest(values []string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		var out []cobra.Completion
+		for _, v := range values {
+			if strings.HasPrefix(v, toComplete) {
+				out = append(out, v)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// registerCompletions adds the flag suggestions to cmd and its subcommands;
+// --profile completes from the profiles in the --config file.
+func registerCompletions(cmd *cobra.Command, configPath *string) {
+	for name, values := range flagSuggestions {
+		if cmd.LocalNonPersistentFlags().Lookup(name) != nil {
+			_ = cmd.RegisterFlagCompletionFunc(name, suggest(values))
+		}
+	}
+	if cmd.LocalNonPersistentFlags().Lookup("print") != nil {
+		_ = cmd.RegisterFlagCompletionFunc("print", func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			res, err := calc.Compute(webDefaultStart, 4, 1, 0, webDefaultNormalStart, webDefaultNormalEnd, 11, 0, 4, "")
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			var names []string
+			for _, n := range printFieldNames(res) {
+				names = append(names, strings.TrimSuffix(n, "[i]"))
+			}
+			return suggest(names)(cmd, args, toComplete)
+		})
+	}
+	for _, name := range []string{"templates-dir", "static-dir"} {
+		if cmd.Flags().Lookup(name) != nil {
+			_ = cmd.MarkFlagDirname(name)
+		}
+	}
+	if cmd.PersistentFlags().Lookup("profile") != nil {
+		_ = cmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			return suggest(cfg.profileNames())(cmd, args, toComplete)
+		})
+	}
+	for _, sub := range cmd.Commands() {
+		registerCompletions(sub, configPath)
+	}
+}
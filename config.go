@@ -0,0 +1,422 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- config file ---------------- */
+
+// Config is the --config file (JSON):
+//
+//	{
+//	  "profiles": {
+//	    "payments": {"normal_start": "08:00", "normal_end": "16:30", "max_overtime": 2},
+//	    "infra":    {"min_rest": 12, "notify": {"teams": "https://..."}}
+//	  },
+//	  "defaults": {"normal_start": "08:30", "min_rest": 12},
+//	  "scenarios": [{"title": "Full day + first 2h of the release", "include": 2}],
+//	  "smtp": {"host": "smtp.example.com", "username": "bot", "password": "...", "from": "bot@example.com"}
+//	}
+type Config struct {
+	Profiles   map[string]Profile `json:"profiles"`
+	Defaults   Profile            `json:"defaults"`             // the work day and limits without a profile, and where profiles leave them out
+	Scenarios  []calc.Rule        `json:"scenarios,omitempty"`  // computed after the built-in ones, for every profile
+	SMTP       *SMTPConfig        `json:"smtp,omitempty"`       // for --email
+	Graph      *GraphConfig       `json:"graph,omitempty"`      // for push outlook
+	GitHub     *GitHubConfig      `json:"github,omitempty"`     // for push github
+	GitLab     *GitLabConfig      `json:"gitlab,omitempty"`     // for push gitlab
+	Jira       *JiraConfig        `json:"jira,omitempty"`       // for push jira
+	Confluence *ConfluenceConfig  `json:"confluence,omitempty"` // for push confluence
+	PagerDuty  *PagerDutyConfig   `json:"pagerduty,omitempty"`  // for push pagerduty
+	Statuspage *StatuspageConfig  `json:"statuspage,omitempty"` // for push statuspage
+	Opsgenie   *OpsgenieConfig    `json:"opsgenie,omitempty"`   // for push opsgenie
+	ServiceNow *ServiceNowConfig  `json:"servicenow,omitempty"` // for push servicenow
+
+	Feeds map[string]FeedConfig `json:"feeds,omitempty"` // served at /feed/{token}.ics
+
+	Rosters map[string]RosterConfig `json:"rosters,omitempty"` // for /availability
+}
+
+// Profile is a named set of work-day defaults and legal limits; omitted
+// fields keep the config's "defaults", then the built-in ones.
+type Profile struct {
+	NormalStart string   `json:"normal_start,omitempty"`
+	NormalEnd   string   `json:"normal_end,omitempty"`
+	MinRest     *float64 `json:"min_rest,omitempty"`
+	Commute     *float64 `json:"commute,omitempty"`
+	MaxOvertime *float64 `json:"max_overtime,omitempty"`
+	PartTime    string   `json:"part_time,omitempty"`  // e.g. 80% or 30h (a week)
+	CoreHours   string   `json:"core_hours,omitempty"` // e.g. 10:00-15:00
+
+	MaxConsecutiveNights int      `json:"max_consecutive_nights,omitempty"`
+	NightHistory         []string `json:"night_history,omitempty"` // earlier release dates with night work, YYYY-MM-DD
+
+	Scenarios []calc.Rule   `json:"scenarios,omitempty"` // after the config's own scenarios
+	Notify    NotifyOptions `json:"notify,omitzero"`     // over the server's --notify-* targets; CLI flags still win
+}
+
+// defaultConfigPath is used when --config is not given and the file exists.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "nightrelcalc", "config.json")
+}
+
+// loadConfigFlag loads the --config file, or the default one if it exists.
+func loadConfigFlag(path string) (Config, error) {
+	if path != "" {
+		return loadConfig(path, true)
+	}
+	return loadConfig(defaultConfigPath(), false)
+}
+
+// loadConfig reads path; with explicit false a missing file is no error.
+func loadConfig(path string, explicit bool) (Config, error) {
+	var cfg Config
+	if path == "" {
+		return cfg, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.SMTP != nil {
+		if err := cfg.SMTP.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Graph != nil {
+		if err := cfg.Graph.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.GitHub != nil {
+		if err := cfg.GitHub.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.GitLab != nil {
+		if err := cfg.GitLab.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Jira != nil {
+		if err := cfg.Jira.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Confluence != nil {
+		if err := cfg.Confluence.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.PagerDuty != nil {
+		if err := cfg.PagerDuty.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Statuspage != nil {
+		if err := cfg.Statuspage.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Opsgenie != nil {
+		if err := cfg.Opsgenie.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.ServiceNow != nil {
+		if err := cfg.ServiceNow.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, r := range cfg.Scenarios {
+		if err := r.Validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := cfg.Defaults.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: defaults: %w", path, err)
+	}
+	if len(cfg.Defaults.Scenarios) > 0 || len(cfg.Defaults.NightHistory) > 0 || cfg.Defaults.Notify.enabled() {
+		return cfg, fmt.Errorf("%s: defaults: only the work day and limits; scenarios, night_history and notify belong in a profile", path)
+	}
+	for name, p := range cfg.Profiles {
+		if err := p.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: profile %q: %w", path, name, err)
+		}
+	}
+	for name, f := range cfg.Feeds {
+		if err := f.validate(cfg); err != nil {
+			return cfg, fmt.Errorf("%s: feed %q: %w", path, name, err)
+		}
+	}
+	for name, r := range cfg.Rosters {
+		if err := r.validate(cfg); err != nil {
+			return cfg, fmt.Errorf("%s: roster %q: %w", path, name, err)
+		}
+	}
+	if err := cfg.checkFeedTokens(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// checkFeedTokens makes sure a token at /feed/ serves one calendar only.
+func (c Config) checkFeedTokens() error {
+	owner := map[string]string{}
+	claim := func(token, who string) error {
+		if prev, ok := owner[token]; ok {
+			return fmt.Errorf("%s has the same token as %s", who, prev)
+		}
+		owner[token] = who
+		return nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Feeds)) {
+		if err := claim(c.Feeds[name].Token, fmt.Sprintf("feed %q", name)); err != nil {
+			return err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Rosters)) {
+		for _, m := range c.Rosters[name].Members {
+			if m.Feed == "" {
+				continue
+			}
+			if err := claim(m.Feed, fmt.Sprintf("roster %q member %q", name, m.Name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p Profile) validate() error {
+	for _, t := range []string{p.NormalStart, p.NormalEnd} {
+		if t == "" {
+			continue
+		}
+		if _, err := calc.ParseClock(t); err != nil {
+			return err
+		}
+	}
+	if p.MinRest != nil && *p.MinRest <= 0 {
+		return errors.New("min_rest must be > 0")
+	}
+	if _, _, err := calc.ParsePartTime(p.PartTime); err != nil {
+		return fmt.Errorf("part_time: %w", err)
+	}
+	if p.CoreHours != "" {
+		if _, _, err := calc.ParseClockRange(p.CoreHours); err != nil {
+			return fmt.Errorf("core_hours: %w", err)
+		}
+	}
+	if p.MaxConsecutiveNights < 0 {
+		return errors.New("max_consecutive_nights must be >= 0")
+	}
+	if _, err := parseNightDates(p.NightHistory); err != nil {
+		return fmt.Errorf("night_history: %w", err)
+	}
+	if p.Commute != nil && *p.Commute < 0 {
+		return errors.New("commute must be >= 0")
+	}
+	if p.MaxOvertime != nil && *p.MaxOvertime < 0 {
+		return errors.New("max_overtime must be >= 0")
+	}
+	for _, r := range p.Scenarios {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rules are the extra scenarios computed under p: the config's, then p's.
+func (c Config) rules(p Profile) []calc.Rule {
+	return append(slices.Clip(c.Scenarios), p.Scenarios...)
+}
+
+// profileNames lists the configured profiles, sorted.
+func (c Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// lookupProfile returns the named profile over the config's defaults; ""
+// is no profile, only the defaults.
+func (c Config) lookupProfile(name string) (Profile, error) {
+	if name == "" {
+		return c.Defaults, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return p, fmt.Errorf("unknown profile %q: no profiles configured (see --config)", name)
+		}
+		return p, fmt.Errorf("unknown profile %q (have: %s)", name, strings.Join(c.profileNames(), ", "))
+	}
+	return p.over(c.Defaults), nil
+}
+
+// over fills the work day and limits p leaves out from d.
+func (p Profile) over(d Profile) Profile {
+	p.NormalStart = orDefault(p.NormalStart, d.NormalStart)
+	p.NormalEnd = orDefault(p.NormalEnd, d.NormalEnd)
+	p.PartTime = orDefault(p.PartTime, d.PartTime)
+	p.CoreHours = orDefault(p.CoreHours, d.CoreHours)
+	p.MinRest = cmp.Or(p.MinRest, d.MinRest)
+	p.Commute = cmp.Or(p.Commute, d.Commute)
+	p.MaxOvertime = cmp.Or(p.MaxOvertime, d.MaxOvertime)
+	p.MaxConsecutiveNights = cmp.Or(p.MaxConsecutiveNights, d.MaxConsecutiveNights)
+	return p
+}
+
+// applyFlags sets the CLI flags the user did not pass from the profile.
+func (p Profile) applyFlags(fs *pflag.FlagSet) error {
+	set := func(flag, val string) error {
+		if val == "" || fs.Changed(flag) {
+			return nil
+		}
+		return fs.Set(flag, val)
+	}
+	opt := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return fmtFloat(*f)
+	}
+	nights := ""
+	if p.MaxConsecutiveNights > 0 {
+		nights = strconv.Itoa(p.MaxConsecutiveNights)
+	}
+	for _, err := range []error{
+		set("normal-start", p.NormalStart),
+		set("normal-end", p.NormalEnd),
+		set("min-rest", opt(p.MinRest)),
+		set("commute", opt(p.Commute)),
+		set("part-time", p.PartTime),
+		set("core-hours", p.CoreHours),
+		set("max-overtime", opt(p.MaxOvertime)),
+		set("max-consecutive-nights", nights),
+		set("night-history", strings.Join(p.NightHistory, ",")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formDefaults are the web form values for omitted fields under this profile.
+func (p Profile) formDefaults() (normalStart, normalEnd, minRest, commute, maxOvertime string) {
+	normalStart = orDefault(p.NormalStart, webDefaultNormalStart)
+	normalEnd = orDefault(p.NormalEnd, webDefaultNormalEnd)
+	minRest, commute, maxOvertime = webDefaultMinRest, webDefaultCommute, webDefaultMaxOvertime
+	if p.MinRest != nil {
+		minRest = fmtFloat(*p.MinRest)
+	}
+	if p.Commute != nil {
+		commute = fmtFloat(*p.Commute)
+	}
+	if p.MaxOvertime != nil {
+		maxOvertime = fmtFloat(*p.MaxOvertime)
+	}
+	return
+}
+
+// profileCalcURL is buildCalcURL for a form submitted under a profile, or
+// none for the config's defaults: the profile is kept in the URL and only
+// values differing from it are added.
+func profileCalcURL(name string, p Profile, in formInput, team, tz string, handoff bool) string {
+	v := url.Values{}
+	v.Set("start", in.Start)
+	v.Set("length", in.Length)
+	if in.Combine != "" {
+		v.Set("combine", in.Combine)
+	}
+	if in.Scenarios != "" {
+		v.Set("scenarios", in.Scenarios)
+	}
+	if in.Commitment != "" {
+		v.Set("commitment", in.Commitment)
+	}
+	if team != "" {
+		v.Set("team", team)
+	}
+	if tz != "" {
+		v.Set("tz", tz)
+	}
+	if handoff {
+		v.Set("handoff", "1")
+	}
+	if name != "" {
+		v.Set("profile", name)
+	}
+	normalStart, normalEnd, minRest, commute, maxOvertime := p.formDefaults()
+	for _, f := range []struct{ name, val, def string }{
+		{"normal_start", in.NormalStart, normalStart},
+		{"normal_end", in.NormalEnd, normalEnd},
+		{"min_rest", in.MinRest, minRest},
+		{"commute", in.Commute, commute},
+		{"part_time", in.PartTime, p.PartTime},
+		{"core_hours", in.CoreHours, p.CoreHours},
+		{"max_overtime", in.MaxOvertime, maxOvertime},
+	} {
+		if f.val != f.def {
+			v.Set(f.name, f.val)
+		}
+	}
+	return "/?" + v.Encode()
+}
+
+// pinBuiltins adds to canonical, a buildCalcURL query, the work day fields
+// of in that it leaves out as the built-in defaults while the defaults d,
+// the config's or a profile's, differ, so the page the query links to
+// computes the same.
+func (d Profile) pinBuiltins(canonical string, in formInput) string {
+	v, err := url.ParseQuery(canonical)
+	if err != nil {
+		return canonical
+	}
+	normalStart, normalEnd, minRest, commute, maxOvertime := d.formDefaults()
+	for _, f := range []struct{ name, val, builtin, def string }{
+		{"normal_start", in.NormalStart, webDefaultNormalStart, normalStart},
+		{"normal_end", in.NormalEnd, webDefaultNormalEnd, normalEnd},
+		{"min_rest", in.MinRest, webDefaultMinRest, minRest},
+		{"commute", in.Commute, webDefaultCommute, commute},
+		{"max_overtime", in.MaxOvertime, webDefaultMaxOvertime, maxOvertime},
+	} {
+		if f.val == f.builtin && f.val != f.def {
+			v.Set(f.name, f.val)
+		}
+	}
+	return v.Encode()
+}
@@ -0,0 +1,167 @@
+package nightrelcalc
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push confluence: the plan as a wiki page ---------------- */
+
+// ConfluenceConfig is the "confluence" section of the config file. Auth is
+// as for Jira: email and API token on Cloud, a personal access token alone
+// on Server and Data Center.
+//
+//	"confluence": {"url": "https://acme.atlassian.net/wiki", "email": "bot@acme.com", "token": "...",
+//	               "space": "OPS", "parent_id": "123456", "title": "Night release plan"}
+type ConfluenceConfig struct {
+	URL      string `json:"url"`
+	Email    string `json:"email,omitempty"`
+	Token    string `json:"token"`
+	Space    string `json:"space"`
+	ParentID string `json:"parent_id,omitempty"` // page the plan goes under; "" is the space root
+	Title    string `json:"title,omitempty"`     // default "Night release plan"
+}
+
+func (c *ConfluenceConfig) validate() error {
+	if c.URL == "" || c.Token == "" || c.Space == "" {
+		return errors.New("confluence: url, token and space are required")
+	}
+	return nil
+}
+
+// confluenceStorage is r in Confluence storage format (XHTML): the release,
+// its limits and a table of the scenarios.
+func confluenceStorage(r datedResult) string {
+	esc := func(s string) string { return html.EscapeString(strings.ReplaceAll(s, "->", "→")) }
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h2>Night release %s → %s (%s)</h2>", esc(r.ReleaseStart), esc(r.ReleaseEnd), esc(r.Date))
+	fmt.Fprintf(&b, "<p>Length <strong>%s</strong> · normal day %s → %s · full day %s · min rest %s · max overtime %s</p>",
+		esc(r.ReleaseLen), esc(r.NormalStart), esc(r.NormalEnd), esc(r.FullDay), esc(r.MinRest), esc(r.MaxOvertime))
+	b.WriteString("<table><tbody><tr>")
+	for _, h := range []string{"Scenario", "Work", "Release", "Total", "Release included", "Overtime", "Next day"} {
+		b.WriteString("<th>" + h + "</th>")
+	}
+	b.WriteString("</tr>")
+	for _, s := range r.Scenarios {
+		b.WriteString("<tr>")
+		for _, c := range []string{s.Title, s.WorkHours, s.ReleaseWindow, s.TotalWork, s.ReleaseIncluded, s.Overtime, s.NextDayHours} {
+			b.WriteString("<td>" + esc(c) + "</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+	fmt.Fprintf(&b, "<p><em>Computed by nightrelcalc %s. This page is replaced on every push.</em></p>", esc(appVersion))
+	return b.String()
+}
+
+// confluencePage is the part of a content object that is read and written.
+type confluencePage struct {
+	ID        string             `json:"id,omitempty"`
+	Type      string             `json:"type"`
+	Title     string             `json:"title"`
+	Space     *confluenceSpace   `json:"space,omitempty"`
+	Ancestors []confluenceRef    `json:"ancestors,omitempty"`
+	Version   *confluenceVersion `json:"version,omitempty"`
+	Body      *confluenceBody    `json:"body,omitempty"`
+}
+
+type confluenceSpace struct {
+	Key string `json:"key"`
+}
+
+type confluenceRef struct {
+	ID string `json:"id"`
+}
+
+type confluenceVersion struct {
+	Number int `json:"number"`
+}
+
+type confluenceBody struct {
+	Storage struct {
+		Value          string `json:"value"`
+		Representation string `json:"representation"` // "storage"
+	} `json:"storage"`
+}
+
+func newPushConfluenceCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts  pushOptions
+		title string
+	)
+	cmd := &cobra.Command{
+		Use:   "confluence",
+		Short: "Create or update a Confluence page with the plan",
+		Long: "Write the plan to a Confluence page, created under the configured parent\n" +
+			"page the first time and replaced on every later push, so the runbook always\n" +
+			"has the latest schedule. Needs a \"confluence\" section in the config file.\n\n" +
+			"  nightrelcalc push confluence --title \"Payments release plan\" --start 22:00 --length 3",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			body := confluenceStorage(r)
+			if opts.calc.DryRun {
+				fmt.Println(body)
+				return nil
+			}
+			if cfg.Confluence == nil {
+				return errors.New(`push confluence needs a "confluence" section in the config file`)
+			}
+			c := cfg.Confluence
+			title = orDefault(title, orDefault(c.Title, "Night release plan"))
+
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			send := func(method, path string, v, out any) error {
+				return atlassianSend(ctx, c.URL, c.Email, c.Token, method, path, v, out)
+			}
+			var found struct {
+				Results []confluencePage `json:"results"`
+			}
+			q := url.Values{"spaceKey": {c.Space}, "title": {title}, "expand": {"version"}}
+			if err := send(http.MethodGet, "/rest/api/content?"+q.Encode(), nil, &found); err != nil {
+				return fmt.Errorf("confluence: find page: %w", err)
+			}
+
+			page := confluencePage{Type: "page", Title: title, Body: &confluenceBody{}}
+			page.Body.Storage.Value = body
+			page.Body.Storage.Representation = "storage"
+			var saved confluencePage
+			if len(found.Results) > 0 {
+				old := found.Results[0]
+				page.Version = &confluenceVersion{1}
+				if old.Version != nil {
+					page.Version.Number = old.Version.Number + 1
+				}
+				err = send(http.MethodPut, "/rest/api/content/"+url.PathEscape(old.ID), page, &saved)
+			} else {
+				page.Space = &confluenceSpace{c.Space}
+				if c.ParentID != "" {
+					page.Ancestors = []confluenceRef{{c.ParentID}}
+				}
+				err = send(http.MethodPost, "/rest/api/content", page, &saved)
+			}
+			if err != nil {
+				return fmt.Errorf("confluence: %w", err)
+			}
+			fmt.Printf("%s/pages/viewpage.action?pageId=%s\n", strings.TrimRight(c.URL, "/"), url.QueryEscape(saved.ID))
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&title, "title", "", "Page title; a page with this title in the space is updated (default: confluence.title of the config, else \"Night release plan\")")
+	return cmd
+}
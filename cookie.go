@@ -0,0 +1,101 @@
+package nightrelcalc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Cookie holding the last-used work day and legal limits for this browser.
+const defaultsCookieName = "nightrelcalc_defaults"
+
+// Fields remembered in the defaults cookie (same names as the form/query params).
+var rememberedFields = []string{"normal_start", "normal_end", "min_rest", "commute", "max_overtime", "part_time", "core_hours", "profile"}
+
+// cookieSigner signs and verifies cookie values with HMAC-SHA256.
+type cookieSigner struct {
+	key  []byte
+	path string // cookie Path attribute
+}
+
+// newCookieSigner uses secret as the HMAC key; an empty secret gets a random
+// per-process key (cookies then stop validating after a restart).
+func newCookieSigner(secret, path string) (*cookieSigner, error) {
+	if secret != "" {
+		return &cookieSigner{key: []byte(secret), path: path}, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &cookieSigner{key: key, path: path}, nil
+}
+
+func (s *cookieSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// sign returns "payload.signature", both base64url encoded.
+func (s *cookieSigner) sign(v url.Values) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(v.Encode()))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify returns the values from a signed string, or false if it was tampered with.
+func (s *cookieSigner) verify(signed string) (url.Values, bool) {
+	payload, sig, ok := strings.Cut(signed, ".")
+	if !ok {
+		return nil, false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.mac(payload)) {
+		return nil, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+	v, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// readDefaults returns the remembered values from the request cookie (empty if missing or invalid).
+func (s *cookieSigner) readDefaults(r *http.Request) url.Values {
+	c, err := r.Cookie(defaultsCookieName)
+	if err != nil {
+		return url.Values{}
+	}
+	v, ok := s.verify(c.Value)
+	if !ok {
+		return url.Values{}
+	}
+	return v
+}
+
+// writeDefaults stores the non-empty remembered fields in a signed cookie.
+func (s *cookieSigner) writeDefaults(w http.ResponseWriter, form url.Values) {
+	v := url.Values{}
+	for _, k := range rememberedFields {
+		if val := strings.TrimSpace(form.Get(k)); val != "" {
+			v.Set(k, val)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     defaultsCookieName,
+		Value:    s.sign(v),
+		Path:     s.path,
+		Expires:  time.Now().AddDate(1, 0, 0),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,223 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- recurring windows from cron expressions ---------------- */
+
+// cronSchedule is a parsed 5-field cron expression (minute hour dom month dow).
+type cronSchedule struct {
+	minute, hour, dom, month, dow [64]bool
+
+	domAny, dowAny bool // "*": with both restricted, either one matching is enough
+}
+
+var (
+	cronMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDays   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// parseCronField sets set[v] for every value of a field such as "1-5",
+// "*/15", "mon,wed" or "0"; names counts from lo (jan=1, sun=0).
+func parseCronField(field string, set *[64]bool, lo, hi int, names []string) error {
+	value := func(s string) (int, error) {
+		for i, n := range names {
+			if strings.EqualFold(s, n) {
+				return i + lo, nil
+			}
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v < lo || v > hi {
+			return 0, fmt.Errorf("%q is not in %d-%d", s, lo, hi)
+		}
+		return v, nil
+	}
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = value(a); err != nil {
+				return err
+			}
+			to = from
+			if isRange {
+				if to, err = value(b); err != nil {
+					return err
+				}
+			} else if hasStep {
+				to = hi
+			}
+			if to < from {
+				return fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+	var c cronSchedule
+	for i, spec := range []struct {
+		set    *[64]bool
+		lo, hi int
+		names  []string
+	}{
+		{&c.minute, 0, 59, nil},
+		{&c.hour, 0, 23, nil},
+		{&c.dom, 1, 31, nil},
+		{&c.month, 1, 12, cronMonths},
+		{&c.dow, 0, 7, cronDays},
+	} {
+		if err := parseCronField(f[i], spec.set, spec.lo, spec.hi, spec.names); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q, field %d: %w", expr, i+1, err)
+		}
+	}
+	if c.dow[7] { // 7 is Sunday too
+		c.dow[0] = true
+	}
+	c.domAny, c.dowAny = f[2] == "*", f[4] == "*"
+	return &c, nil
+}
+
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	if !c.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns up to n start times after from, looking at most five years ahead.
+func (c *cronSchedule) next(from time.Time, n int) []time.Time {
+	var out []time.Time
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for d := 0; d < 5*366 && len(out) < n; d++ {
+		t := day.AddDate(0, 0, d)
+		if !c.matchesDay(t) {
+			continue
+		}
+		for h := 0; h < 24 && len(out) < n; h++ {
+			if !c.hour[h] {
+				continue
+			}
+			for m := 0; m < 60 && len(out) < n; m++ {
+				at := time.Date(t.Year(), t.Month(), t.Day(), h, m, 0, 0, t.Location())
+				if c.minute[m] && at.After(from) {
+					out = append(out, at)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// parseCronWindow splits "0 22 * * 2 for 3h" into the schedule and the window length.
+func parseCronWindow(s string) (*cronSchedule, time.Duration, error) {
+	expr, dur, ok := strings.Cut(s, " for ")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid window %q: want \"<cron expression> for <duration>\", e.g. \"0 22 * * 2 for 3h\"", s)
+	}
+	c, err := parseCron(expr)
+	if err != nil {
+		return nil, 0, err
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(dur))
+	if err != nil || d <= 0 {
+		return nil, 0, fmt.Errorf("invalid window length %q, e.g. 3h or 2h30m", strings.TrimSpace(dur))
+	}
+	return c, d, nil
+}
+
+func newPlanCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts  calcOptions
+		count int
+		from  string
+	)
+	cmd := &cobra.Command{
+		Use:   `plan "<cron> for <duration>"`,
+		Short: "Calculate the upcoming instances of a recurring release window",
+		Long: "Calculate the next --count instances of a recurring window given as a\n" +
+			"standard 5-field cron expression plus a length, e.g. every Tuesday at 22:00\n" +
+			"for three hours:\n\n" +
+			"  nightrelcalc plan \"0 22 * * 2 for 3h\"",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			if err := opts.prepare(cmd.Flags(), cfg, *profileName); err != nil {
+				return err
+			}
+			sched, length, err := parseCronWindow(args[0])
+			if err != nil {
+				return err
+			}
+			if count <= 0 {
+				return fmt.Errorf("--count must be > 0")
+			}
+			start := appClock.Now()
+			if from != "" {
+				if start, err = time.ParseInLocation(time.DateOnly, from, time.Local); err != nil {
+					return fmt.Errorf("invalid --from %q, expected YYYY-MM-DD", from)
+				}
+				start = start.Add(-time.Minute) // include a window at 00:00 that day
+			}
+			var rs []datedResult
+			for _, at := range sched.next(start, count) {
+				row := opts
+				row.Start = at.Format("15:04")
+				row.Length = length.Minutes() / 60
+				res, err := row.compute()
+				if err != nil {
+					return err
+				}
+				r := datedResult{Date: at.Format(time.DateOnly), Result: res, req: row.request()}
+				if err := opts.checkNights(r); err != nil {
+					return err
+				}
+				rs = append(rs, r)
+			}
+			if len(rs) == 0 {
+				return fmt.Errorf("%q has no instances in the next five years", args[0])
+			}
+			return writeBatch(os.Stdout, opts, rs)
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().IntVar(&count, "count", 5, "Number of upcoming instances")
+	cmd.Flags().StringVar(&from, "from", "", "First day to look at, YYYY-MM-DD (default: from now)")
+	return cmd
+}
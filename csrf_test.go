@@ -0,0 +1,61 @@
+package nightrelcalc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckCSRF(t *testing.T) {
+	signer, _ := newCookieSigner("secret", "/")
+	restarted, _ := newCookieSigner("other secret", "/")
+
+	// A browser's first GET: the nonce cookie and the form's token
+	rec := httptest.NewRecorder()
+	token := signer.ensureCSRF(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName || cookies[0].SameSite != http.SameSiteStrictMode || !cookies[0].HttpOnly {
+		t.Fatalf("nonce cookie %v", cookies)
+	}
+	nonce := cookies[0].Value
+	tampered := []byte(token)
+	tampered[0] ^= 1
+
+	// and the next one keeps them
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: nonce})
+	rec = httptest.NewRecorder()
+	if again := signer.ensureCSRF(rec, req); again != token || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("second GET: token %q and cookies %v, want %q and none", again, rec.Result().Cookies(), token)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		signer *cookieSigner
+		cookie string // nonce; "" for none
+		field  string // token; "" for none
+		want   bool
+	}{
+		{"valid", signer, nonce, token, true},
+		{"tampered token", signer, nonce, string(tampered), false},
+		{"another browser's token", signer, "other-nonce", token, false},
+		{"signed before a secret change", restarted, nonce, token, false},
+		{"missing cookie", signer, "", token, false},
+		{"missing token", signer, nonce, "", false},
+	} {
+		form := url.Values{}
+		if tc.field != "" {
+			form.Set(csrfFieldName, tc.field)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tc.cookie})
+		}
+		if got := tc.signer.checkCSRF(req); got != tc.want {
+			t.Errorf("%s: checkCSRF = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package nightrelcalc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- --dry-run and /api/v1/validate ---------------- */
+
+// dryRun is what a calculation would use and produce: its inputs after
+// flags, profile and defaults, the rules that shape the scenarios and the
+// scenarios it would give. Building it records nothing.
+type dryRun struct {
+	Date      string      `json:"date,omitempty"`
+	Event     string      `json:"event,omitempty"`
+	Valid     bool        `json:"valid"`
+	Request   calcRequest `json:"request"`
+	Rules     []string    `json:"rules"`
+	Scenarios []string    `json:"scenarios"`
+}
+
+// request is the calculation the flags ask for, as an API request.
+func (o calcOptions) request() calcRequest {
+	req := calcRequest{
+		Start:       o.Start,
+		Length:      o.Length,
+		Full:        o.Full,
+		NormalStart: o.NormalStart,
+		NormalEnd:   o.NormalEnd,
+		MinRest:     &o.MinRest,
+		Commute:     &o.Commute,
+		MaxOvertime: &o.MaxOvertime,
+		PartTime:    o.PartTime,
+		CoreHours:   o.CoreHours,
+		Commitment:  o.Commitment,
+		Scenarios:   o.Scenarios,
+		Strict:      o.Strict,
+	}
+	if o.Combine >= 0 {
+		req.Combine = &o.Combine
+	}
+	return req
+}
+
+func newDryRun(r datedResult) dryRun {
+	req := r.req.withDefaults()
+	d := dryRun{Date: r.Date, Event: r.Event, Valid: true, Request: req}
+	for _, s := range r.Scenarios {
+		d.Scenarios = append(d.Scenarios, s.Title)
+	}
+	if len(r.Scenarios) == 0 {
+		return d
+	}
+	tl := r.Scenarios[0].Timeline
+
+	if req.Full > 0 {
+		d.Rules = append(d.Rules, fmt.Sprintf("Full day of %s, as given", r.FullDay))
+	} else {
+		d.Rules = append(d.Rules, fmt.Sprintf("Full day of %s, the normal day %s -> %s", r.FullDay, r.NormalStart, r.NormalEnd))
+	}
+	if tl.NextStart > tl.RestEnd {
+		d.Rules = append(d.Rules, fmt.Sprintf("Next day starts at the normal start of the day after the release ends, %s; the %s rest is over by %s",
+			tl.NextStart, r.MinRest, tl.RestEnd))
+	} else {
+		d.Rules = append(d.Rules, fmt.Sprintf("Next day starts at %s, later than the normal start: %s rest after the release ends at %s",
+			tl.NextStart, r.MinRest, r.ReleaseEnd))
+	}
+	if tl.ReleaseEnd.Sub(tl.ReleaseStart) > calc.Hours(*req.MaxOvertime) {
+		d.Rules = append(d.Rules, fmt.Sprintf("Overtime is capped at %s, less than the %s release: the day starts later so part of the release counts as work",
+			r.MaxOvertime, r.ReleaseLen))
+	} else {
+		d.Rules = append(d.Rules, fmt.Sprintf("Overtime is capped at %s; the %s release fits within it", r.MaxOvertime, r.ReleaseLen))
+	}
+	if req.Combine != nil {
+		d.Rules = append(d.Rules, fmt.Sprintf("Combine scenario counts %sh of the release in the full day, the rest as overtime", fmtFloat(*req.Combine)))
+	}
+	return d
+}
+
+// writeDryRun writes the plain-text report of d.
+func writeDryRun(w io.Writer, d dryRun) {
+	req := d.Request
+	inputs := []string{"start=" + req.Start, "length=" + fmtFloat(req.Length)}
+	if req.Combine != nil {
+		inputs = append(inputs, "combine="+fmtFloat(*req.Combine))
+	}
+	if req.Full > 0 {
+		inputs = append(inputs, "full="+fmtFloat(req.Full))
+	}
+	inputs = append(inputs, "normal_start="+req.NormalStart, "normal_end="+req.NormalEnd,
+		"min_rest="+fmtFloat(*req.MinRest), "max_overtime="+fmtFloat(*req.MaxOvertime))
+	if req.Commute != nil && *req.Commute > 0 {
+		inputs = append(inputs, "commute="+fmtFloat(*req.Commute))
+	}
+	if req.PartTime != "" {
+		inputs = append(inputs, "part_time="+req.PartTime)
+	}
+	if req.CoreHours != "" {
+		inputs = append(inputs, "core_hours="+req.CoreHours)
+	}
+	if req.Commitment != "" {
+		inputs = append(inputs, "commitment="+req.Commitment)
+	}
+	if len(req.Scenarios) > 0 {
+		inputs = append(inputs, "scenarios="+formatScenarioList(req.Scenarios))
+	}
+
+	fmt.Fprintf(w, "Valid, would compute: %s\n", strings.Join(inputs, " "))
+	fmt.Fprintln(w, "Rules:")
+	for _, r := range d.Rules {
+		fmt.Fprintf(w, "  - %s\n", r)
+	}
+	fmt.Fprintln(w, "Scenarios:")
+	for _, s := range d.Scenarios {
+		fmt.Fprintf(w, "  - %s\n", s)
+	}
+}
+
+// writeDryRunResult writes one dry run; JSON is the same object as
+// /api/v1/validate returns.
+func writeDryRunResult(w io.Writer, format string, d dryRun) error {
+	switch format {
+	case "json":
+		return writeIndentedJSON(w, d)
+	case "ndjson":
+		return json.NewEncoder(w).Encode(d)
+	}
+	writeDryRun(w, d)
+	return nil
+}
+
+// apiValidateHandler serves /api/v1/validate: the same GET query or POST body
+// as /api/v1/calc, answered with the dry run instead of the result. Nothing
+// is recorded in the stats or the recent calculations.
+func apiValidateHandler(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req calcRequest
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			if req, err = calcRequestFromQuery(r.URL.Query()); err != nil {
+				writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+				return
+			}
+		case http.MethodPost:
+			if req, err = decodeCalcBody(r); err != nil {
+				writeBodyError(w, err)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+		req, err = req.under(cfg)
+		var res *calc.Result
+		if err == nil {
+			res, err = req.compute()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, newDryRun(datedResult{Result: res, req: req}))
+	}
+}
@@ -0,0 +1,111 @@
+package nightrelcalc
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- /api/v1/eligible: who can take a release ---------------- */
+
+// fairnessWindow is how far back a person's releases count towards their
+// fairness score.
+const fairnessWindow = 28 * 24 * time.Hour
+
+// eligiblePerson is someone who can take the release. Score is the hours of
+// release they worked in the fairnessWindow before it; lower is fairer.
+type eligiblePerson struct {
+	Name        string  `json:"name"`
+	Score       float64 `json:"score"`
+	Releases    int     `json:"releases"`               // in the window
+	LastRelease string  `json:"last_release,omitempty"` // YYYY-MM-DD, the latest before the release
+}
+
+type blockedPerson struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type eligibleResponse struct {
+	Roster   string           `json:"roster"`
+	Start    time.Time        `json:"start"`
+	Length   string           `json:"length"`
+	Eligible []eligiblePerson `json:"eligible"` // fairest first
+	Blocked  []blockedPerson  `json:"blocked"`
+}
+
+// load is m's releases in the fairnessWindow before start: their hours and
+// number, and the date of the last one before start at all.
+func (m RosterMember) load(start time.Time) (hours float64, n int, last string) {
+	for _, r := range m.Releases {
+		rs, _ := r.window()
+		if !rs.Before(start) {
+			continue
+		}
+		if start.Sub(rs) <= fairnessWindow {
+			hours += r.Length
+			n++
+		}
+		last = max(last, r.Date)
+	}
+	return hours, n, last
+}
+
+// apiEligibleHandler serves GET /api/v1/eligible?roster=&date=&start=&length=:
+// who of the roster can take the release on date (default today, in the
+// server's time zone) from start for length hours under the roster's rest
+// and night rules and everyone's leave, fairest first, and why the others
+// cannot.
+func apiEligibleHandler(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET")
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+		q := r.URL.Query()
+		name := strings.TrimSpace(q.Get("roster"))
+		roster, ok := cfg.Rosters[name]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, apiError{"unknown roster " + name})
+			return
+		}
+		rs, err := calc.ParseClock(strings.TrimSpace(q.Get("start")))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{"start: " + err.Error()})
+			return
+		}
+		lengthH, err := calc.ParseHours(strings.TrimSpace(q.Get("length")))
+		if err != nil || lengthH <= 0 {
+			writeJSON(w, http.StatusBadRequest, apiError{"length must be > 0 hours, e.g. 4 or 4h30m"})
+			return
+		}
+		day, err := time.ParseInLocation(time.DateOnly, orDefault(q.Get("date"), appClock.Now().Format(time.DateOnly)), time.Local)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{"date: expected YYYY-MM-DD"})
+			return
+		}
+
+		start := calc.DayOf(day).Time(rs)
+		length := calc.Hours(lengthH)
+		rules := roster.rules(cfg)
+		resp := eligibleResponse{Roster: name, Start: start, Length: calc.FmtDuration(length), Eligible: []eligiblePerson{}, Blocked: []blockedPerson{}}
+		for _, m := range roster.Members {
+			if e := m.eligible(r.Context(), start, length, rules); !e.OK {
+				resp.Blocked = append(resp.Blocked, blockedPerson{Name: m.Name, Reason: e.Reason})
+				continue
+			}
+			hours, n, last := m.load(start)
+			resp.Eligible = append(resp.Eligible, eligiblePerson{Name: m.Name, Score: hours, Releases: n, LastRelease: last})
+		}
+		// Least worked first, then whoever has gone longest without one.
+		slices.SortStableFunc(resp.Eligible, func(a, b eligiblePerson) int {
+			return cmp.Or(cmp.Compare(a.Score, b.Score), strings.Compare(a.LastRelease, b.LastRelease))
+		})
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,239 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------------- email (SMTP) ---------------- */
+
+// SMTPConfig is the "smtp" section of the config file:
+//
+//	"smtp": {"host": "smtp.example.com", "port": 587, "username": "bot", "password": "...", "from": "Night releases <bot@example.com>"}
+//
+// TLS is "starttls" (the default, required when the server offers it), "tls"
+// (implicit, usually port 465) or "none" for a local relay.
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
+	TLS      string `json:"tls,omitempty"`
+}
+
+func (c *SMTPConfig) validate() error {
+	if c.Host == "" || c.From == "" {
+		return errors.New("smtp: host and from are required")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("smtp: invalid from %q: %w", c.From, err)
+	}
+	switch c.TLS {
+	case "", "starttls", "tls", "none":
+	default:
+		return fmt.Errorf("smtp: invalid tls %q (have: starttls, tls, none)", c.TLS)
+	}
+	return nil
+}
+
+var emailTpl = template.Must(template.New("email").Parse(`<!doctype html>
+<html><body style="font-family: system-ui, sans-serif; color: #212121">
+<h2 style="margin: 0 0 4px">Night release {{.Result.ReleaseStart}} &rarr; {{.Result.ReleaseEnd}}</h2>
+<p style="margin: 0 0 16px; color: #666">{{.Date}} · length {{.Result.ReleaseLen}} · full day {{.Result.FullDay}} · min rest {{.Result.MinRest}} · max overtime {{.Result.MaxOvertime}}</p>
+{{range .Result.Scenarios}}
+<table style="border-collapse: collapse; margin: 0 0 16px">
+<tr><th colspan="2" style="text-align: left; padding: 4px 0">{{.Title}}</th></tr>
+<tr><td style="padding: 2px 16px 2px 0; color: #666">Work</td><td>{{.WorkHours}}</td></tr>
+<tr><td style="padding: 2px 16px 2px 0; color: #666">Release</td><td>{{.ReleaseWindow}}</td></tr>
+<tr><td style="padding: 2px 16px 2px 0; color: #666">Total</td><td>{{.TotalWork}}</td></tr>
+<tr><td style="padding: 2px 16px 2px 0; color: #666">Release included</td><td>{{.ReleaseIncluded}}</td></tr>
+<tr><td style="padding: 2px 16px 2px 0; color: #666">Overtime</td><td>{{.Overtime}}</td></tr>
+<tr><td style="padding: 2px 16px 2px 0; color: #666">Next day</td><td>{{.NextDayHours}}</td></tr>
+</table>
+{{end}}
+{{with .Link}}<p><a href="{{.}}">Open in nightrelcalc</a></p>{{end}}
+<p style="color: #999; font-size: 12px">The attached calendar has the release, the work day, the mandatory rest and the next day.</p>
+</body></html>
+`))
+
+// planEmail builds the message: text and HTML bodies, plus the plan as an
+// ICS attachment dated on the day the release runs next.
+func planEmail(from string, to []string, n notice, now time.Time) ([]byte, error) {
+	r := datedResult{Result: n.Result}
+	if len(r.Scenarios) > 0 {
+		r.Date = releaseDay(now, r.Scenarios[0].Timeline).Date().Format(time.DateOnly)
+	}
+
+	var text bytes.Buffer
+	writeCLI(&text, n.Result)
+	if n.Link != "" {
+		text.WriteString(n.Link + "\n")
+	}
+	var html bytes.Buffer
+	if err := emailTpl.Execute(&html, struct {
+		datedResult
+		Link string
+	}{r, n.Link}); err != nil {
+		return nil, err
+	}
+	var ics bytes.Buffer
+	if err := writeICS(&ics, []datedResult{r}, now); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	alt := multipart.NewWriter(&body)
+	for _, b := range []struct {
+		typ  string
+		data []byte
+	}{{"text/plain; charset=utf-8", text.Bytes()}, {"text/html; charset=utf-8", html.Bytes()}} {
+		p, err := alt.CreatePart(textproto.MIMEHeader{"Content-Type": {b.typ}, "Content-Transfer-Encoding": {"quoted-printable"}})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(p)
+		_, _ = qp.Write(b.data)
+		_ = qp.Close()
+	}
+	_ = alt.Close()
+
+	var msg bytes.Buffer
+	subject := fmt.Sprintf("%s on %s", n.title(), r.Date)
+	mixed := multipart.NewWriter(&msg)
+	for _, h := range [][2]string{
+		{"From", from},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + randomHex(12) + "@nightrelcalc>"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/mixed; boundary=" + mixed.Boundary()},
+	} {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+
+	part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()}})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(body.Bytes())
+
+	att, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`text/calendar; charset=utf-8; method=PUBLISH; name="nightrelcalc-plan.ics"`},
+		"Content-Disposition":       {`attachment; filename="nightrelcalc-plan.ics"`},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	enc := base64.StdEncoding.EncodeToString(ics.Bytes())
+	for len(enc) > 76 {
+		att.Write([]byte(enc[:76] + "\r\n"))
+		enc = enc[76:]
+	}
+	att.Write([]byte(enc + "\r\n"))
+	_ = mixed.Close()
+	return msg.Bytes(), nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sendMail delivers msg to the recipients through c.
+func sendMail(ctx context.Context, c *SMTPConfig, to []string, msg []byte) error {
+	port := c.Port
+	if port == 0 {
+		port = 587
+		if c.TLS == "tls" {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+	d := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if c.TLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: d, Config: &tls.Config{ServerName: c.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if c.TLS == "" || c.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return err
+		}
+	}
+	from, _ := mail.ParseAddress(c.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("%s: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// postEmail mails n to o.Email through the config file's SMTP server.
+func postEmail(ctx context.Context, o NotifyOptions, n notice) error {
+	if o.smtp == nil {
+		return errors.New("needs an smtp section in the config file")
+	}
+	msg, err := planEmail(o.smtp.From, o.Email, n, appClock.Now())
+	if err != nil {
+		return err
+	}
+	return sendMail(ctx, o.smtp, o.Email, msg)
+}
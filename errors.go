@@ -0,0 +1,92 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+type ErrorData struct {
+	Status     int
+	StatusText string
+	Message    string
+
+	Version  string
+	BasePath string
+	CSPNonce string
+	Brand    Brand
+}
+
+// pageRenderer writes HTML pages and styled error pages for the web UI.
+type pageRenderer struct {
+	basePath string
+	brand    Brand
+	errorTpl *template.Template
+}
+
+// writeTemplate executes tpl into a buffer and only then writes it with
+// status; on an error nothing is written.
+func writeTemplate(w http.ResponseWriter, tpl *template.Template, status int, data any) error {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+	return nil
+}
+
+// render writes tpl with status (see writeTemplate), so a template failure
+// becomes a logged 500 instead of a half-written 200 page.
+func (p pageRenderer) render(w http.ResponseWriter, r *http.Request, tpl *template.Template, status int, data any) {
+	if err := writeTemplate(w, tpl, status, data); err != nil {
+		log.Printf("render %s %s: %v", tpl.Name(), r.URL.Path, err)
+		p.error(w, r, http.StatusInternalServerError, "Something went wrong while rendering this page.")
+	}
+}
+
+// error writes a styled error page with status.
+func (p pageRenderer) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	data := ErrorData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    msg,
+		Version:    appVersion,
+		BasePath:   p.basePath,
+		CSPNonce:   cspNonce(r),
+		Brand:      p.brand,
+	}
+	if err := writeTemplate(w, p.errorTpl, status, data); err != nil {
+		log.Printf("render error page: %v", err)
+		http.Error(w, msg, status)
+	}
+}
+
+// methodNotAllowed answers 405 with the Allow header set.
+func (p pageRenderer) methodNotAllowed(w http.ResponseWriter, r *http.Request, allow string) {
+	w.Header().Set("Allow", allow)
+	p.error(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported here, use "+allow+".")
+}
+
+const errorHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Brand.Name}} - {{.StatusText}}</title>
+  <meta name="robots" content="noindex">
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 960px; box-sizing: border-box; }
+    h2 { margin-top: 0; font-weight: 600; }
+    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
+    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
+  </style>
+</head>
+<body>
+  <h2>{{.Status}} {{.StatusText}}</h2>
+  <div class="err">{{.Message}}</div>
+  <p><a href="{{.BasePath}}/">Back to the calculator</a></p>
+  <footer>{{with .Brand.Footer}}<div>{{.}}</div>{{end}}nightrelcalc v{{.Version}}</footer>
+</body>
+</html>`
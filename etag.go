@@ -0,0 +1,63 @@
+package nightrelcalc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// pageETag identifies a rendered form/result page: everything that goes into
+// the body (the result is a pure function of the form values, the config
+// scenarios, the version and, for team results, the day). The CSRF token is
+// per browser, so ETags are too.
+func pageETag(d PageData) string {
+	rules, _ := json.Marshal(d.rules)
+	return hashETag(appVersion, d.BasePath, d.day, string(rules), d.Start, d.Length, d.Combine,
+		d.NormalStart, d.NormalEnd, d.MinRest, d.Commute, d.MaxOvertime, d.PartTime, d.CoreHours, d.Commitment, d.Scenarios, d.Team, d.TZ, strconv.FormatBool(d.Handoff), d.CSRFToken, d.CustomCSS, d.OGImage, d.PrintURL, d.ExportURL, d.CompareURL, d.ShortURL, d.OEmbed,
+		d.Brand.Title, d.Brand.Logo, d.Brand.Footer, d.Brand.Accent, d.Profile, strings.Join(d.Profiles, ","),
+		assetHash("app.css"), assetHash("app.js"))
+}
+
+// hashETag is a strong ETag over parts.
+func hashETag(parts ...string) string {
+	h := sha256.New()
+	for _, s := range parts {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:18]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotModified sets ETag and caching headers and, when the client already
+// has this version, answers 304 and returns true.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	hdr := w.Header()
+	hdr.Set("ETag", etag)
+	hdr.Set("Cache-Control", "private, no-cache")
+	hdr.Add("Vary", "Cookie")
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// pageNonce is the CSP nonce for a cached page: stable for one ETag so a 304
+// keeps matching the cached body, but keyed so it can't be predicted.
+func (s *cookieSigner) pageNonce(etag string) string {
+	return base64.StdEncoding.EncodeToString(s.mac("csp-nonce:" + etag)[:16])
+}
@@ -0,0 +1,173 @@
+package nightrelcalc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* ---------------- /feed/{token}.ics subscription feeds ---------------- */
+
+// FeedConfig is one entry of the config file's "feeds": a live calendar of
+// the upcoming instances of recurring release windows (as for the plan
+// command), computed under a profile:
+//
+//	"feeds": {"payments": {"token": "<long random string>", "windows": ["0 22 * * 2 for 3h"], "profile": "payments"}}
+//
+// The token is the feed's only protection, as calendar clients cannot log
+// in; whoever has the URL can read the feed.
+type FeedConfig struct {
+	Token   string   `json:"token"`
+	Windows []string `json:"windows"`
+	Profile string   `json:"profile,omitempty"`
+	Count   int      `json:"count,omitempty"` // upcoming releases in the feed, default 20
+}
+
+// feedMinToken keeps feed URLs from being guessable.
+const feedMinToken = 16
+
+// checkFeedToken checks the token of a feed or of a roster member's.
+func checkFeedToken(token string) error {
+	if len(token) < feedMinToken {
+		return fmt.Errorf("token must be at least %d characters", feedMinToken)
+	}
+	if strings.ContainsAny(token, "/?#") {
+		return errors.New(`token must not contain "/", "?" or "#"`)
+	}
+	return nil
+}
+
+func (f FeedConfig) validate(cfg Config) error {
+	if err := checkFeedToken(f.Token); err != nil {
+		return err
+	}
+	if len(f.Windows) == 0 {
+		return errors.New("windows are required")
+	}
+	for _, w := range f.Windows {
+		if _, _, err := parseCronWindow(w); err != nil {
+			return err
+		}
+	}
+	if f.Count < 0 {
+		return errors.New("count must be >= 0")
+	}
+	_, err := cfg.lookupProfile(f.Profile)
+	return err
+}
+
+// plans are the next releases of f's windows after now, in order.
+func (f FeedConfig) plans(cfg Config, now time.Time) ([]datedResult, error) {
+	p, err := cfg.lookupProfile(f.Profile)
+	if err != nil {
+		return nil, err
+	}
+	count := f.Count
+	if count == 0 {
+		count = 20
+	}
+	type instance struct {
+		at     time.Time
+		length time.Duration
+	}
+	var all []instance
+	for _, w := range f.Windows {
+		sched, length, err := parseCronWindow(w)
+		if err != nil {
+			return nil, err
+		}
+		for _, at := range sched.next(now, count) {
+			all = append(all, instance{at, length})
+		}
+	}
+	slices.SortStableFunc(all, func(a, b instance) int { return a.at.Compare(b.at) })
+	var rs []datedResult
+	for _, in := range all[:min(count, len(all))] {
+		req := cfg.request(p, in.at.Format("15:04"), in.length.Minutes()/60)
+		res, err := req.compute()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, datedResult{Date: in.at.Format(time.DateOnly), Result: res, req: req})
+	}
+	return rs, nil
+}
+
+// request is the calculation of a release from start for lengthH hours
+// under p, with the config's and p's extra scenarios.
+func (c Config) request(p Profile, start string, lengthH float64) calcRequest {
+	req := calcRequest{
+		Start:       start,
+		Length:      lengthH,
+		NormalStart: p.NormalStart,
+		NormalEnd:   p.NormalEnd,
+		MinRest:     p.MinRest,
+		Commute:     p.Commute,
+		MaxOvertime: p.MaxOvertime,
+		PartTime:    p.PartTime,
+		CoreHours:   p.CoreHours,
+		rules:       c.rules(p),
+	}
+	return req
+}
+
+// hasFeeds reports whether there is anything to serve at /feed/.
+func (c Config) hasFeeds() bool {
+	if len(c.Feeds) > 0 {
+		return true
+	}
+	for _, r := range c.Rosters {
+		for _, m := range r.Members {
+			if m.Feed != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// feedHandler serves /feed/{token}.ics, the feeds and the roster members'
+// own calendars. An unknown token is a plain 404, so the handler does not
+// tell which tokens exist.
+func feedHandler(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+		match := func(t string) bool {
+			return ok && t != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1
+		}
+		var plans func() ([]datedResult, error)
+		for _, f := range cfg.Feeds {
+			if match(f.Token) {
+				plans = func() ([]datedResult, error) { return f.plans(cfg, appClock.Now()) }
+			}
+		}
+		for _, roster := range cfg.Rosters {
+			for _, m := range roster.Members {
+				if match(m.Feed) {
+					plans = func() ([]datedResult, error) { return roster.plans(cfg, m) }
+				}
+			}
+		}
+		if plans == nil {
+			http.NotFound(w, r)
+			return
+		}
+		rs, err := plans()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var b strings.Builder
+		if err := writeICS(&b, rs, appClock.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Cache-Control", "private, max-age=900")
+		_, _ = w.Write([]byte(b.String()))
+	}
+}
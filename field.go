@@ -0,0 +1,89 @@
+package nightrelcalc
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- --print <field> ---------------- */
+
+// stringFields are the string fields of v by JSON name.
+func stringFields(v any) map[string]string {
+	b, _ := json.Marshal(v)
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	out := map[string]string{}
+	for k, val := range m {
+		if s, ok := val.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+// scenarioFields are a scenario's JSON fields plus the single clock times
+// scripts usually want.
+func scenarioFields(s calc.Scenario) map[string]string {
+	out := stringFields(s)
+	delete(out, "title")
+	clock := func(o calc.Offset) string { return o.Clock().String() }
+	out["work_start"] = clock(s.Timeline.WorkStart)
+	out["work_end"] = clock(s.Timeline.WorkEnd)
+	out["next_day_start"] = clock(s.Timeline.NextStart)
+	out["next_day_end"] = clock(s.Timeline.NextEnd)
+	out["rest_end"] = clock(s.Timeline.RestEnd)
+	return out
+}
+
+// resultField looks up a --print spec: a result field ("release_end") or a
+// scenario field with an optional index ("overtime[1]", default [0]).
+func resultField(res *calc.Result, spec string) (string, error) {
+	name, idx := strings.TrimSpace(spec), 0
+	open := strings.IndexByte(name, '[')
+	if open >= 0 && strings.HasSuffix(name, "]") {
+		n, err := strconv.Atoi(name[open+1 : len(name)-1])
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid --print %q: the index must be a scenario number from 0", spec)
+		}
+		name, idx = name[:open], n
+	}
+	if len(res.Scenarios) == 0 {
+		return "", fmt.Errorf("invalid --print %q: no scenarios", spec)
+	}
+
+	top := stringFields(res)
+	tl := res.Scenarios[0].Timeline
+	top["release_start"] = tl.ReleaseStart.Clock().String()
+	top["release_end"] = tl.ReleaseEnd.Clock().String()
+	if v, ok := top[name]; ok && open < 0 {
+		return v, nil
+	}
+	fields := scenarioFields(res.Scenarios[0])
+	if _, ok := fields[name]; !ok {
+		return "", fmt.Errorf("unknown --print field %q (have: %s)", spec, strings.Join(printFieldNames(res), ", "))
+	}
+	if idx >= len(res.Scenarios) {
+		return "", fmt.Errorf("invalid --print %q: there are %d scenarios (0-%d)", spec, len(res.Scenarios), len(res.Scenarios)-1)
+	}
+	return scenarioFields(res.Scenarios[idx])[name], nil
+}
+
+// printFieldNames lists the --print fields of res, scenario fields as "name[i]".
+func printFieldNames(res *calc.Result) []string {
+	var names []string
+	for k := range stringFields(res) {
+		names = append(names, k)
+	}
+	if len(res.Scenarios) > 0 {
+		for k := range scenarioFields(res.Scenarios[0]) {
+			names = append(names, k+"[i]")
+		}
+	}
+	slices.Sort(names)
+	return names
+}
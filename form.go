@@ -0,0 +1,150 @@
+package nightrelcalc
+
+import (
+	"slices"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- web form validation ---------------- */
+
+// Form field labels, as on the page.
+var fieldLabels = map[string]string{
+	"start":        "Release start",
+	"length":       "Release length",
+	"combine":      "Combine",
+	"normal_start": "Normal work start",
+	"normal_end":   "Normal work end",
+	"min_rest":     "Min rest",
+	"commute":      "Commute",
+	"part_time":    "Part time",
+	"core_hours":   "Core hours",
+	"commitment":   "Next-day commitment",
+	"max_overtime": "Max overtime",
+	"profile":      "Profile",
+	"scenarios":    "Scenarios",
+	"team":         "Team",
+	"handoff":      "Follow the sun",
+	"tz":           "Time zone",
+}
+
+// fieldErrors maps a form field name to the message shown next to it.
+type fieldErrors map[string]string
+
+// formInput is the web form with the defaults applied and the numbers parsed.
+type formInput struct {
+	Start, Length, Combine string
+	NormalStart, NormalEnd string
+	MinRest, MaxOvertime   string
+	Commute                string
+	PartTime               string // "80%" or "30h" a week, optional
+	CoreHours              string // HH:MM-HH:MM, optional
+	Commitment             string // "HH:MM label", optional
+	Scenarios              string // picked scenario numbers, e.g. 1,3; "" for all
+
+	LengthH, CombineH      float64 // CombineH < 0: no combine scenario
+	MinRestH, MaxOvertimeH float64
+	CommuteH               float64
+	Picks                  []int
+}
+
+// validateForm checks every field rather than stopping at the first problem,
+// so the form can mark all offending fields at once.
+func validateForm(start, length, combine, normalStart, normalEnd, minRest, commute, maxOvertime, partTime, coreHours, commitment, scenarios string) (formInput, fieldErrors) {
+	in := formInput{
+		Start:       start,
+		Length:      length,
+		Combine:     combine,
+		NormalStart: orDefault(normalStart, webDefaultNormalStart),
+		NormalEnd:   orDefault(normalEnd, webDefaultNormalEnd),
+		MinRest:     orDefault(minRest, webDefaultMinRest),
+		Commute:     orDefault(commute, webDefaultCommute),
+		PartTime:    partTime,
+		CoreHours:   coreHours,
+		Commitment:  commitment,
+		MaxOvertime: orDefault(maxOvertime, webDefaultMaxOvertime),
+		CombineH:    -1,
+	}
+	errs := fieldErrors{}
+
+	if in.Start == "" {
+		errs["start"] = "required (HH:MM)"
+	} else if _, err := calc.ParseClock(in.Start); err != nil {
+		errs["start"] = "expected a time such as 21:30, 2130 or 9:30pm"
+	}
+
+	var err error
+	if in.LengthH, err = calc.ParseHours(in.Length); err != nil || in.LengthH <= 0 {
+		errs["length"] = "must be > 0 hours, e.g. 4 or 4h30m"
+	}
+
+	if in.Combine != "" {
+		if in.CombineH, err = calc.ParseHours(in.Combine); err != nil || in.CombineH < 0 {
+			errs["combine"] = "must be >= 0 hours, or empty"
+		}
+	}
+
+	ns, errNS := calc.ParseClock(in.NormalStart)
+	if errNS != nil {
+		errs["normal_start"] = "expected a time such as 09:00 or 9am"
+	}
+	ne, errNE := calc.ParseClock(in.NormalEnd)
+	if errNE != nil {
+		errs["normal_end"] = "expected a time such as 17:30 or 5:30pm"
+	} else if errNS == nil && ne <= ns {
+		errs["normal_end"] = "must be after the normal work start (same day)"
+	}
+
+	if _, _, err := calc.ParsePartTime(in.PartTime); err != nil {
+		errs["part_time"] = "expected a percentage such as 80% or weekly hours such as 30h, or empty"
+	}
+	if in.CoreHours != "" {
+		if _, _, err := calc.ParseClockRange(in.CoreHours); err != nil {
+			errs["core_hours"] = "expected HH:MM-HH:MM, e.g. 10:00-15:00, or empty"
+		}
+	}
+	if in.Commitment != "" {
+		if _, _, err := calc.ParseCommitment(in.Commitment); err != nil {
+			errs["commitment"] = "expected HH:MM and an optional label, e.g. 10:00 customer call, or empty"
+		}
+	}
+
+	if in.Picks, err = parseScenarioList(scenarios); err != nil {
+		errs["scenarios"] = "pick scenarios by number, e.g. 1,3"
+	}
+	in.Scenarios = formatScenarioList(in.Picks)
+
+	if in.MinRestH, err = calc.ParseHours(in.MinRest); err != nil || in.MinRestH <= 0 {
+		errs["min_rest"] = "must be > 0 hours (default 11)"
+	}
+	if in.CommuteH, err = calc.ParseHours(in.Commute); err != nil || in.CommuteH < 0 {
+		errs["commute"] = "must be >= 0 hours each way (default 0)"
+	}
+	if in.MaxOvertimeH, err = calc.ParseHours(in.MaxOvertime); err != nil || in.MaxOvertimeH < 0 {
+		errs["max_overtime"] = "must be >= 0 hours (default 4)"
+	}
+
+	if len(errs) == 0 {
+		return in, nil
+	}
+	return in, errs
+}
+
+// pick keeps only the picked scenarios of res. Picking all of them is the
+// same as picking none, so the share link leaves them out.
+func (in *formInput) pick(res *calc.Result) error {
+	if slices.Equal(in.Picks, scenarioNumbers(len(res.Scenarios))) {
+		in.Picks, in.Scenarios = nil, ""
+	}
+	return selectScenarios(res, in.Picks)
+}
+
+// compute runs the calculation with the config's extra scenarios; the web
+// form always derives the full day.
+func (in formInput) compute(rules []calc.Rule) (*calc.Result, error) {
+	res, err := calc.Compute(in.Start, in.LengthH, in.CombineH, 0, in.NormalStart, in.NormalEnd, in.MinRestH, in.CommuteH, in.MaxOvertimeH, in.PartTime, rules...)
+	if err != nil {
+		return nil, err
+	}
+	return res, calc.CheckNextDay(res, in.CoreHours, in.Commitment)
+}
@@ -0,0 +1,126 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push github: comment on an issue or pull request ---------------- */
+
+// GitHubConfig is the "github" section of the config file:
+//
+//	"github": {"token": "github_pat_...", "repo": "acme/deploys"}
+//
+// The token needs write access to issues (or pull requests) of the repo.
+type GitHubConfig struct {
+	Token  string `json:"token"`
+	Repo   string `json:"repo,omitempty"`    // owner/name used when --repo is not given
+	APIURL string `json:"api_url,omitempty"` // GitHub Enterprise, e.g. https://github.example.com/api/v3
+}
+
+func (c *GitHubConfig) validate() error {
+	if c.Token == "" {
+		return errors.New("github: token is required")
+	}
+	if c.Repo != "" {
+		if _, err := splitRepo(c.Repo); err != nil {
+			return fmt.Errorf("github: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitRepo checks an "owner/name" repository and returns it as path segments.
+func splitRepo(repo string) ([]string, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repo %q, expected owner/name", repo)
+	}
+	return parts, nil
+}
+
+// planComment is the comment body for the trackers: the Markdown plan and
+// where it came from.
+func planComment(r datedResult) string {
+	var b strings.Builder
+	writeMarkdown(&b, r)
+	fmt.Fprintf(&b, "\n<sub>Staffing plan computed by nightrelcalc %s.</sub>\n", appVersion)
+	return b.String()
+}
+
+func newPushGitHubCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts  pushOptions
+		repo  string
+		issue int
+	)
+	cmd := &cobra.Command{
+		Use:   "github",
+		Short: "Comment the plan on a GitHub issue or pull request",
+		Long: "Post the plan as a Markdown comment on an issue or pull request, e.g. the\n" +
+			"release tracking issue, so the staffing plan sits next to the deployment\n" +
+			"discussion. Needs a \"github\" section with a token in the config file.\n\n" +
+			"  nightrelcalc push github --repo acme/deploys --issue 42 --start 22:00 --length 3",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			if issue <= 0 {
+				return errors.New("--issue is required (the issue or pull request number)")
+			}
+			body := planComment(r)
+			if opts.calc.DryRun {
+				fmt.Print(body)
+				return nil
+			}
+			if cfg.GitHub == nil {
+				return errors.New(`push github needs a "github" section with a token in the config file`)
+			}
+			parts, err := splitRepo(orDefault(repo, cfg.GitHub.Repo))
+			if err != nil {
+				return err
+			}
+
+			api := strings.TrimRight(orDefault(cfg.GitHub.APIURL, "https://api.github.com"), "/")
+			u := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", api, parts[0], parts[1], issue)
+			b, err := json.Marshal(map[string]string{"body": body})
+			if err != nil {
+				return err
+			}
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/vnd.github+json")
+			req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+			req.Header.Set("Authorization", "Bearer "+cfg.GitHub.Token)
+			var created struct {
+				HTMLURL string `json:"html_url"`
+			}
+			if err := doJSON(req, &created); err != nil {
+				return fmt.Errorf("github: %w", err)
+			}
+			fmt.Println(created.HTMLURL)
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&repo, "repo", "", "Repository, owner/name (default: github.repo of the config)")
+	cmd.Flags().IntVar(&issue, "issue", 0, "Issue or pull request number to comment on")
+	return cmd
+}
@@ -0,0 +1,113 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push gitlab: comment on a merge request or issue ---------------- */
+
+// GitLabConfig is the "gitlab" section of the config file:
+//
+//	"gitlab": {"token": "glpat-...", "project": "acme/platform/deploys"}
+//
+// The token needs the api scope on the project.
+type GitLabConfig struct {
+	Token   string `json:"token"`
+	Project string `json:"project,omitempty"` // path or numeric id used when --project is not given
+	URL     string `json:"url,omitempty"`     // self-managed instance, default https://gitlab.com
+}
+
+func (c *GitLabConfig) validate() error {
+	if c.Token == "" {
+		return errors.New("gitlab: token is required")
+	}
+	return nil
+}
+
+func newPushGitLabCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts      pushOptions
+		project   string
+		mr, issue int
+	)
+	cmd := &cobra.Command{
+		Use:   "gitlab",
+		Short: "Comment the plan on a GitLab merge request or issue",
+		Long: "Post the plan as a Markdown note on a merge request (--mr) or issue\n" +
+			"(--issue), given by its IID within the project, e.g. the release MR.\n" +
+			"Needs a \"gitlab\" section with a token in the config file.\n\n" +
+			"  nightrelcalc push gitlab --project acme/deploys --mr 17 --start 22:00 --length 3",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			kind, iid := "merge_requests", mr
+			switch {
+			case (mr > 0) == (issue > 0):
+				return errors.New("give either --mr or --issue (the IID in the project)")
+			case issue > 0:
+				kind, iid = "issues", issue
+			}
+			body := planComment(r)
+			if opts.calc.DryRun {
+				fmt.Print(body)
+				return nil
+			}
+			if cfg.GitLab == nil {
+				return errors.New(`push gitlab needs a "gitlab" section with a token in the config file`)
+			}
+			project = orDefault(project, cfg.GitLab.Project)
+			if project == "" {
+				return errors.New("--project is required (path or id; or set gitlab.project in the config)")
+			}
+
+			base := strings.TrimRight(orDefault(cfg.GitLab.URL, "https://gitlab.com"), "/")
+			u := fmt.Sprintf("%s/api/v4/projects/%s/%s/%d/notes", base, url.PathEscape(project), kind, iid)
+			b, err := json.Marshal(map[string]string{"body": body})
+			if err != nil {
+				return err
+			}
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("PRIVATE-TOKEN", cfg.GitLab.Token)
+			var note struct {
+				ID int `json:"id"`
+			}
+			if err := doJSON(req, &note); err != nil {
+				return fmt.Errorf("gitlab: %w", err)
+			}
+			// Notes have no URL of their own; a project path gives the page.
+			if _, err := strconv.Atoi(project); err != nil {
+				fmt.Printf("%s/%s/-/%s/%d#note_%d\n", base, project, kind, iid, note.ID)
+			} else {
+				fmt.Printf("Added note %d to %s %d of project %s\n", note.ID, strings.TrimSuffix(strings.ReplaceAll(kind, "_", " "), "s"), iid, project)
+			}
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&project, "project", "", "Project path (group/name) or numeric id (default: gitlab.project of the config)")
+	cmd.Flags().IntVar(&mr, "mr", 0, "Merge request IID to comment on")
+	cmd.Flags().IntVar(&issue, "issue", 0, "Issue IID to comment on")
+	return cmd
+}
@@ -0,0 +1,137 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- follow the sun: hand a long release around the team ---------------- */
+
+// handoffMember is a team member's normal hours, as times of their own
+// day in Loc.
+type handoffMember struct {
+	Name                   string
+	Loc                    *time.Location
+	NormalStart, NormalEnd calc.Offset
+}
+
+func newHandoffMember(m teamMember, in formInput, releaseLoc *time.Location) (handoffMember, error) {
+	h := handoffMember{Name: m.Name, Loc: releaseLoc}
+	var err error
+	if m.TZ != "" {
+		if h.Loc, err = time.LoadLocation(m.TZ); err != nil {
+			return h, fmt.Errorf("%s: unknown time zone %q", m.Name, m.TZ)
+		}
+	}
+	if h.NormalStart, err = calc.ParseClock(orDefault(m.Fields["normal_start"], in.NormalStart)); err != nil {
+		return h, fmt.Errorf("%s: normal work start: %w", m.Name, err)
+	}
+	if h.NormalEnd, err = calc.ParseClock(orDefault(m.Fields["normal_end"], in.NormalEnd)); err != nil {
+		return h, fmt.Errorf("%s: normal work end: %w", m.Name, err)
+	}
+	return h, nil
+}
+
+// working reports whether t is within m's normal hours, in their time zone.
+func (m handoffMember) working(t time.Time) bool {
+	local := t.In(m.Loc)
+	at := calc.DayOf(local).Offset(local)
+	return at >= m.NormalStart && at < m.NormalEnd
+}
+
+// handoffSegment is one person's part of a follow-the-sun release.
+type handoffSegment struct {
+	member   int // index in the team
+	Name     string
+	TZ       string    // the person's time zone
+	From, To time.Time // in the person's time zone
+	Next     string    // who takes over at To; "" for the last part
+
+	// Release is the part in the release's time zone, Local in the
+	// person's, both as "Tue 22:00 → Wed 02:00".
+	Release, Local string
+	Len            string
+}
+
+// loadZone is the time zone named tz, or the server's for "".
+func loadZone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q, expected a name such as Europe/Berlin", tz)
+	}
+	return loc, nil
+}
+
+// releaseInstant is when the release of in runs in loc: today, or tomorrow
+// when today's is already over at now (as releaseDay for the CLI).
+func releaseInstant(now time.Time, in formInput, loc *time.Location) (start time.Time, length time.Duration) {
+	rs, _ := calc.ParseClock(in.Start)
+	length = calc.Hours(in.LengthH)
+	start = calc.DayOf(now.In(loc)).Time(rs)
+	if now.After(start.Add(length)) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start, length
+}
+
+// handoffPlan splits the release from start for length between team, in
+// team order: whoever holds it hands off at the end of their normal hours
+// to the first person whose normal hours have begun, and keeps it while
+// nobody's have. The first holder is the first person working at the
+// start, or else the first of the team. A person hands off at most once.
+func handoffPlan(team []handoffMember, start time.Time, length time.Duration) []handoffSegment {
+	end := start.Add(length)
+	done := make([]bool, len(team))
+	cur := -1
+	var segs []handoffSegment
+	for t := start; t.Before(end); t = t.Add(time.Minute) {
+		if cur >= 0 && team[cur].working(t) {
+			continue
+		}
+		next := -1
+		for i, m := range team {
+			if !done[i] && i != cur && m.working(t) {
+				next = i
+				break
+			}
+		}
+		if next < 0 && cur < 0 {
+			next = 0
+		}
+		if next < 0 {
+			continue
+		}
+		if cur >= 0 {
+			segs[len(segs)-1].To = t
+			done[cur] = true
+		}
+		cur = next
+		segs = append(segs, handoffSegment{member: next, From: t})
+	}
+	if len(segs) > 0 {
+		segs[len(segs)-1].To = end
+	}
+	for i := range segs {
+		s := &segs[i]
+		m := team[s.member]
+		s.Name, s.TZ = m.Name, m.Loc.String()
+		s.Release = fmtSpan(s.From.In(start.Location()), s.To.In(start.Location()))
+		s.From, s.To = s.From.In(m.Loc), s.To.In(m.Loc)
+		s.Local = fmtSpan(s.From, s.To)
+		s.Len = calc.FmtDuration(s.To.Sub(s.From))
+		if i+1 < len(segs) {
+			s.Next = team[segs[i+1].member].Name
+		}
+	}
+	return segs
+}
+
+// fmtSpan is "Tue 22:00 → Wed 02:00".
+func fmtSpan(from, to time.Time) string {
+	return from.Format("Mon 15:04") + " → " + to.Format("Mon 15:04")
+}
@@ -0,0 +1,89 @@
+package nightrelcalc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Default Content-Security-Policy; "{nonce}" is replaced per request and the
+// same nonce is put on the page's inline <style> and <script> blocks.
+// 'wasm-unsafe-eval' lets the form load the offline compute core (calc.wasm).
+const defaultCSP = "default-src 'self'; script-src 'self' 'wasm-unsafe-eval' 'nonce-{nonce}'; style-src 'self' 'nonce-{nonce}'; img-src 'self' data:; form-action 'self'; base-uri 'none'; object-src 'none'"
+
+// HeaderOptions are the security response headers; an empty value disables a header.
+type HeaderOptions struct {
+	CSP            string
+	FrameAncestors string
+	ReferrerPolicy string
+}
+
+func (o *HeaderOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.CSP, "csp", defaultCSP, "Content-Security-Policy ({nonce} = per-request script/style nonce, empty = off)")
+	fs.StringVar(&o.FrameAncestors, "frame-ancestors", "'none'", "CSP frame-ancestors sources allowed to embed the UI (empty = any)")
+	fs.StringVar(&o.ReferrerPolicy, "referrer-policy", "strict-origin-when-cross-origin", "Referrer-Policy header (empty = off)")
+}
+
+type cspKey struct{}
+
+// cspState is the per-request nonce and the policy it is rendered into.
+type cspState struct {
+	opts  HeaderOptions
+	nonce string
+}
+
+func (c *cspState) setHeader(hdr http.Header) {
+	var directives []string
+	if c.opts.CSP != "" {
+		directives = append(directives, strings.ReplaceAll(c.opts.CSP, "{nonce}", c.nonce))
+	}
+	if c.opts.FrameAncestors != "" {
+		directives = append(directives, "frame-ancestors "+c.opts.FrameAncestors)
+	}
+	if len(directives) > 0 {
+		hdr.Set("Content-Security-Policy", strings.Join(directives, "; "))
+	}
+}
+
+// cspNonce returns the nonce securityHeaders attached to the request ("" if none).
+func cspNonce(r *http.Request) string {
+	if c, ok := r.Context().Value(cspKey{}).(*cspState); ok {
+		return c.nonce
+	}
+	return ""
+}
+
+// pinCSPNonce replaces the random nonce with a fixed one and returns it. Used
+// by cacheable pages: a 304 must carry the same nonce as the cached body.
+func pinCSPNonce(w http.ResponseWriter, r *http.Request, nonce string) string {
+	c, ok := r.Context().Value(cspKey{}).(*cspState)
+	if !ok {
+		return ""
+	}
+	c.nonce = nonce
+	c.setHeader(w.Header())
+	return nonce
+}
+
+// securityHeaders sets CSP, nosniff and referrer headers on every response
+// and makes a fresh nonce available to templates via cspNonce.
+func securityHeaders(o HeaderOptions, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		c := &cspState{opts: o, nonce: base64.StdEncoding.EncodeToString(b)}
+
+		hdr := w.Header()
+		hdr.Set("X-Content-Type-Options", "nosniff")
+		if o.ReferrerPolicy != "" {
+			hdr.Set("Referrer-Policy", o.ReferrerPolicy)
+		}
+		c.setHeader(hdr)
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), cspKey{}, c)))
+	})
+}
@@ -0,0 +1,99 @@
+package nightrelcalc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- iCalendar (RFC 5545) export ---------------- */
+
+// icsEvent is one block of a plan on the calendar.
+type icsEvent struct {
+	Summary     string
+	Start, End  time.Time
+	Description string
+}
+
+// planEvents places a dated result on the calendar: the release, the work
+// day and rest of its first scenario, and the next day's work.
+func planEvents(r datedResult) ([]icsEvent, error) {
+	day, err := time.ParseInLocation(time.DateOnly, r.Date, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("a calendar needs a date (YYYY-MM-DD) for every release")
+	}
+	if len(r.Scenarios) == 0 {
+		return nil, nil
+	}
+	s := r.Scenarios[0]
+	at := calc.DayOf(day).Time
+	var desc strings.Builder
+	writeCLI(&desc, r.Result)
+	return []icsEvent{
+		{Summary: "Release", Start: at(s.Timeline.ReleaseStart), End: at(s.Timeline.ReleaseEnd), Description: desc.String()},
+		{Summary: "Work: " + s.Title, Start: at(s.Timeline.WorkStart), End: at(s.Timeline.WorkEnd)},
+		{Summary: "Mandatory rest", Start: at(s.Timeline.ReleaseEnd), End: at(s.Timeline.RestEnd)},
+		{Summary: "Next day work", Start: at(s.Timeline.NextStart), End: at(s.Timeline.NextEnd)},
+	}, nil
+}
+
+// writeICS writes the plans as one calendar. Times are floating (local to
+// whoever imports it), like the clock times of the calculation itself.
+func writeICS(w io.Writer, rs []datedResult, stamp time.Time) error {
+	var b strings.Builder
+	line := func(s string) {
+		// Fold at 75 octets; continuation lines start with a space.
+		for len(s) > 75 {
+			cut := 75
+			for cut > 0 && s[cut]&0xc0 == 0x80 { // do not split a UTF-8 sequence
+				cut--
+			}
+			b.WriteString(s[:cut] + "\r\n")
+			s = " " + s[cut:]
+		}
+		b.WriteString(s + "\r\n")
+	}
+	const layout = "20060102T150405"
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//nightrelcalc//nightrelcalc " + appVersion + "//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, r := range rs {
+		events, err := planEvents(r)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			line("BEGIN:VEVENT")
+			line("UID:" + eventUID(r, e) + "@nightrelcalc")
+			line("DTSTAMP:" + stamp.UTC().Format(layout) + "Z")
+			line("DTSTART:" + e.Start.Format(layout))
+			line("DTEND:" + e.End.Format(layout))
+			line("SUMMARY:" + icsText(e.Summary))
+			if e.Description != "" {
+				line("DESCRIPTION:" + icsText(e.Description))
+			}
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// eventUID identifies e of plan r, the same however often it is exported.
+func eventUID(r datedResult, e icsEvent) string {
+	uid := sha256.Sum256([]byte(r.Date + "|" + r.ReleaseStart + "|" + r.ReleaseLen + "|" + e.Summary))
+	return hex.EncodeToString(uid[:12])
+}
+
+// icsText escapes a TEXT value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,225 @@
+package nightrelcalc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------------- iCalendar import ---------------- */
+
+// importedEvent is a timed VEVENT of an imported calendar.
+type importedEvent struct {
+	Summary string
+	Start   time.Time // in the local time zone
+	Length  time.Duration
+}
+
+// icsProp is one content line: NAME;PARAM=..:VALUE.
+type icsProp struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsLines unfolds the content lines of r (continuations start with a space or tab).
+func icsLines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, sc.Err()
+}
+
+func parseICSProp(line string) icsProp {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	p := icsProp{Name: strings.ToUpper(parts[0]), Params: map[string]string{}, Value: value}
+	for _, kv := range parts[1:] {
+		k, v, _ := strings.Cut(kv, "=")
+		p.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p
+}
+
+// icsTime reads a DATE-TIME: UTC ("Z"), in a TZID, or floating (local). Time
+// zones Go does not know (Outlook's Windows names) are taken as local time.
+func icsTime(p icsProp) (time.Time, error) {
+	const layout = "20060102T150405"
+	if p.Params["VALUE"] == "DATE" || !strings.Contains(p.Value, "T") {
+		return time.Time{}, errAllDay
+	}
+	if v, ok := strings.CutSuffix(p.Value, "Z"); ok {
+		t, err := time.ParseInLocation(layout, v, time.UTC)
+		return t.Local(), err
+	}
+	loc := time.Local
+	if tz := p.Params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(layout, p.Value, loc)
+	return t.Local(), err
+}
+
+var errAllDay = fmt.Errorf("all-day event")
+
+var icsDurationRE = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// icsDuration parses a DURATION value such as PT3H or PT2H30M.
+func icsDuration(s string) (time.Duration, error) {
+	m := icsDurationRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[0] == "P" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseICSEvents returns the timed events of a calendar in file order.
+// All-day events are skipped; recurring events count once, at their first
+// occurrence.
+func parseICSEvents(r io.Reader) ([]importedEvent, error) {
+	lines, err := icsLines(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out     []importedEvent
+		inEvent bool
+		ev      map[string]icsProp
+	)
+	for _, l := range lines {
+		switch strings.ToUpper(l) {
+		case "BEGIN:VEVENT":
+			inEvent, ev = true, map[string]icsProp{}
+			continue
+		case "END:VEVENT":
+			inEvent = false
+			e, err := importEvent(ev)
+			if err == errAllDay {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, e)
+			continue
+		}
+		if inEvent {
+			p := parseICSProp(l)
+			if _, seen := ev[p.Name]; !seen {
+				ev[p.Name] = p
+			}
+		}
+	}
+	return out, nil
+}
+
+func importEvent(ev map[string]icsProp) (importedEvent, error) {
+	e := importedEvent{Summary: icsUnescape(ev["SUMMARY"].Value)}
+	name := e.Summary
+	if name == "" {
+		name = ev["UID"].Value
+	}
+	start, ok := ev["DTSTART"]
+	if !ok {
+		return e, fmt.Errorf("event %q: no DTSTART", name)
+	}
+	var err error
+	if e.Start, err = icsTime(start); err != nil {
+		if err == errAllDay {
+			return e, err
+		}
+		return e, fmt.Errorf("event %q: DTSTART: %w", name, err)
+	}
+	switch {
+	case ev["DTEND"].Value != "":
+		end, err := icsTime(ev["DTEND"])
+		if err != nil {
+			return e, fmt.Errorf("event %q: DTEND: %w", name, err)
+		}
+		e.Length = end.Sub(e.Start)
+	case ev["DURATION"].Value != "":
+		if e.Length, err = icsDuration(ev["DURATION"].Value); err != nil {
+			return e, fmt.Errorf("event %q: %w", name, err)
+		}
+	}
+	if e.Length <= 0 {
+		return e, fmt.Errorf("event %q: no duration (needs DTEND or DURATION)", name)
+	}
+	return e, nil
+}
+
+func icsUnescape(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// isICS reports whether data looks like an iCalendar file.
+func isICS(data []byte) bool {
+	return bytes.HasPrefix(bytes.ToUpper(bytes.TrimSpace(data)), []byte("BEGIN:VCALENDAR"))
+}
+
+// readBatchICS reads a plan per calendar event: its start is the release
+// start and its duration the release length.
+func readBatchICS(r io.Reader, defaults calcOptions, add func(batchRow) error) error {
+	events, err := parseICSEvents(r)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		row := defaults
+		row.Start = e.Start.Format("15:04")
+		row.Length = e.Length.Minutes() / 60
+		if err := add(batchRow{where: fmt.Sprintf("event %q", e.Summary), compute: func() (datedResult, error) {
+			res, err := row.compute()
+			return datedResult{Date: e.Start.Format(time.DateOnly), Event: e.Summary, Result: res, req: row.request()}, err
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchURL opens a batch input given as an http(s) URL, e.g. a published
+// calendar. The download stops when ctx is cancelled.
+func fetchURL(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,203 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push jira: comment on a change ticket ---------------- */
+
+// JiraConfig is the "jira" section of the config file. Jira Cloud takes the
+// account email with an API token; Server and Data Center a personal access
+// token alone. Fields names the custom fields to fill, if any:
+//
+//	"jira": {"url": "https://acme.atlassian.net", "email": "bot@acme.com", "token": "...",
+//	         "fields": {"planned_start": "customfield_10050", "planned_end": "customfield_10051", "overtime": "customfield_10052"}}
+type JiraConfig struct {
+	URL    string     `json:"url"`
+	Email  string     `json:"email,omitempty"`
+	Token  string     `json:"token"`
+	Fields jiraFields `json:"fields,omitzero"`
+}
+
+// jiraFields are custom field ids. The planned start and end are date-time
+// fields set to the release window; overtime is a number field set to the
+// most overtime, in hours, of any scenario.
+type jiraFields struct {
+	PlannedStart string `json:"planned_start,omitempty"`
+	PlannedEnd   string `json:"planned_end,omitempty"`
+	Overtime     string `json:"overtime,omitempty"`
+}
+
+func (c *JiraConfig) validate() error {
+	if c.URL == "" || c.Token == "" {
+		return errors.New("jira: url and token are required")
+	}
+	return nil
+}
+
+// jiraIssueKey is a ticket key such as CHG-123.
+var jiraIssueKey = regexp.MustCompile(`^[A-Z][A-Z0-9_]*-[0-9]+$`)
+
+// jiraComment is r in Jira wiki markup, which the v2 API takes on Cloud and
+// Server alike.
+func jiraComment(r datedResult) string {
+	cell := strings.NewReplacer("|", `\|`, "->", "→")
+	var b strings.Builder
+	fmt.Fprintf(&b, "h3. Night release %s → %s (%s)\n\n", r.ReleaseStart, r.ReleaseEnd, r.Date)
+	fmt.Fprintf(&b, "Length *%s* · normal day %s → %s · full day %s · min rest %s · max overtime %s\n\n",
+		r.ReleaseLen, r.NormalStart, r.NormalEnd, r.FullDay, r.MinRest, r.MaxOvertime)
+	b.WriteString("||Scenario||Work||Release||Total||Release included||Overtime||Next day||\n")
+	for _, s := range r.Scenarios {
+		cells := []string{s.Title, s.WorkHours, s.ReleaseWindow, s.TotalWork, s.ReleaseIncluded, s.Overtime, s.NextDayHours}
+		for i, c := range cells {
+			cells[i] = cell.Replace(c)
+		}
+		b.WriteString("|" + strings.Join(cells, "|") + "|\n")
+	}
+	fmt.Fprintf(&b, "\n_Staffing plan computed by nightrelcalc %s._\n", appVersion)
+	return b.String()
+}
+
+// jiraFieldValues are the configured custom fields set from r.
+func jiraFieldValues(f jiraFields, r datedResult) (map[string]any, error) {
+	events, err := planEvents(r)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	const layout = "2006-01-02T15:04:05.000-0700"
+	v := map[string]any{}
+	if f.PlannedStart != "" {
+		v[f.PlannedStart] = events[0].Start.Format(layout)
+	}
+	if f.PlannedEnd != "" {
+		v[f.PlannedEnd] = events[0].End.Format(layout)
+	}
+	if f.Overtime != "" {
+		var most time.Duration
+		for _, s := range r.Scenarios {
+			d, err := time.ParseDuration(s.Overtime)
+			if err != nil {
+				return nil, fmt.Errorf("overtime %q: %w", s.Overtime, err)
+			}
+			most = max(most, d)
+		}
+		v[f.Overtime] = most.Hours()
+	}
+	return v, nil
+}
+
+// atlassianSend sends v (none if nil) to the REST API path under base and
+// decodes the answer into out (unless nil). Cloud gets basic auth with the
+// account email; Server and Data Center (no email) the token as bearer.
+func atlassianSend(ctx context.Context, base, email, token, method, path string, v, out any) error {
+	var body io.Reader
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if email != "" {
+		req.SetBasicAuth(email, token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doJSON(req, out)
+}
+
+func jiraSend(ctx context.Context, c *JiraConfig, method, path string, v any) error {
+	return atlassianSend(ctx, c.URL, c.Email, c.Token, method, path, v, nil)
+}
+
+func newPushJiraCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts     pushOptions
+		issue    string
+		noFields bool
+	)
+	cmd := &cobra.Command{
+		Use:   "jira",
+		Short: "Comment the plan on a Jira ticket and fill its planned-window fields",
+		Long: "Post the plan as a comment on a change ticket and, when the config names\n" +
+			"them, set its custom fields to the planned start and end of the release and\n" +
+			"the most overtime of any scenario. Needs a \"jira\" section in the config file.\n\n" +
+			"  nightrelcalc push jira --issue CHG-123 --start 22:00 --length 3",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			if !jiraIssueKey.MatchString(issue) {
+				return fmt.Errorf("--issue must be a ticket key such as CHG-123, got %q", issue)
+			}
+			var jc JiraConfig
+			if cfg.Jira != nil {
+				jc = *cfg.Jira
+			}
+			if noFields {
+				jc.Fields = jiraFields{}
+			}
+			fields, err := jiraFieldValues(jc.Fields, r)
+			if err != nil {
+				return err
+			}
+			comment := jiraComment(r)
+			if opts.calc.DryRun {
+				fmt.Print(comment)
+				if len(fields) > 0 {
+					fmt.Println()
+					return writeIndentedJSON(os.Stdout, map[string]any{"fields": fields})
+				}
+				return nil
+			}
+			if cfg.Jira == nil {
+				return errors.New(`push jira needs a "jira" section with a url and token in the config file`)
+			}
+
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			path := "/rest/api/2/issue/" + url.PathEscape(issue)
+			if err := jiraSend(ctx, &jc, http.MethodPost, path+"/comment", map[string]string{"body": comment}); err != nil {
+				return fmt.Errorf("jira: comment: %w", err)
+			}
+			if len(fields) > 0 {
+				if err := jiraSend(ctx, &jc, http.MethodPut, path, map[string]any{"fields": fields}); err != nil {
+					return fmt.Errorf("jira: fields: %w", err)
+				}
+			}
+			fmt.Printf("%s/browse/%s\n", strings.TrimRight(jc.URL, "/"), issue)
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&issue, "issue", "", "Ticket key to comment on, e.g. CHG-123")
+	cmd.Flags().BoolVar(&noFields, "no-fields", false, "Only comment; leave the custom fields of the config alone")
+	return cmd
+}
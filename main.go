@@ -1,17 +1,21 @@
-package main
+package nightrelcalc
 
 import (
+	"context"
 	"fmt"
 	"html/template"
-	"math"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
+
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"nightrelcalc/calc"
 )
 
 const appVersion = "0.1.11"
@@ -23,39 +27,10 @@ const (
 	webDefaultNormalStart = "09:00"
 	webDefaultNormalEnd   = "17:30"
 	webDefaultMinRest     = "11"
+	webDefaultCommute     = "0"
 	webDefaultMaxOvertime = "4"
 )
 
-type Scenario struct {
-	Title string
-
-	WorkHours     string // Start -> End (regular)
-	ReleaseWindow string // Start -> End (release)
-	TotalWork     string // Start -> End (regular + overtime)
-
-	ReleaseIncluded string // e.g. 4h00m
-	Overtime        string // e.g. 0h00m
-
-	NextDayHours string // Start -> End (normal window length)
-}
-
-type CalcResult struct {
-	ReleaseStart string
-	ReleaseEnd   string
-	ReleaseLen   string
-
-	FullDay string
-
-	NormalStart string
-	NormalEnd   string
-	NormalLen   string
-
-	MinRest     string
-	MaxOvertime string
-
-	Scenarios []Scenario
-}
-
 type PageData struct {
 	Start   string
 	Length  string
@@ -64,32 +39,126 @@ type PageData struct {
 	NormalStart string
 	NormalEnd   string
 	MinRest     string
+	Commute     string
 	MaxOvertime string
+	PartTime    string
+	CoreHours   string
+	Commitment  string
+
+	// Scenarios are the picked scenario numbers ("" for all); ScenarioChoices
+	// the checkboxes for them, one per scenario of the full result.
+	Scenarios       string
+	ScenarioChoices []scenarioChoice
+
+	// Team is the team field, one person per line (see parseTeam);
+	// TeamResults are their results, shown instead of the form's own.
+	Team        string
+	TeamResults []teamResult
+
+	// Handoff splits the release between the team (follow the sun) in the
+	// time zone TZ ("" for the server's); Handoffs are the parts, in order.
+	Handoff  bool
+	TZ       string
+	Handoffs []handoffSegment
 
 	// Full is shown but derived unless explicitly overridden via CLI.
 	Full string
 
+	// MinuteStep is the --granularity in minutes, the time picker's step.
+	MinuteStep int
+
 	Version string
 
+	// BasePath is prepended to every link/form action ("" when served at the root).
+	BasePath string
+
+	// CSRFToken goes into a hidden field of every POST form.
+	CSRFToken string
+
+	// CSPNonce allows inline <style> and <script> under the Content-Security-Policy
+	// (the built-in page loads its CSS and JS from /assets/; operator templates may inline).
+	CSPNonce string
+
+	// CustomCSS links the operator stylesheet from --static-dir ("" if none).
+	CustomCSS string
+
 	Error  string
-	Result *CalcResult
+	Result *calc.Result
 
 	// Share text: meta description when Result is set (for link previews).
 	ShareDescription string
+
+	// OGImage is the absolute URL of the preview card when Result is set.
+	OGImage string
+
+	// PrintURL links the print-friendly view of the result; ExportURL the
+	// same page as a self-contained HTML download.
+	PrintURL  string
+	ExportURL string
+
+	// PageURL is the absolute result page URL (shown on printed/exported pages).
+	PageURL string
+
+	// CompareURL opens /compare with this result as side A.
+	CompareURL string
+
+	// ShortURL is the same result as a compact /?s=<token> link.
+	ShortURL string
+
+	// FieldErrors marks form fields that failed validation, by field name.
+	FieldErrors fieldErrors
+
+	// Partial is set when only the results partial is rendered (/results).
+	Partial bool
+
+	// ResultsHTML is the results partial already rendered for this
+	// calculation (see --result-cache), "" to render it in place.
+	ResultsHTML template.HTML
+
+	Brand Brand
+
+	// Picker is the server-side time picker shown after a clock button
+	// submitted the form (JavaScript off).
+	Picker *timePicker
+
+	// Profile is the selected config profile; Profiles lists all of them
+	// (the selector is only shown when there are some).
+	Profile  string
+	Profiles []string
+
+	// Availability links /availability, shown when rosters are configured.
+	Availability bool
+
+	// OEmbed is the oEmbed discovery URL when Result is set.
+	OEmbed string
+
+	cached *cachedPage // the calculation, shared with the other requests for it
+
+	// day is the day team and handoff times are computed for (time zones
+	// change across DST days), rules the config scenarios computed; both
+	// shape the results without being form values.
+	day   string
+	rules []calc.Rule
+}
+
+// invalid reports whether the page shows validation or calculation errors.
+func (d PageData) invalid() bool {
+	return d.Error != "" || len(d.FieldErrors) > 0
 }
 
-func main() {
+// Main runs the nightrelcalc command: a calculation, its subcommands, or the
+// web UI with --listen.
+func Main() {
 	var (
-		startStr string
-		lengthH  float64
-		combineH float64
-		fullH    float64
-		port     int
-
-		normalStartStr string
-		normalEndStr   string
-		minRestH       float64
-		maxOvertimeH   float64
+		opts   calcOptions
+		port   int
+		listen string
+
+		webOpts WebOptions
+
+		configPath  string
+		profileName string
+		nowFlag     string
 	)
 
 	cmd := &cobra.Command{
@@ -97,51 +166,69 @@ func main() {
 		Short: "Night release calculator (CLI or web)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if ok, _ := cmd.Flags().GetBool("version"); ok {
-				fmt.Printf("nightrelcalc v%s\n", appVersion)
+				fmt.Println(appBuild())
 				return nil
 			}
 
-			if port > 0 {
-				printListenAddrs(port)
-				return serveWeb(port, normalStartStr, normalEndStr, minRestH, maxOvertimeH)
+			cfg, err := loadConfigFlag(configPath)
+			if err != nil {
+				return err
 			}
 
-			if strings.TrimSpace(startStr) == "" {
-				return fmt.Errorf("--start is required (or use --port)")
-			}
-			if lengthH <= 0 {
-				return fmt.Errorf("--length must be > 0")
+			if listen == "" && port > 0 {
+				listen = fmt.Sprintf(":%d", port)
 			}
-			if minRestH <= 0 {
-				return fmt.Errorf("--min-rest must be > 0")
+			if listen != "" {
+				ln, err := listenOn(listen)
+				if err != nil {
+					return err
+				}
+				webOpts.BasePath = normalizeBasePath(webOpts.BasePath)
+				webOpts.Config = cfg
+				webOpts.Notify = opts.Notify
+				webOpts.Policy = InputPolicy{Granularity: opts.Granularity, Strict: opts.Strict}
+				webOpts.Notify.smtp = cfg.SMTP
+				printListenAddrs(ln.Addr(), webOpts.BasePath)
+				return serveWeb(cmd.Context(), ln, webOpts)
 			}
 
-			res, err := compute(startStr, lengthH, combineH, fullH, normalStartStr, normalEndStr, minRestH, maxOvertimeH)
-			if err != nil {
-				return err
+			if (strings.TrimSpace(opts.Start) == "" || opts.Length <= 0) && stdinIsTerminal() {
+				if err := promptRelease(newPrompter(os.Stdin, os.Stderr), &opts.Start, &opts.Length); err != nil {
+					return err
+				}
+			}
+			if strings.TrimSpace(opts.Start) == "" {
+				return fmt.Errorf("--start is required (or use --listen)")
 			}
-			printCLI(res)
-			return nil
+			return opts.run(cmd.Context(), cmd.Flags(), cfg, profileName)
 		},
 	}
 
-	cmd.Version = appVersion
-	cmd.SetVersionTemplate("nightrelcalc v{{.Version}}\n")
+	cmd.Version = appBuild().String()
+	cmd.SetVersionTemplate("{{.Version}}\n")
 	cmd.Flags().BoolP("version", "v", false, "Show version and exit")
 
-	cmd.Flags().StringVar(&startStr, "start", "", "Release start HH:MM")
-	cmd.Flags().Float64Var(&lengthH, "length", 0, "Release length in hours (e.g. 4, 3.5)")
-	cmd.Flags().Float64Var(&combineH, "combine", -1, "Hours of release included in full day (optional)")
+	cmd.Flags().StringVar(&opts.Start, "start", "", "Release start (e.g. 21:30, 2130 or 9:30pm)")
+	opts.addFlags(cmd.Flags())
+	opts.addSingleFlags(cmd.Flags())
 
-	// Full is optional: 0 means "derive from normal day".
-	cmd.Flags().Float64Var(&fullH, "full", 0, "Full workday hours (0 = derive from normal-start/normal-end)")
+	cmd.Flags().StringVar(&listen, "listen", "", "Run web UI on this address (e.g. :8484, 127.0.0.1:8484, unix:/run/nightrelcalc.sock)")
+	cmd.Flags().IntVar(&port, "port", 0, "Run web UI on this port on all interfaces (e.g. 8484)")
+	_ = cmd.Flags().MarkDeprecated("port", "use --listen :PORT instead")
+	webOpts.addFlags(cmd.Flags())
 
-	cmd.Flags().IntVar(&port, "port", 0, "Run web UI on this port (e.g. 8484)")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "JSON config file with named profiles (default: <user config dir>/nightrelcalc/config.json if present)")
+	cmd.PersistentFlags().StringVar(&profileName, "profile", "", "Use the work day and legal limits of this config profile (e.g. payments)")
+	cmd.PersistentFlags().StringVar(&nowFlag, "now", "", "Run as if it were this time (e.g. 2026-10-14T21:30), for reproducing a run")
+	_ = cmd.PersistentFlags().MarkHidden("now")
+	cmd.PersistentPreRunE = func(*cobra.Command, []string) error { return setNow(nowFlag) }
 
-	cmd.Flags().StringVar(&normalStartStr, "normal-start", "09:00", "Normal work start time (HH:MM)")
-	cmd.Flags().StringVar(&normalEndStr, "normal-end", "17:30", "Normal work end time (HH:MM)")
-	cmd.Flags().Float64Var(&minRestH, "min-rest", 11, "Minimum rest after release end in hours (default 11)")
-	cmd.Flags().Float64Var(&maxOvertimeH, "max-overtime", 4, "Maximum allowed overtime in hours (legal cap, default 4)")
+	cmd.AddCommand(newTUICommand(&configPath, &profileName))
+	cmd.AddCommand(newNowCommand(&configPath, &profileName))
+	cmd.AddCommand(newBatchCommand(&configPath, &profileName))
+	cmd.AddCommand(newPlanCommand(&configPath, &profileName))
+	cmd.AddCommand(newPushCommand(&configPath, &profileName))
+	registerCompletions(cmd, &configPath)
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -149,248 +236,370 @@ func main() {
 	}
 }
 
-/* ---------------- core logic (clock math only) ---------------- */
+/* ---------------- web ---------------- */
+
+func serveWeb(ctx context.Context, ln net.Listener, opts WebOptions) error {
+	h, err := newWebHandler(opts)
+	if err != nil {
+		return err
+	}
+	return runServer(ctx, ln, opts.Server, h)
+}
 
-func compute(startStr string, lengthH, combineH, fullH float64, normalStartStr, normalEndStr string, minRestH, maxOvertimeH float64) (*CalcResult, error) {
-	rsMin, err := parseHHMMToMin(startStr)
+// newWebHandler is the whole site: pages, API and assets, under
+// opts.BasePath.
+func newWebHandler(opts WebOptions) (http.Handler, error) {
+	tpl, err := loadTemplate(opts.TemplatesDir, pageTemplateFile, pageHTML)
 	if err != nil {
 		return nil, err
 	}
-	if lengthH <= 0 {
-		return nil, fmt.Errorf("length must be > 0")
+	errTpl, err := loadTemplate(opts.TemplatesDir, errorTemplateFile, errorHTML)
+	if err != nil {
+		return nil, err
 	}
-
-	nsMin, err := parseHHMMToMin(normalStartStr)
+	mux := http.NewServeMux()
+	brand, err := loadBranding(opts.Branding, opts.BasePath, mux)
 	if err != nil {
-		return nil, fmt.Errorf("invalid --normal-start: %w", err)
+		return nil, err
 	}
-	neMin, err := parseHHMMToMin(normalEndStr)
+	pages := pageRenderer{basePath: opts.BasePath, brand: brand, errorTpl: errTpl}
+	customCSS := customCSSURL(opts.StaticDir, opts.BasePath)
+	minuteStep, err := gridMinutes(opts.Policy.Granularity)
 	if err != nil {
-		return nil, fmt.Errorf("invalid --normal-end: %w", err)
+		return nil, fmt.Errorf("--granularity: %w", err)
 	}
-	normalLenMin := neMin - nsMin
-	if normalLenMin <= 0 {
-		return nil, fmt.Errorf("normal day must be within same day and end after start (e.g. 09:00 -> 17:30)")
+	if opts.StaticDir != "" {
+		mux.Handle("/static/", staticHandler(opts.StaticDir))
 	}
 
-	minRestMin := hoursToMin(minRestH)
-	if minRestMin <= 0 {
-		return nil, fmt.Errorf("min rest must be > 0")
+	signer, err := newCookieSigner(opts.CookieSecret, opts.BasePath+"/")
+	if err != nil {
+		return nil, fmt.Errorf("cookie secret: %w", err)
 	}
 
-	maxOvertimeMin := hoursToMin(maxOvertimeH)
-	if maxOvertimeMin < 0 {
-		return nil, fmt.Errorf("max overtime must be >= 0")
+	siteCreds, err := siteCredentials(opts)
+	if err != nil {
+		return nil, err
 	}
-
-	releaseLenMin := hoursToMin(lengthH)
-
-	// Full day: derive from normal day unless explicitly provided and >0
-	fullDayMin := normalLenMin
-	if fullH > 0 {
-		fullDayMin = hoursToMin(fullH)
+	if err := opts.Notify.check(); err != nil {
+		return nil, err
 	}
-
-	reEndAbs := rsMin + releaseLenMin
-	releaseWindow := fmtRange(rsMin, reEndAbs)
-
-	// Next-day: start = max(next day normal-start, releaseEnd+minRest)
-	// end = start + normal day length
-	nextStart := calcNextDayStartAbs(reEndAbs, nsMin, minRestMin)
-	nextEnd := nextStart + normalLenMin
-	nextDayHours := fmtRange(nextStart, nextEnd)
-
-	scenarios := make([]Scenario, 0, 3)
-
-	// 1) Full day (release included as much as possible)
-	// Legal cap: include at least (releaseLen - maxOvertime) so OT <= maxOvertime; pull work start later if needed
-	requiredIncluded := maxInt(0, releaseLenMin-maxOvertimeMin)
-	inc := minInt(fullDayMin, maxInt(requiredIncluded, minInt(releaseLenMin, fullDayMin)))
-	pre := fullDayMin - inc
-	workStart := rsMin - pre
-	workEnd := rsMin + inc
-	otMin := maxInt(releaseLenMin-inc, 0)
-
-	scenarios = append(scenarios, Scenario{
-		Title:           "Full day (release included) - No Overtime",
-		WorkHours:       fmtRange(workStart, workEnd),
-		ReleaseWindow:   releaseWindow,
-		TotalWork:       fmtRange(workStart, reEndAbs),
-		ReleaseIncluded: fmtHM(inc),
-		Overtime:        fmtHM(otMin),
-		NextDayHours:    nextDayHours,
-	})
-
-	// 2) Full day + release (all overtime) — cap OT at max by pulling work start later
-	ot2 := releaseLenMin
-	workStart2 := rsMin - fullDayMin
-	workEnd2 := rsMin
-	if ot2 > maxOvertimeMin {
-		// End work (releaseEnd - maxOvertime) so only maxOvertime is OT after work
-		workEnd2 = reEndAbs - maxOvertimeMin
-		workStart2 = workEnd2 - fullDayMin
-		ot2 = maxOvertimeMin
-	}
-	scenarios = append(scenarios, Scenario{
-		Title:           "Full day + release (Overtime)",
-		WorkHours:       fmtRange(workStart2, workEnd2),
-		ReleaseWindow:   releaseWindow,
-		TotalWork:       fmtRange(workStart2, reEndAbs),
-		ReleaseIncluded: fmtHM(0),
-		Overtime:        fmtHM(ot2),
-		NextDayHours:    nextDayHours,
-	})
-
-	// 3) Full day + combine + rest (only if combine set)
-	if combineH >= 0 {
-		x := hoursToMin(combineH)
-		x = minInt(x, releaseLenMin)
-		x = minInt(x, fullDayMin)
-
-		pre3 := fullDayMin - x
-		workStart3 := rsMin - pre3
-		workEnd3 := rsMin + x
-		ot3 := releaseLenMin - x
-		if ot3 > maxOvertimeMin {
-			// Pull work start later: include more of release so OT <= max
-			x = maxInt(releaseLenMin-maxOvertimeMin, 0)
-			x = minInt(x, fullDayMin)
-			pre3 = fullDayMin - x
-			workStart3 = rsMin - pre3
-			workEnd3 = rsMin + x
-			ot3 = releaseLenMin - x
-		}
-
-		scenarios = append(scenarios, Scenario{
-			Title:           fmt.Sprintf("Full day + %.2fh + %.2fh", combineH, lengthH-combineH),
-			WorkHours:       fmtRange(workStart3, workEnd3),
-			ReleaseWindow:   releaseWindow,
-			TotalWork:       fmtRange(workStart3, reEndAbs),
-			ReleaseIncluded: fmtHM(x),
-			Overtime:        fmtHM(ot3),
-			NextDayHours:    nextDayHours,
-		})
+	for name, p := range opts.Config.Profiles {
+		if err := p.Notify.orElse(opts.Notify).check(); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	for name, roster := range opts.Config.Rosters {
+		for _, m := range roster.Members {
+			to := m.Notify
+			to.smtp = opts.Notify.smtp
+			if err := to.check(); err != nil {
+				return nil, fmt.Errorf("roster %q member %q: %w", name, m.Name, err)
+			}
+		}
 	}
 
-	return &CalcResult{
-		ReleaseStart: fmtClock(rsMin),
-		ReleaseEnd:   fmtClock(reEndAbs),
-		ReleaseLen:   fmtHM(releaseLenMin),
-
-		FullDay: fmtHM(fullDayMin),
-
-		NormalStart: fmtClock(nsMin),
-		NormalEnd:   fmtClock(neMin),
-		NormalLen:   fmtHM(normalLenMin),
-
-		MinRest:     fmtHM(minRestMin),
-		MaxOvertime: fmtHM(maxOvertimeMin),
-
-		Scenarios: scenarios,
-	}, nil
-}
-
-func calcNextDayStartAbs(releaseEndAbs int, normalStartOfDayMin int, minRestMin int) int {
-	earliest := releaseEndAbs + minRestMin
-	reEndDay := floorDiv(releaseEndAbs, 1440)
-	nextDay := (reEndDay + 1) * 1440
-	baseline := nextDay + normalStartOfDayMin
-	return maxInt(baseline, earliest)
-}
-
-func printCLI(res *CalcResult) {
-	fmt.Printf("Release Window: %s -> %s (len %s)\n", res.ReleaseStart, res.ReleaseEnd, res.ReleaseLen)
-	fmt.Printf("Normal day: %s -> %s (len %s)\n", res.NormalStart, res.NormalEnd, res.NormalLen)
-	fmt.Printf("Full day used: %s, Min rest: %s, Max overtime (cap): %s\n\n", res.FullDay, res.MinRest, res.MaxOvertime)
+	stats := newWebStats()
+	cache := newResultCache(opts.ResultCache)
+	if opts.AdminAuth != "" {
+		adminCreds, err := parseCredential(opts.AdminAuth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --admin-auth: %w", err)
+		}
+		mux.Handle("/admin", requireBasicAuth(adminCreds, "nightrelcalc admin", adminHandler(stats, cache, opts.Config, pages)))
+	}
+	keys, err := loadAPIKeys(opts.APIKeys, opts.APIKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("api keys: %w", err)
+	}
+	hooks := opts.Notify.forMachines()
+	mux.Handle("/api/v1/calc", requireAPIKey(keys, apiCalcHandler(opts.Config, stats, opts.Policy, hooks)))
+	mux.Handle("/api/v1/calc/batch", requireAPIKey(keys, apiBatchHandler(opts.Config, stats, opts.APIBatchMax, opts.Policy, hooks)))
+	mux.Handle("/api/v1/validate", requireAPIKey(keys, apiValidateHandler(opts.Config)))
+	if len(opts.Config.Rosters) > 0 {
+		mux.Handle("/api/v1/eligible", requireAPIKey(keys, apiEligibleHandler(opts.Config)))
+	}
+	mux.Handle("/schema.json", schemaHandler())
+	mux.Handle("/version", versionHandler())
+	if opts.Config.hasFeeds() {
+		mux.Handle("/feed/{file}", feedHandler(opts.Config))
+	}
+	if opts.SlackSigningSecret != "" {
+		mux.Handle("/slack/command", slackCommandHandler(opts.Config, opts.SlackSigningSecret, opts.BasePath, opts.TrustForwarded, stats, hooks))
+	}
+	mux.Handle("/og.png", ogImageHandler(pages, opts.Config, siteCreds != nil))
+	registerPWA(mux)
+	registerAssets(mux, pages)
+	mux.Handle("/oembed", oEmbedHandler(opts.Config, opts.BasePath, opts.TrustForwarded))
+	printTpl, err := loadTemplate(opts.TemplatesDir, printTemplateFile, printHTML)
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("/print", printHandler(pages, printTpl, opts.Config, opts.TrustForwarded, false))
+	mux.Handle("/export.html", printHandler(pages, printTpl, opts.Config, opts.TrustForwarded, true))
+	compareTpl, err := loadTemplate(opts.TemplatesDir, compareTemplateFile, compareHTML)
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("/compare", compareHandler(pages, compareTpl, opts.Config))
+	bulkTpl, err := loadTemplate(opts.TemplatesDir, bulkTemplateFile, bulkHTML)
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("/bulk", bulkHandler(pages, bulkTpl, signer, opts.Config, opts.Server.MaxBodyBytes))
+	if len(opts.Config.Rosters) > 0 {
+		availabilityTpl, err := loadTemplate(opts.TemplatesDir, availabilityTemplateFile, availabilityHTML)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle("/availability", availabilityHandler(pages, availabilityTpl, opts.Config))
+	}
+	if opts.Config.hasReleases() {
+		nextTpl, err := loadTemplate(opts.TemplatesDir, nextTemplateFile, nextHTML)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle("/next", nextHandler(pages, nextTpl, opts.Config, false))
+		mux.Handle("/next.json", nextHandler(pages, nextTpl, opts.Config, true))
+		mux.Handle("/next/events", withoutDeadline(nextEventsHandler(opts.Config)))
+	}
 
-	for _, s := range res.Scenarios {
-		fmt.Println(s.Title)
-		fmt.Printf("  Work Hours:                    %s\n", s.WorkHours)
-		fmt.Printf("  Release Window:                %s\n", s.ReleaseWindow)
-		fmt.Printf("  Total Work:                    %s\n", s.TotalWork)
-		fmt.Printf("  Release Hours Included in Full %s\n", s.ReleaseIncluded)
-		fmt.Printf("  Overtime:                      %s\n", s.Overtime)
-		fmt.Printf("  Next Day Hours:                %s\n\n", s.NextDayHours)
+	metrics := newHTTPMetrics(stats, cache)
+	if opts.Metrics {
+		mux.Handle("/metrics", requireMetricsToken(opts.MetricsToken, metrics))
 	}
-}
 
-/* ---------------- web ---------------- */
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		pages.error(w, r, http.StatusNotFound, "There is no page at "+opts.BasePath+r.URL.Path+".")
+	})
 
-func serveWeb(port int, defaultNormalStart, defaultNormalEnd string, defaultMinRestH, defaultMaxOvertimeH float64) error {
-	tpl := template.Must(template.New("page").Parse(pageHTML))
-	mux := http.NewServeMux()
+	// pageData reads the form fields from the query and runs the calculation;
+	// record counts it in the admin stats (live recalcs are not counted).
+	profiles := opts.Config.profileNames()
+	pageData := func(r *http.Request, record bool) PageData {
+		q, tokenErr := expandStateToken(r.URL.Query())
+
+		// Remembered defaults only prefill a bare form; a shared calculation URL
+		// is authoritative, and its omitted params mean the global defaults, or
+		// those of the profile it names.
+		profileName := strings.TrimSpace(q.Get("profile"))
+		p, profileErr := opts.Config.lookupProfile(profileName)
+		defNormalStart, defNormalEnd, defMinRest, defCommute, defMaxOvertime := p.formDefaults()
+		defPartTime, defCoreHours := p.PartTime, p.CoreHours
+		if q.Get("start") == "" && profileName == "" {
+			c := signer.readDefaults(r)
+			defNormalStart = orDefault(c.Get("normal_start"), defNormalStart)
+			defNormalEnd = orDefault(c.Get("normal_end"), defNormalEnd)
+			defMinRest = orDefault(c.Get("min_rest"), defMinRest)
+			defCommute = orDefault(c.Get("commute"), defCommute)
+			defPartTime = orDefault(c.Get("part_time"), defPartTime)
+			defCoreHours = orDefault(c.Get("core_hours"), defCoreHours)
+			defMaxOvertime = orDefault(c.Get("max_overtime"), defMaxOvertime)
+			if _, ok := opts.Config.Profiles[c.Get("profile")]; ok {
+				profileName = c.Get("profile")
+				p, _ = opts.Config.lookupProfile(profileName)
+			}
+		}
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query()
 		data := PageData{
 			Start:       orDefault(q.Get("start"), webDefaultStart),
 			Length:      orDefault(q.Get("length"), webDefaultLength),
 			Combine:     strings.TrimSpace(q.Get("combine")),
-			NormalStart: orDefault(strings.TrimSpace(q.Get("normal_start")), webDefaultNormalStart),
-			NormalEnd:   orDefault(strings.TrimSpace(q.Get("normal_end")), webDefaultNormalEnd),
-			MinRest:     orDefault(strings.TrimSpace(q.Get("min_rest")), webDefaultMinRest),
-			MaxOvertime: orDefault(strings.TrimSpace(q.Get("max_overtime")), webDefaultMaxOvertime),
-
-			Full:    "(auto)",
-			Version: appVersion,
+			NormalStart: orDefault(strings.TrimSpace(q.Get("normal_start")), defNormalStart),
+			NormalEnd:   orDefault(strings.TrimSpace(q.Get("normal_end")), defNormalEnd),
+			MinRest:     orDefault(strings.TrimSpace(q.Get("min_rest")), defMinRest),
+			Commute:     orDefault(strings.TrimSpace(q.Get("commute")), defCommute),
+			PartTime:    orDefault(strings.TrimSpace(q.Get("part_time")), defPartTime),
+			CoreHours:   orDefault(strings.TrimSpace(q.Get("core_hours")), defCoreHours),
+			Commitment:  strings.TrimSpace(q.Get("commitment")),
+			MaxOvertime: orDefault(strings.TrimSpace(q.Get("max_overtime")), defMaxOvertime),
+			Scenarios:   strings.Join(q["scenarios"], ","),
+			Team:        strings.TrimSpace(q.Get("team")),
+			Handoff:     q.Get("handoff") == "1",
+			TZ:          strings.TrimSpace(q.Get("tz")),
+
+			Full:       "(auto)",
+			Version:    appVersion,
+			BasePath:   opts.BasePath,
+			CSPNonce:   cspNonce(r),
+			CustomCSS:  customCSS,
+			Brand:      brand,
+			Profile:    profileName,
+			Profiles:   profiles,
+			MinuteStep: minuteStep,
+
+			Availability: len(opts.Config.Rosters) > 0,
 		}
 		if data.NormalEnd == "" {
 			data.NormalEnd = webDefaultNormalEnd
 		}
+		if tokenErr != nil {
+			data.Error = tokenErr.Error()
+			if record {
+				stats.record("", true)
+			}
+			return data
+		}
+		if profileErr != nil {
+			data.FieldErrors = fieldErrors{"profile": profileErr.Error()}
+			if record {
+				stats.record("", true)
+			}
+			return data
+		}
 
-		// If we have start and valid length, run calculation (so URL with params shows results).
+		// If we have start and length, run calculation (so URL with params shows results).
 		if data.Start != "" && data.Length != "" {
-			lengthH, err := parseFloat(data.Length)
-			if err == nil && lengthH > 0 {
-				normalStart := data.NormalStart
-				normalEnd := data.NormalEnd
-				minRestStr := data.MinRest
-				maxOvertimeStr := data.MaxOvertime
-				if normalStart == "" {
-					normalStart = webDefaultNormalStart
-				}
-				if normalEnd == "" {
-					normalEnd = webDefaultNormalEnd
-				}
-				if minRestStr == "" {
-					minRestStr = webDefaultMinRest
+			in, ferr := validateForm(data.Start, data.Length, data.Combine, data.NormalStart, data.NormalEnd, data.MinRest, data.Commute, data.MaxOvertime, data.PartTime, data.CoreHours, data.Commitment, data.Scenarios)
+			if ferr == nil {
+				ferr = in.checkGrid(opts.Policy.Granularity)
+			}
+			if ferr != nil {
+				data.FieldErrors = ferr
+				if record {
+					stats.record("", true)
 				}
-				if maxOvertimeStr == "" {
-					maxOvertimeStr = webDefaultMaxOvertime
+				return data
+			}
+			canonical := strings.TrimPrefix(buildCalcURL(in.Start, in.Length, in.Combine, in.NormalStart, in.NormalEnd, in.MinRest, in.Commute, in.MaxOvertime, in.PartTime, in.CoreHours, in.Commitment, in.Scenarios, data.Team, data.TZ, data.Handoff), "/?")
+			canonical = p.pinBuiltins(canonical, in)
+			// The other views compute it under the same profile.
+			link := withProfile(canonical, profileName)
+
+			// The same canonical query under the same profile gives the same
+			// page; a team's results also depend on the day (time zones).
+			key := profileName + "?" + canonical
+			if data.Team != "" || data.Handoff {
+				data.day = appClock.Now().Format(time.DateOnly)
+				key += "#" + data.day
+			}
+			data.rules = opts.Config.rules(p)
+			page, ok := cache.get(key)
+			if !ok {
+				var (
+					team     []teamResult
+					handoffs []handoffSegment
+				)
+				if data.Team != "" || data.Handoff {
+					team, handoffs, ferr = teamResults(in, data.Team, data.Handoff, data.TZ, appClock.Now(), opts.Config.personRules(), data.rules, opts.Policy)
 				}
-				minRestH, _ := parseFloat(minRestStr)
-				maxOvertimeH, _ := parseFloat(maxOvertimeStr)
-				if minRestH <= 0 {
-					minRestH = defaultMinRestH
+				if ferr != nil {
+					data.FieldErrors = ferr
+					if record {
+						stats.record("", true)
+					}
+					return data
 				}
-				if maxOvertimeH < 0 {
-					maxOvertimeH = defaultMaxOvertimeH
+				page = &cachedPage{}
+				res, err := in.compute(data.rules)
+				if err == nil && opts.Policy.Strict {
+					err = strictAdjustments(res)
 				}
-				combineH := -1.0
-				if data.Combine != "" {
-					if v, err := parseFloat(data.Combine); err == nil && v >= 0 {
-						combineH = v
-					}
+				if err == nil {
+					page.ScenarioChoices = scenarioChoices(res, in.Picks)
+					err = in.pick(res)
 				}
-				res, err := compute(data.Start, lengthH, combineH, 0, normalStart, normalEnd, minRestH, maxOvertimeH)
+				page.Scenarios = in.Scenarios
 				if err != nil {
-					data.Error = err.Error()
+					page.Error = err.Error()
 				} else {
-					data.Result = res
-					data.Full = res.FullDay
-					data.ShareDescription = buildShareDescription(res)
+					page.Result, page.TeamResults, page.Handoffs = res, team, handoffs
+				}
+				cache.add(key, page)
+			}
+			data.cached = page
+			data.ScenarioChoices = page.ScenarioChoices
+			data.Scenarios = page.Scenarios
+			data.Error = page.Error
+			res, team := page.Result, page.TeamResults
+			if res != nil {
+				data.Result = res
+				data.TeamResults = team
+				data.Handoffs = page.Handoffs
+				data.Full = res.FullDay
+				data.ShareDescription = buildShareDescription(res)
+			}
+			if res != nil && team != nil {
+				// The print, export and compare pages and share tokens
+				// have one result only; the page link is the team's.
+				data.OGImage = requestOrigin(r, opts.TrustForwarded) + opts.BasePath + "/og.png?" + link
+			} else if res != nil {
+				origin := requestOrigin(r, opts.TrustForwarded)
+				data.OGImage = origin + opts.BasePath + "/og.png?" + link
+				data.PrintURL = opts.BasePath + "/print?" + link
+				data.ExportURL = opts.BasePath + "/export.html?" + link
+				data.CompareURL = opts.BasePath + "/compare?a=" + url.QueryEscape(link)
+				data.OEmbed = oEmbedURL(origin, opts.BasePath, origin+opts.BasePath+"/?"+link)
+				if cq, err := url.ParseQuery(canonical); err == nil {
+					if token, err := encodeStateToken(cq); err == nil {
+						data.ShortURL = withProfile(opts.BasePath+"/?s="+token, profileName)
+					}
 				}
 			}
+			if record && q.Get("start") != "" {
+				stats.record(canonical, page.Error != "")
+			}
+		}
+		return data
+	}
+
+	resultsTpl := tpl.Lookup(resultsTemplateName)
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			pages.methodNotAllowed(w, r, "GET")
+			return
+		}
+		data := pageData(r, true)
+		data.CSRFToken = signer.ensureCSRF(w, r)
+		if data.invalid() {
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
+		}
+		etag := pageETag(data)
+		data.CSPNonce = pinCSPNonce(w, r, signer.pageNonce(etag))
+		if checkNotModified(w, r, etag) {
+			return
 		}
+		data.ResultsHTML = cachedResultsHTML(r, resultsTpl, data)
+		pages.render(w, r, tpl, http.StatusOK, data)
+	})
 
-		_ = tpl.Execute(w, data)
+	// /results is the results partial of the page for live recalculation from the form.
+	mux.HandleFunc("/results", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			pages.methodNotAllowed(w, r, "GET")
+			return
+		}
+		if resultsTpl == nil {
+			pages.error(w, r, http.StatusNotFound, "This page template has no "+resultsTemplateName+" partial.")
+			return
+		}
+		data := pageData(r, false)
+		data.Partial = true
+		w.Header().Set("Cache-Control", "no-store")
+		status := http.StatusOK
+		if data.invalid() {
+			status = http.StatusBadRequest
+		}
+		if h := cachedResultsHTML(r, resultsTpl, data); h != "" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			_, _ = io.WriteString(w, string(h))
+			return
+		}
+		pages.render(w, r, resultsTpl, status, data)
 	})
 
 	mux.HandleFunc("/calc", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			pages.methodNotAllowed(w, r, "POST")
+			return
+		}
 		if err := r.ParseForm(); err != nil {
-			http.Error(w, "bad form", http.StatusBadRequest)
+			pages.error(w, r, http.StatusBadRequest, "The form could not be read: "+err.Error())
 			return
 		}
+		pickedOK := applyPickedTime(r.Form)
 
 		start := strings.TrimSpace(r.FormValue("start"))
 		lengthStr := strings.TrimSpace(r.FormValue("length"))
@@ -398,7 +607,16 @@ func serveWeb(port int, defaultNormalStart, defaultNormalEnd string, defaultMinR
 		normalStart := strings.TrimSpace(r.FormValue("normal_start"))
 		normalEnd := strings.TrimSpace(r.FormValue("normal_end"))
 		minRestStr := strings.TrimSpace(r.FormValue("min_rest"))
+		commuteStr := strings.TrimSpace(r.FormValue("commute"))
+		partTimeStr := strings.TrimSpace(r.FormValue("part_time"))
+		coreHoursStr := strings.TrimSpace(r.FormValue("core_hours"))
+		commitmentStr := strings.TrimSpace(r.FormValue("commitment"))
 		maxOvertimeStr := strings.TrimSpace(r.FormValue("max_overtime"))
+		scenariosStr := strings.Join(r.Form["scenarios"], ",")
+		teamStr := strings.TrimSpace(r.FormValue("team"))
+		tzStr := strings.TrimSpace(r.FormValue("tz"))
+		handoff := r.FormValue("handoff") == "1"
+		profileName := strings.TrimSpace(r.FormValue("profile"))
 
 		if normalEnd == "" {
 			normalEnd = "17:30"
@@ -411,81 +629,143 @@ func serveWeb(port int, defaultNormalStart, defaultNormalEnd string, defaultMinR
 			NormalStart: normalStart,
 			NormalEnd:   normalEnd,
 			MinRest:     minRestStr,
+			Commute:     commuteStr,
+			PartTime:    partTimeStr,
+			CoreHours:   coreHoursStr,
+			Commitment:  commitmentStr,
 			MaxOvertime: maxOvertimeStr,
+			Scenarios:   scenariosStr,
+			Team:        teamStr,
+			Handoff:     handoff,
+			TZ:          tzStr,
 			Version:     appVersion,
+			BasePath:    opts.BasePath,
+			CSPNonce:    cspNonce(r),
+			CustomCSS:   customCSS,
+			Brand:       brand,
+			Profile:     profileName,
+			Profiles:    profiles,
+			MinuteStep:  minuteStep,
+
+			Availability: len(opts.Config.Rosters) > 0,
 		}
-
-		if start == "" {
-			data.Error = "release start is required (HH:MM)"
-			_ = tpl.Execute(w, data)
-			return
+		renderError := func(msg string) {
+			data.Error = msg
+			stats.record("", true)
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
 		}
 
-		lengthH, err := parseFloat(lengthStr)
-		if err != nil || lengthH <= 0 {
-			data.Error = "release length must be > 0 (hours, e.g. 4)"
-			_ = tpl.Execute(w, data)
+		data.CSRFToken = signer.ensureCSRF(w, r)
+		if !signer.checkCSRF(r) {
+			data.Error = "the form expired or was submitted from another site, please submit it again"
+			pages.render(w, r, tpl, http.StatusForbidden, data)
 			return
 		}
 
-		if normalStart == "" {
-			normalStart = "09:00"
+		if r.Form.Has("pick") {
+			data.Picker = newTimePicker(r.FormValue("pick"), r.FormValue(r.FormValue("pick")), opts.Policy.Granularity)
+			pages.render(w, r, tpl, http.StatusOK, data)
+			return
 		}
-		if minRestStr == "" {
-			minRestStr = "11" // prefill behavior even after empty submit
+		if !pickedOK {
+			renderError("the picked time is not valid, pick an hour and minute")
+			return
 		}
-		if maxOvertimeStr == "" {
-			maxOvertimeStr = "4"
+		p, err := opts.Config.lookupProfile(profileName)
+		if err != nil {
+			data.FieldErrors = fieldErrors{"profile": err.Error()}
+			stats.record("", true)
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
+			return
 		}
-
-		minRestH, err := parseFloat(minRestStr)
-		if err != nil || minRestH <= 0 {
-			data.Error = "min rest must be > 0 (hours, default 11)"
-			_ = tpl.Execute(w, data)
+		if r.Form.Has("use_profile") {
+			// Switch profile: keep the release, take the work day and limits from the profile.
+			v := url.Values{}
+			for k, val := range map[string]string{"profile": profileName, "start": start, "length": lengthStr, "combine": combineStr, "scenarios": scenariosStr, "team": teamStr, "tz": tzStr, "handoff": r.FormValue("handoff")} {
+				if val != "" {
+					v.Set(k, val)
+				}
+			}
+			http.Redirect(w, r, opts.BasePath+"/?"+v.Encode(), http.StatusFound)
 			return
 		}
 
-		maxOvertimeH, err := parseFloat(maxOvertimeStr)
-		if err != nil || maxOvertimeH < 0 {
-			data.Error = "max overtime must be >= 0 (hours, default 4)"
-			_ = tpl.Execute(w, data)
+		in, ferr := validateForm(start, lengthStr, combineStr, normalStart, normalEnd, minRestStr, commuteStr, maxOvertimeStr, partTimeStr, coreHoursStr, commitmentStr, scenariosStr)
+		if ferr == nil {
+			ferr = in.checkGrid(opts.Policy.Granularity)
+		}
+		var team []teamResult
+		if ferr == nil && (teamStr != "" || handoff) {
+			team, _, ferr = teamResults(in, teamStr, handoff, tzStr, appClock.Now(), opts.Config.personRules(), opts.Config.rules(p), opts.Policy)
+		}
+		if ferr != nil {
+			data.FieldErrors = ferr
+			stats.record("", true)
+			pages.render(w, r, tpl, http.StatusBadRequest, data)
 			return
 		}
-
-		combineH := -1.0
-		if combineStr != "" {
-			v, err := parseFloat(combineStr)
-			if err != nil || v < 0 {
-				data.Error = "combine must be >= 0 (hours) or empty"
-				_ = tpl.Execute(w, data)
-				return
-			}
-			combineH = v
+		res, err := in.compute(opts.Config.rules(p))
+		if err == nil && opts.Policy.Strict {
+			err = strictAdjustments(res)
+		}
+		if err == nil {
+			err = in.pick(res)
 		}
-
-		// Web: full day is derived from normal day.
-		_, err = compute(start, lengthH, combineH, 0, normalStart, normalEnd, minRestH, maxOvertimeH)
 		if err != nil {
-			data.Error = err.Error()
-			_ = tpl.Execute(w, data)
+			renderError(err.Error())
 			return
 		}
+		signer.writeDefaults(w, url.Values{
+			"normal_start": {in.NormalStart},
+			"normal_end":   {in.NormalEnd},
+			"min_rest":     {in.MinRest},
+			"commute":      {in.Commute},
+			"part_time":    {in.PartTime},
+			"core_hours":   {in.CoreHours},
+			"max_overtime": {in.MaxOvertime},
+			"profile":      {profileName},
+		})
+
 		// Redirect to GET with query params (only non-defaults) so the URL reflects the calculation.
-		redir := buildCalcURL(start, lengthStr, combineStr, normalStart, normalEnd, minRestStr, maxOvertimeStr)
+		// Without a profile p is the config's defaults.
+		redir := opts.BasePath + profileCalcURL(profileName, p, in, teamStr, tzStr, handoff)
+		p.Notify.orElse(opts.Notify).postAsync(r.Context(), notice{Result: res, Link: requestOrigin(r, opts.TrustForwarded) + redir, Source: "web"})
+		for _, t := range team {
+			// Everyone with notify targets in a roster hears only about their own part.
+			to, ok := opts.Config.contact(t.Name)
+			if !ok || t.Result == nil {
+				continue
+			}
+			to.smtp = opts.Notify.smtp
+			mi := t.in
+			link := strings.TrimPrefix(buildCalcURL(mi.Start, mi.Length, mi.Combine, mi.NormalStart, mi.NormalEnd, mi.MinRest, mi.Commute, mi.MaxOvertime, mi.PartTime, mi.CoreHours, mi.Commitment, mi.Scenarios, "", "", false), "/?")
+			link = requestOrigin(r, opts.TrustForwarded) + opts.BasePath + "/?" + withProfile(p.pinBuiltins(link, mi), profileName)
+			to.postAsync(r.Context(), notice{Result: t.Result, Link: link, Source: "web", Person: t.Name})
+		}
 		http.Redirect(w, r, redir, http.StatusFound)
 	})
 
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+	var h http.Handler = mux
+	if siteCreds != nil {
+		h = protectSite(siteCreds, len(keys) > 0, opts.MetricsToken != "", h)
+	}
+	if opts.RateLimit > 0 {
+		h = newRateLimiter(opts.RateLimit, opts.RateBurst, opts.TrustForwarded).limit(h)
+	}
+	return withBasePath(opts.BasePath, securityHeaders(opts.Headers, metrics.instrument(h))), nil
 }
 
 // buildCalcURL returns "/?start=...&length=..." and only adds other params when not default.
-func buildCalcURL(start, length, combine, normalStart, normalEnd, minRest, maxOvertime string) string {
+func buildCalcURL(start, length, combine, normalStart, normalEnd, minRest, commute, maxOvertime, partTime, coreHours, commitment, scenarios, team, tz string, handoff bool) string {
 	v := url.Values{}
 	v.Set("start", start)
 	v.Set("length", length)
 	if combine != "" {
 		v.Set("combine", combine)
 	}
+	if scenarios != "" {
+		v.Set("scenarios", scenarios)
+	}
 	if normalStart != "" && normalStart != webDefaultNormalStart {
 		v.Set("normal_start", normalStart)
 	}
@@ -495,9 +775,30 @@ func buildCalcURL(start, length, combine, normalStart, normalEnd, minRest, maxOv
 	if minRest != "" && minRest != webDefaultMinRest {
 		v.Set("min_rest", minRest)
 	}
+	if commute != "" && commute != webDefaultCommute {
+		v.Set("commute", commute)
+	}
 	if maxOvertime != "" && maxOvertime != webDefaultMaxOvertime {
 		v.Set("max_overtime", maxOvertime)
 	}
+	if partTime != "" {
+		v.Set("part_time", partTime)
+	}
+	if coreHours != "" {
+		v.Set("core_hours", coreHours)
+	}
+	if commitment != "" {
+		v.Set("commitment", commitment)
+	}
+	if team != "" {
+		v.Set("team", team)
+	}
+	if tz != "" {
+		v.Set("tz", tz)
+	}
+	if handoff {
+		v.Set("handoff", "1")
+	}
 	return "/?" + v.Encode()
 }
 
@@ -509,7 +810,7 @@ func orDefault(val, def string) string {
 }
 
 // buildShareDescription returns the meta description for link previews when Result is set.
-func buildShareDescription(res *CalcResult) string {
+func buildShareDescription(res *calc.Result) string {
 	if len(res.Scenarios) == 0 {
 		return fmt.Sprintf("Release %s → %s (len %s). Full day %s, min rest %s, max OT %s.",
 			res.ReleaseStart, res.ReleaseEnd, res.ReleaseLen, res.FullDay, res.MinRest, res.MaxOvertime)
@@ -519,241 +820,242 @@ func buildShareDescription(res *CalcResult) string {
 		res.ReleaseStart, res.ReleaseEnd, res.ReleaseLen, s.WorkHours, s.ReleaseIncluded, s.Overtime, s.NextDayHours)
 }
 
-/* ---------------- helpers ---------------- */
-
-func fmtRange(aMin, bMin int) string {
-	return fmtClock(aMin) + " -> " + fmtClock(bMin)
-}
-
-func parseHHMMToMin(s string) (int, error) {
-	t := strings.TrimSpace(s)
-	parts := strings.Split(t, ":")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
-	}
-	h, err := strconv.Atoi(parts[0])
-	if err != nil || h < 0 || h > 23 {
-		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
-	}
-	m, err := strconv.Atoi(parts[1])
-	if err != nil || m < 0 || m > 59 {
-		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
-	}
-	return h*60 + m, nil
-}
-
-func hoursToMin(h float64) int {
-	return int(math.Round(h * 60.0))
-}
-
-func fmtClock(min int) string {
-	days := floorDiv(min, 1440)
-	min = mod(min, 1440)
-	h := min / 60
-	m := min % 60
-	if days == 0 {
-		return fmt.Sprintf("%02d:%02d", h, m)
-	}
-	return fmt.Sprintf("%02d:%02d (+%dd)", h, m, days)
-}
-
-func fmtHM(min int) string {
-	if min < 0 {
-		min = -min
-	}
-	h := min / 60
-	m := min % 60
-	return fmt.Sprintf("%dh%02dm", h, m)
-}
-
-func parseFloat(s string) (float64, error) {
-	s = strings.TrimSpace(s)
-	s = strings.ReplaceAll(s, ",", ".")
-	return strconv.ParseFloat(s, 64)
-}
-
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-func maxInt(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
-
-func floorDiv(a, b int) int {
-	if b == 0 {
-		return 0
-	}
-	q := a / b
-	r := a % b
-	if (r != 0) && ((r > 0) != (b > 0)) {
-		q--
-	}
-	return q
-}
-
-func mod(a, b int) int {
-	m := a % b
-	if m < 0 {
-		m += b
-	}
-	return m
-}
-
-func printListenAddrs(port int) {
-	fmt.Println("Listening on:")
-	fmt.Printf("  http://127.0.0.1:%d/\n", port)
-
-	ifaces, _ := net.Interfaces()
-	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 {
-			continue
-		}
-		addrs, _ := iface.Addrs()
-		for _, a := range addrs {
-			ip, _, err := net.ParseCIDR(a.String())
-			if err != nil || ip == nil || ip.IsLoopback() || ip.To4() == nil {
-				continue
-			}
-			fmt.Printf("  http://%s:%d/\n", ip.String(), port)
-		}
-	}
-	fmt.Println()
-}
-
 /* ---------------- HTML ---------------- */
 
 const pageHTML = `<!doctype html>
 <html>
 <head>
   <meta charset="utf-8">
-  <title>nightrelcalc</title>
+  <title>{{.Brand.Name}}</title>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="theme-color" content="#1976d2">
+  <link rel="manifest" href="{{.BasePath}}/manifest.webmanifest">
+  <link rel="icon" type="image/png" href="{{.BasePath}}/icons/icon-192.png">
+  <link rel="apple-touch-icon" href="{{.BasePath}}/icons/icon-192.png">
   {{if .ShareDescription}}
   <meta name="description" content="{{.ShareDescription}}">
   <meta property="og:description" content="{{.ShareDescription}}">
   {{end}}
-  <style>
-    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 960px; box-sizing: border-box; }
-    * { box-sizing: border-box; }
-    h2 { margin-top: 0; font-weight: 600; }
-    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
-    .card { border: 1px solid #e0e0e0; border-radius: 10px; padding: 16px; margin: 16px 0; background: #fafafa; }
-    .card:first-of-type { background: #fff; }
-    .mono { font-family: ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, "Liberation Mono", "Courier New", monospace; }
-    table { border-collapse: collapse; width: 100%; margin-top: 10px; }
-    td { padding: 8px 10px; border-top: 1px solid #eee; vertical-align: top; }
-    .k { width: 320px; color: #444; }
-    .hint { color: #666; font-size: 0.9em; margin-top: 4px; }
-    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
-
-    .form-grid { display: grid; grid-template-columns: 1fr 1fr; gap: 0 32px; }
-    @media (max-width: 640px) { .form-grid { grid-template-columns: 1fr; } }
-    .form-section { margin-bottom: 4px; }
-    .form-section-title { font-size: 0.85em; font-weight: 600; text-transform: uppercase; letter-spacing: 0.04em; color: #555; margin-bottom: 12px; padding-bottom: 6px; border-bottom: 1px solid #e0e0e0; }
-    .field { margin-bottom: 14px; }
-    .field label { display: block; font-weight: 500; color: #333; margin-bottom: 4px; font-size: 0.95em; }
-    .field input[type="number"], .field input[type="text"] { padding: 8px 10px; font-size: 1em; border: 1px solid #ccc; border-radius: 6px; width: 100%; max-width: 140px; }
-    .time-row { display: flex; align-items: center; gap: 8px; flex-wrap: wrap; }
-    .time-row input.time-value { max-width: 80px; }
-    .time-picker-btn { padding: 6px 12px; font-size: 0.9em; background: #f5f5f5; border: 1px solid #ccc; border-radius: 6px; cursor: pointer; }
-    .time-picker-btn:hover { background: #e8e8e8; }
-    .time-picker-overlay { position: fixed; inset: 0; background: rgba(0,0,0,0.4); display: none; align-items: center; justify-content: center; z-index: 1000; }
-    .time-picker-overlay.open { display: flex; }
-    .time-picker-modal { background: #fff; border-radius: 10px; padding: 20px; box-shadow: 0 4px 20px rgba(0,0,0,0.2); min-width: 200px; }
-    .time-picker-modal h3 { margin: 0 0 14px 0; font-size: 1em; font-weight: 600; }
-    .time-picker-row { display: flex; gap: 12px; align-items: center; margin-bottom: 16px; }
-    .time-picker-row select { padding: 8px 10px; font-size: 1em; border: 1px solid #ccc; border-radius: 6px; }
-    .time-picker-actions { display: flex; gap: 8px; justify-content: flex-end; }
-    .time-picker-actions button { padding: 8px 16px; border-radius: 6px; border: 1px solid #ccc; background: #f5f5f5; cursor: pointer; font-size: 0.95em; }
-    .time-picker-actions button.primary { background: #1976d2; color: #fff; border-color: #1976d2; }
-    .time-picker-actions button.primary:hover { background: #1565c0; }
-    .field input:focus { outline: none; border-color: #1976d2; box-shadow: 0 0 0 2px rgba(25,118,210,0.2); }
-    .fields-row { display: flex; gap: 20px; flex-wrap: wrap; }
-    .fields-row .field { flex: 1; min-width: 120px; }
-    .form-actions { margin-top: 0px; padding-top: 16px; border-top: 1px solid #e0e0e0; }
-    button[type="submit"] { padding: 10px 20px; font-size: 1em; font-weight: 500; background: #1976d2; color: #fff; border: none; border-radius: 6px; cursor: pointer; }
-    button[type="submit"]:hover { background: #1565c0; }
-  </style>
+  {{if .OGImage}}
+  <meta property="og:title" content="{{.Brand.Name}}">
+  <meta property="og:image" content="{{.OGImage}}">
+  <meta property="og:image:width" content="1200">
+  <meta property="og:image:height" content="630">
+  <meta name="twitter:card" content="summary_large_image">
+  {{end}}
+  {{if .OEmbed}}
+  <link rel="alternate" type="application/json+oembed" href="{{.OEmbed}}" title="{{.Brand.Name}}">
+  {{end}}
+  <link rel="stylesheet" href="{{.BasePath}}/assets/app.css?v={{assetHash "app.css"}}">
+  {{with .Brand.Accent}}<style nonce="{{$.CSPNonce}}">:root { --accent: {{.}}; }</style>{{end}}
+  {{if .CustomCSS}}<link rel="stylesheet" href="{{.CustomCSS}}">{{end}}
 </head>
-<body>
-    <form method="POST" action="/calc">
+<body data-base-path="{{.BasePath}}">
+  {{if or .Brand.Title .Brand.Logo}}
+  <header class="brand">{{with .Brand.Logo}}<img src="{{.}}" alt="" height="32">{{end}}{{with .Brand.Title}}<span>{{.}}</span>{{end}}</header>
+  {{end}}
+    <form method="POST" action="{{.BasePath}}/calc">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <!-- Enter submits with the first submit button; keep that Calculate, not a clock button. -->
+    <button type="submit" class="default-submit" tabindex="-1" aria-hidden="true">Calculate</button>
     <div class="form-grid">
       <div class="form-section">
         <div class="form-section-title">Release</div>
-        <div class="field">
+        <div class="field{{if index .FieldErrors "start"}} invalid{{end}}">
           <label for="start">Release start</label>
           <div class="time-row">
-            <input id="start" name="start" type="text" class="time-value" value="{{.Start}}" placeholder="18:30" pattern="[0-9]{1,2}:[0-9]{2}" required autocomplete="off">
-            <button type="button" class="time-picker-btn" data-for="start" aria-label="Pick time">🕐</button>
+            <input id="start" name="start" type="text" class="time-value" value="{{.Start}}" placeholder="18:30" required autocomplete="off" aria-invalid="{{if index .FieldErrors "start"}}true{{else}}false{{end}}" aria-describedby="start-error">
+            <button type="submit" name="pick" value="start" formaction="{{$.BasePath}}/calc#picker" formnovalidate class="time-picker-btn" data-for="start" aria-label="Pick time">🕐</button>
           </div>
+          <div class="field-error" id="start-error">{{index .FieldErrors "start"}}</div>
         </div>
-        <div class="field">
+        <div class="field{{if index .FieldErrors "length"}} invalid{{end}}">
           <label for="length">Release length (hours)</label>
-          <input id="length" name="length" type="number" min="0.25" step="0.25" value="{{.Length}}" placeholder="4" required>
-          <div class="hint">e.g. 4, 3.5, 2.25</div>
+          <input id="length" name="length" type="text" autocomplete="off" value="{{.Length}}" placeholder="4" required aria-invalid="{{if index .FieldErrors "length"}}true{{else}}false{{end}}" aria-describedby="length-error">
+          <div class="hint">e.g. 4, 3.5 or 4h30m</div>
+          <div class="field-error" id="length-error">{{index .FieldErrors "length"}}</div>
         </div>
-        <div class="field">
+        <div class="field{{if index .FieldErrors "combine"}} invalid{{end}}">
           <label for="combine">Combine (hours)</label>
-          <input id="combine" name="combine" type="number" min="0" step="0.25" value="{{.Combine}}" placeholder="optional">
+          <input id="combine" name="combine" type="text" autocomplete="off" value="{{.Combine}}" placeholder="optional" aria-invalid="{{if index .FieldErrors "combine"}}true{{else}}false{{end}}" aria-describedby="combine-error">
+          <div class="field-error" id="combine-error">{{index .FieldErrors "combine"}}</div>
+        </div>
+        {{if .ScenarioChoices}}
+        <fieldset class="field scenario-picks{{if index .FieldErrors "scenarios"}} invalid{{end}}" aria-describedby="scenarios-error">
+          <legend>Show scenarios</legend>
+          {{range .ScenarioChoices}}<label><input type="checkbox" name="scenarios" value="{{.N}}"{{if .Checked}} checked{{end}}> {{.N}}. {{.Title}}</label>{{end}}
+          <div class="hint">Unchecked scenarios are left out of the results and the share link</div>
+          <div class="field-error" id="scenarios-error">{{index .FieldErrors "scenarios"}}</div>
+        </fieldset>
+        {{end}}
+        <div class="field{{if index .FieldErrors "team"}} invalid{{end}}">
+          <label for="team">Team</label>
+          <textarea id="team" name="team" rows="3" placeholder="optional, one person per line: Ann&#10;Bob, 08:00-16:30, max_overtime=2" aria-invalid="{{if index .FieldErrors "team"}}true{{else}}false{{end}}" aria-describedby="team-error">{{.Team}}</textarea>
+          <div class="hint">A result per person for this release: a name, then their own normal hours (08:00-16:30) or limits such as min_rest=12, part_time=80% or max_day=10; the rest comes from the form</div>
+          <div class="field-error" id="team-error">{{index .FieldErrors "team"}}</div>
+        </div>
+        <div class="fields-row">
+          <div class="field{{if index .FieldErrors "handoff"}} invalid{{end}}">
+            <label><input id="handoff" name="handoff" type="checkbox" value="1"{{if .Handoff}} checked{{end}} aria-describedby="handoff-error"> Follow the sun</label>
+            <div class="hint">Split the release between the team: each hands off when their normal hours end</div>
+            <div class="field-error" id="handoff-error">{{index .FieldErrors "handoff"}}</div>
+          </div>
+          <div class="field{{if index .FieldErrors "tz"}} invalid{{end}}">
+            <label for="tz">Time zone</label>
+            <input id="tz" name="tz" type="text" value="{{.TZ}}" placeholder="e.g. Europe/Berlin" autocomplete="off" aria-invalid="{{if index .FieldErrors "tz"}}true{{else}}false{{end}}" aria-describedby="tz-error">
+            <div class="hint">Of the release start (default the server's); tz= on a team line is that person's</div>
+            <div class="field-error" id="tz-error">{{index .FieldErrors "tz"}}</div>
+          </div>
         </div>
       </div>
 
       <div class="form-section">
+        {{if .Profiles}}
+        <div class="field profile-field{{if index .FieldErrors "profile"}} invalid{{end}}">
+          <label for="profile">Profile</label>
+          <div class="time-row">
+            <select id="profile" name="profile" aria-invalid="{{if index .FieldErrors "profile"}}true{{else}}false{{end}}" aria-describedby="profile-error">
+              <option value="">(none)</option>
+              {{range .Profiles}}<option{{if eq . $.Profile}} selected{{end}}>{{.}}</option>{{end}}
+            </select>
+            <button type="submit" name="use_profile" value="1" formnovalidate class="use-profile">Apply</button>
+          </div>
+          <div class="hint">Fills the work day and legal limits below</div>
+          <div class="field-error" id="profile-error">{{index .FieldErrors "profile"}}</div>
+        </div>
+        {{end}}
         <div class="form-section-title">Work day</div>
         <div class="fields-row">
-          <div class="field">
+          <div class="field{{if index .FieldErrors "normal_start"}} invalid{{end}}">
             <label for="normal_start">Normal work start</label>
             <div class="time-row">
-              <input id="normal_start" name="normal_start" type="text" class="time-value" value="{{.NormalStart}}" placeholder="09:00" pattern="[0-9]{1,2}:[0-9]{2}" autocomplete="off">
-              <button type="button" class="time-picker-btn" data-for="normal_start" aria-label="Pick time">🕐</button>
+              <input id="normal_start" name="normal_start" type="text" class="time-value" value="{{.NormalStart}}" placeholder="09:00" autocomplete="off" aria-invalid="{{if index .FieldErrors "normal_start"}}true{{else}}false{{end}}" aria-describedby="normal_start-error">
+              <button type="submit" name="pick" value="normal_start" formaction="{{$.BasePath}}/calc#picker" formnovalidate class="time-picker-btn" data-for="normal_start" aria-label="Pick time">🕐</button>
             </div>
+            <div class="field-error" id="normal_start-error">{{index .FieldErrors "normal_start"}}</div>
           </div>
-          <div class="field">
+          <div class="field{{if index .FieldErrors "normal_end"}} invalid{{end}}">
             <label for="normal_end">Normal work end</label>
             <div class="time-row">
-              <input id="normal_end" name="normal_end" type="text" class="time-value" value="{{.NormalEnd}}" placeholder="17:30" pattern="[0-9]{1,2}:[0-9]{2}" autocomplete="off">
-              <button type="button" class="time-picker-btn" data-for="normal_end" aria-label="Pick time">🕐</button>
+              <input id="normal_end" name="normal_end" type="text" class="time-value" value="{{.NormalEnd}}" placeholder="17:30" autocomplete="off" aria-invalid="{{if index .FieldErrors "normal_end"}}true{{else}}false{{end}}" aria-describedby="normal_end-error">
+              <button type="submit" name="pick" value="normal_end" formaction="{{$.BasePath}}/calc#picker" formnovalidate class="time-picker-btn" data-for="normal_end" aria-label="Pick time">🕐</button>
             </div>
+            <div class="field-error" id="normal_end-error">{{index .FieldErrors "normal_end"}}</div>
           </div>
         </div>
+        <div class="field{{if index .FieldErrors "part_time"}} invalid{{end}}">
+          <label for="part_time">Part time</label>
+          <input id="part_time" name="part_time" type="text" value="{{.PartTime}}" placeholder="optional, 80% or 30h" autocomplete="off" aria-invalid="{{if index .FieldErrors "part_time"}}true{{else}}false{{end}}" aria-describedby="part_time-error">
+          <div class="hint">Share of full time, or hours a week; pro-rates the full day and the overtime cap</div>
+          <div class="field-error" id="part_time-error">{{index .FieldErrors "part_time"}}</div>
+        </div>
+        <div class="field{{if index .FieldErrors "core_hours"}} invalid{{end}}">
+          <label for="core_hours">Core hours</label>
+          <input id="core_hours" name="core_hours" type="text" value="{{.CoreHours}}" placeholder="optional, 10:00-15:00" autocomplete="off" aria-invalid="{{if index .FieldErrors "core_hours"}}true{{else}}false{{end}}" aria-describedby="core_hours-error">
+          <div class="hint">Scenarios whose next day starts later are flagged</div>
+          <div class="field-error" id="core_hours-error">{{index .FieldErrors "core_hours"}}</div>
+        </div>
+        <div class="field{{if index .FieldErrors "commitment"}} invalid{{end}}">
+          <label for="commitment">Next-day commitment</label>
+          <input id="commitment" name="commitment" type="text" value="{{.Commitment}}" placeholder="optional, 10:00 customer call" autocomplete="off" aria-invalid="{{if index .FieldErrors "commitment"}}true{{else}}false{{end}}" aria-describedby="commitment-error">
+          <div class="hint">Something that cannot move; scenarios that start later are flagged</div>
+          <div class="field-error" id="commitment-error">{{index .FieldErrors "commitment"}}</div>
+        </div>
         <div class="form-section-title">Legal limits</div>
         <div class="fields-row">
-          <div class="field">
+          <div class="field{{if index .FieldErrors "min_rest"}} invalid{{end}}">
             <label for="min_rest">Min rest after release (hours)</label>
-            <input id="min_rest" name="min_rest" type="number" min="1" step="0.5" value="{{.MinRest}}" placeholder="11">
+            <input id="min_rest" name="min_rest" type="text" autocomplete="off" value="{{.MinRest}}" placeholder="11" aria-invalid="{{if index .FieldErrors "min_rest"}}true{{else}}false{{end}}" aria-describedby="min_rest-error">
+            <div class="field-error" id="min_rest-error">{{index .FieldErrors "min_rest"}}</div>
+          </div>
+          <div class="field{{if index .FieldErrors "commute"}} invalid{{end}}">
+            <label for="commute">Commute each way (hours)</label>
+            <input id="commute" name="commute" type="text" autocomplete="off" value="{{.Commute}}" placeholder="0" aria-invalid="{{if index .FieldErrors "commute"}}true{{else}}false{{end}}" aria-describedby="commute-error">
+            <div class="hint">Travel home and back in; not counted as rest</div>
+            <div class="field-error" id="commute-error">{{index .FieldErrors "commute"}}</div>
           </div>
-          <div class="field">
+          <div class="field{{if index .FieldErrors "max_overtime"}} invalid{{end}}">
             <label for="max_overtime">Max overtime (hours)</label>
-            <input id="max_overtime" name="max_overtime" type="number" min="0" step="0.5" value="{{.MaxOvertime}}" placeholder="4">
+            <input id="max_overtime" name="max_overtime" type="text" autocomplete="off" value="{{.MaxOvertime}}" placeholder="4" aria-invalid="{{if index .FieldErrors "max_overtime"}}true{{else}}false{{end}}" aria-describedby="max_overtime-error">
             <div class="hint">Legal cap; work start shifts if OT would exceed this</div>
+            <div class="field-error" id="max_overtime-error">{{index .FieldErrors "max_overtime"}}</div>
           </div>
         </div>
       </div>
     </div>
 
+    {{with .Picker}}
+    <div class="card picker" id="picker">
+      <div class="form-section-title">{{.Label}} (24h)</div>
+      <div class="time-picker-row">
+        <label for="pick_hour">Hour</label>
+        <select id="pick_hour" name="pick_hour">{{range .Hours}}<option{{if .Selected}} selected{{end}}>{{.Value}}</option>{{end}}</select>
+        <label for="pick_minute">Min</label>
+        <select id="pick_minute" name="pick_minute">{{range .Minutes}}<option{{if .Selected}} selected{{end}}>{{.Value}}</option>{{end}}</select>
+      </div>
+      <div class="time-picker-actions">
+        <button type="submit" name="pick" value="" formnovalidate>Cancel</button>
+        <button type="submit" name="picked" value="{{.Field}}" formnovalidate class="primary">OK</button>
+      </div>
+    </div>
+    {{end}}
+
     <div class="form-actions">
       <button type="submit">Calculate</button>
+      <a class="bulk-link" href="{{.BasePath}}/bulk">Many releases? Upload a CSV</a>
+      {{if .Availability}}<a class="bulk-link" href="{{.BasePath}}/availability">Who is free? Team availability</a>{{end}}
     </div>
   </form>
 
+  <div id="results">{{with .ResultsHTML}}{{.}}{{else}}{{template "results" .}}{{end}}</div>
+
+  {{define "results"}}
   {{if .Error}}<div class="err">{{.Error}}</div>{{end}}
+  {{if and .Partial .FieldErrors}}<div class="err" data-field-errors>{{range $f, $msg := .FieldErrors}}<div data-field="{{$f}}" data-message="{{$msg}}">{{fieldLabel $f}}: {{$msg}}</div>{{end}}</div>{{end}}
 
   {{with .Result}}
     <div class="card">
       <div><b>Release Window</b>: <span class="mono">{{.ReleaseStart}}</span> → <span class="mono">{{.ReleaseEnd}}</span> (len <span class="mono">{{.ReleaseLen}}</span>)</div>
-      <div><b>Normal day</b>: <span class="mono">{{.NormalStart}} → {{.NormalEnd}}</span> (len <span class="mono">{{.NormalLen}}</span>)</div>
-      <div><b>Full day used</b>: <span class="mono">{{.FullDay}}</span>, <b>Min rest</b>: <span class="mono">{{.MinRest}}</span>, <b>Max overtime (cap)</b>: <span class="mono">{{.MaxOvertime}}</span></div>
+      {{if $.Handoffs}}
+      <table class="handoffs">
+        <tr><th>Who</th><th>Part of the release</th><th>Their time</th><th>Hands off to</th></tr>
+        {{range $.Handoffs}}<tr><td>{{.Name}}</td><td class="mono">{{.Release}}</td><td class="mono">{{.Local}} ({{.TZ}}, {{.Len}})</td><td>{{.Next}}</td></tr>{{end}}
+      </table>
+      {{else if $.TeamResults}}
+      <div>{{len $.TeamResults}} people</div>
+      {{else}}
+      {{template "day" .}}
+      <div class="links"><a href="{{$.PrintURL}}">Print view</a> · <a href="{{$.ExportURL}}" download>Download as HTML</a> · <a href="{{$.CompareURL}}">Compare with…</a>{{if $.ShortURL}} · <a href="{{$.ShortURL}}">Short link</a>{{end}}</div>
+      {{end}}
     </div>
 
-    {{range .Scenarios}}
+    {{if $.TeamResults}}
+      {{range $.TeamResults}}
+      <section class="person">
+        <h2>{{.Name}}{{with .Zone}} <span class="zone">{{.}}</span>{{end}}</h2>
+        {{with .Segment}}<div class="hint">Their part: {{.Local}} ({{.TZ}}){{with .Next}}, then hands off to {{.}}{{end}}</div>{{else}}{{if $.Handoffs}}<div class="hint">Not needed: the others cover the release</div>{{end}}{{end}}
+        {{with .Error}}<div class="err">{{.}}</div>{{end}}
+        {{with .Result}}
+          <div class="card">{{template "day" .}}</div>
+        {{end}}
+        {{range .Views}}{{template "scenario" .}}{{end}}
+      </section>
+      {{end}}
+    {{else}}
+      {{range .Scenarios}}{{template "scenario" (view .)}}{{end}}
+    {{end}}
+  {{end}}
+  {{end}}
+
+  {{define "day"}}
+      <div><b>Normal day</b>: <span class="mono">{{.NormalStart}} → {{.NormalEnd}}</span> (len <span class="mono">{{.NormalLen}}</span>)</div>
+      <div><b>Full day used</b>: <span class="mono">{{.FullDay}}</span>, <b>Min rest</b>: <span class="mono">{{.MinRest}}</span>{{with .Commute}} (+ <span class="mono">{{.}}</span> commute each way){{end}}, <b>Max overtime (cap)</b>: <span class="mono">{{.MaxOvertime}}</span>{{with .PartTime}}, <b>Part time</b>: <span class="mono">{{.}}</span>{{end}}</div>
+  {{end}}
+
+  {{define "scenario"}}
       <div class="card">
         <div><b>{{.Title}}</b></div>
+        {{timeline .Scenario}}
         <table>
           <tr><td class="k">Work Hours</td><td class="mono">{{.WorkHours}}</td></tr>
           <tr><td class="k">Release Window</td><td class="mono">{{.ReleaseWindow}}</td></tr>
@@ -761,9 +1063,12 @@ const pageHTML = `<!doctype html>
           <tr><td class="k">Release Hours Included in Full</td><td class="mono">{{.ReleaseIncluded}}</td></tr>
           <tr><td class="k">Overtime</td><td class="mono">{{.Overtime}}</td></tr>
           <tr><td class="k">Next Day Hours</td><td class="mono">{{.NextDayHours}}</td></tr>
+          {{with .TOIL}}<tr><td class="k">TOIL Balance</td><td class="mono">{{.}}</td></tr>{{end}}
+          {{with .UTC}}<tr><td class="k">In UTC</td><td class="mono">{{with .WorkHours}}work {{.}}, {{end}}release {{.ReleaseWindow}}, next day {{.NextDayHours}}</td></tr>{{end}}
         </table>
+        {{with .Notes}}<ol class="notes">{{range .}}<li>{{.}}</li>{{end}}</ol>{{end}}
+        {{with .Warnings}}<ul class="warnings">{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
       </div>
-    {{end}}
   {{end}}
 
   <div id="time-picker-overlay" class="time-picker-overlay" role="dialog" aria-modal="true" aria-label="Pick time (24h)">
@@ -773,7 +1078,7 @@ const pageHTML = `<!doctype html>
         <label for="tp-hour">Hour</label>
         <select id="tp-hour"></select>
         <label for="tp-minute">Min</label>
-        <select id="tp-minute"></select>
+        <select id="tp-minute" data-step="{{.MinuteStep}}"></select>
       </div>
       <div class="time-picker-actions">
         <button type="button" id="tp-cancel">Cancel</button>
@@ -782,82 +1087,8 @@ const pageHTML = `<!doctype html>
     </div>
   </div>
 
-  <script>
-(function() {
-  var overlay = document.getElementById('time-picker-overlay');
-  var hourSelect = document.getElementById('tp-hour');
-  var minuteSelect = document.getElementById('tp-minute');
-  var okBtn = document.getElementById('tp-ok');
-  var cancelBtn = document.getElementById('tp-cancel');
-  var targetInput = null;
-
-  function pad2(n) { return (n < 10 ? '0' : '') + n; }
-  function parseTime(s) {
-    if (!s || typeof s !== 'string') return { h: 0, m: 0 };
-    s = s.trim();
-    var m = s.match(/^(\d{1,2}):(\d{2})$/);
-    if (!m) return { h: 0, m: 0 };
-    var h = parseInt(m[1], 10);
-    var min = parseInt(m[2], 10);
-    if (h < 0 || h > 23 || min < 0 || min > 59) return { h: 0, m: 0 };
-    return { h: h, m: min };
-  }
-  function fillDropdowns() {
-    hourSelect.innerHTML = '';
-    for (var i = 0; i < 24; i++) {
-      var o = document.createElement('option');
-      o.value = i;
-      o.textContent = pad2(i);
-      hourSelect.appendChild(o);
-    }
-    minuteSelect.innerHTML = '';
-    for (var j = 0; j < 60; j++) {
-      var o = document.createElement('option');
-      o.value = j;
-      o.textContent = pad2(j);
-      minuteSelect.appendChild(o);
-    }
-  }
-  fillDropdowns();
-
-  function openPicker(inputId) {
-    targetInput = document.getElementById(inputId);
-    if (!targetInput) return;
-    var val = targetInput.value;
-    var t = parseTime(val);
-    hourSelect.value = t.h;
-    minuteSelect.value = t.m;
-    overlay.classList.add('open');
-    hourSelect.focus();
-  }
-  function closePicker() {
-    overlay.classList.remove('open');
-    targetInput = null;
-  }
-  function applyTime() {
-    if (!targetInput) return;
-    var h = parseInt(hourSelect.value, 10);
-    var m = parseInt(minuteSelect.value, 10);
-    targetInput.value = pad2(h) + ':' + pad2(m);
-    closePicker();
-  }
-
-  document.querySelectorAll('.time-picker-btn').forEach(function(btn) {
-    btn.addEventListener('click', function() { openPicker(btn.getAttribute('data-for')); });
-  });
-  okBtn.addEventListener('click', applyTime);
-  cancelBtn.addEventListener('click', closePicker);
-  overlay.addEventListener('click', function(e) {
-    if (e.target === overlay) closePicker();
-  });
-  document.addEventListener('keydown', function(e) {
-    if (!overlay.classList.contains('open')) return;
-    if (e.key === 'Escape') { e.preventDefault(); closePicker(); }
-    if (e.key === 'Enter') { e.preventDefault(); applyTime(); }
-  });
-})();
-  </script>
-
-  <footer>nightrelcalc v{{.Version}}</footer>
+  <script src="{{.BasePath}}/assets/app.js?v={{assetHash "app.js"}}"></script>
+
+  <footer>{{with .Brand.Footer}}<div class="brand-footer">{{.}}</div>{{end}}nightrelcalc v{{.Version}}</footer>
 </body>
 </html>`
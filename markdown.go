@@ -0,0 +1,42 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/* ---------------- Markdown plan (--output markdown, push github/gitlab) ---------------- */
+
+// mdCell escapes a table cell; Markdown would read "|" as a column break.
+var mdCell = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// writeMarkdown writes r as a heading, the release's limits and a table with
+// one row per scenario, for issue trackers and chat that render GitHub
+// flavoured Markdown.
+func writeMarkdown(w io.Writer, r datedResult) {
+	title := "Night release " + r.ReleaseStart + " → " + r.ReleaseEnd
+	if label := strings.TrimSpace(r.Date + " " + r.Event); label != "" {
+		title += " (" + label + ")"
+	}
+	fmt.Fprintf(w, "### %s\n\n", mdCell.Replace(title))
+	rest := r.MinRest
+	if r.Commute != "" {
+		rest += " + " + r.Commute + " commute each way"
+	}
+	fmt.Fprintf(w, "Length **%s** · normal day %s → %s · full day %s · min rest %s · max overtime %s\n\n",
+		r.ReleaseLen, r.NormalStart, r.NormalEnd, r.FullDay, rest, r.MaxOvertime)
+	if r.PartTime != "" {
+		fmt.Fprintf(w, "Part time %s\n\n", mdCell.Replace(r.PartTime))
+	}
+
+	fmt.Fprintln(w, "| Scenario | Work | Release | Total | Release included | Overtime | Next day |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|---|")
+	for _, s := range r.Scenarios {
+		cells := []string{s.Title, s.WorkHours, s.ReleaseWindow, s.TotalWork, s.ReleaseIncluded, s.Overtime, s.NextDayHours}
+		for i, c := range cells {
+			cells[i] = mdCell.Replace(strings.ReplaceAll(c, "->", "→"))
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
@@ -0,0 +1,69 @@
+package nightrelcalc
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/* ---------------- Matrix room messages ---------------- */
+
+// matrixTxn keeps transaction IDs unique within the process; the homeserver
+// drops a send whose ID it has seen as a retry.
+var matrixTxn atomic.Int64
+
+// matrixEvent is an m.room.message with an HTML body; Body is the plain-text
+// fallback.
+type matrixEvent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func matrixMessage(n notice) matrixEvent {
+	r := n.Result
+	var plain, rich strings.Builder
+	title := n.title()
+	meta := "Length " + r.ReleaseLen + " · full day " + r.FullDay + " · min rest " + r.MinRest + " · max overtime " + r.MaxOvertime
+	fmt.Fprintf(&plain, "%s\n%s\n", title, meta)
+	fmt.Fprintf(&rich, "<h4>%s</h4><p>%s</p>", html.EscapeString(title), html.EscapeString(meta))
+	for _, s := range r.Scenarios {
+		fmt.Fprintf(&plain, "\n%s\n  Work: %s\n  Total: %s\n  Overtime: %s (release included %s)\n  Next day: %s\n",
+			s.Title, s.WorkHours, s.TotalWork, s.Overtime, s.ReleaseIncluded, s.NextDayHours)
+		fmt.Fprintf(&rich, "<p><b>%s</b><br>Work: %s<br>Total: %s<br>Overtime: %s (release included %s)<br>Next day: %s</p>",
+			html.EscapeString(s.Title), html.EscapeString(s.WorkHours), html.EscapeString(s.TotalWork),
+			html.EscapeString(s.Overtime), html.EscapeString(s.ReleaseIncluded), html.EscapeString(s.NextDayHours))
+	}
+	if n.Link != "" {
+		fmt.Fprintf(&plain, "\n%s\n", n.Link)
+		fmt.Fprintf(&rich, `<p><a href="%s">Open in nightrelcalc</a></p>`, html.EscapeString(n.Link))
+	}
+	return matrixEvent{MsgType: "m.notice", Body: plain.String(), Format: "org.matrix.custom.html", FormattedBody: rich.String()}
+}
+
+// postMatrix sends ev to o.MatrixRoom with the client-server API, resolving
+// a #alias to its room ID first.
+func postMatrix(ctx context.Context, o NotifyOptions, ev matrixEvent) error {
+	if err := o.check(); err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(o.MatrixHomeserver, "/") + "/_matrix/client/v3"
+	room := o.MatrixRoom
+	if strings.HasPrefix(room, "#") {
+		var dir struct {
+			RoomID string `json:"room_id"`
+		}
+		if err := sendJSON(ctx, http.MethodGet, base+"/directory/room/"+url.PathEscape(room), o.MatrixToken, nil, &dir); err != nil {
+			return fmt.Errorf("resolve %s: %w", room, err)
+		}
+		room = dir.RoomID
+	}
+	txn := fmt.Sprintf("nightrelcalc-%d-%d", time.Now().UnixNano(), matrixTxn.Add(1))
+	return sendJSON(ctx, http.MethodPut, base+"/rooms/"+url.PathEscape(room)+"/send/m.room.message/"+txn, o.MatrixToken, ev, nil)
+}
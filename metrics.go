@@ -0,0 +1,179 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency histogram buckets in seconds (Prometheus client defaults).
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	route  string
+	method string
+	code   int
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative
+	sum    float64
+	count  uint64
+}
+
+// httpMetrics collects per-route request counts and latencies and renders
+// them in the Prometheus text exposition format.
+type httpMetrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[string]*histogram
+	stats     *webStats
+	cache     *resultCache
+}
+
+func newHTTPMetrics(stats *webStats, cache *resultCache) *httpMetrics {
+	return &httpMetrics{
+		requests:  map[requestKey]uint64{},
+		durations: map[string]*histogram{},
+		stats:     stats,
+		cache:     cache,
+	}
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// instrument records every request served by h, labelled by the pattern the
+// ServeMux inside h matched.
+func (m *httpMetrics) instrument(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		began := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(rec, r)
+
+		route := r.Pattern // set by the mux once it picked a handler
+		if route == "" {
+			route = "unmatched"
+		}
+		m.observe(requestKey{route: route, method: r.Method, code: rec.code}, time.Since(began))
+	})
+}
+
+func (m *httpMetrics) observe(k requestKey, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[k]++
+	h := m.durations[k.route]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.durations[k.route] = h
+	}
+	sec := d.Seconds()
+	for i, b := range latencyBuckets {
+		if sec <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += sec
+	h.count++
+}
+
+// requireMetricsToken rejects scrapes without "Authorization: Bearer
+// <token>"; with no token /metrics is as open as the site.
+func requireMetricsToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !(apiKeys{token}).valid(requestAPIKey(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="nightrelcalc metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP writes all metrics in the text exposition format.
+func (m *httpMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+	b.WriteString("# HELP nightrelcalc_http_requests_total HTTP requests by route, method and status code.\n")
+	b.WriteString("# TYPE nightrelcalc_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "nightrelcalc_http_requests_total{route=%q,method=%q,code=\"%d\"} %d\n", k.route, k.method, k.code, m.requests[k])
+	}
+
+	routes := make([]string, 0, len(m.durations))
+	for route := range m.durations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	b.WriteString("# HELP nightrelcalc_http_request_duration_seconds HTTP request latency by route.\n")
+	b.WriteString("# TYPE nightrelcalc_http_request_duration_seconds histogram\n")
+	for _, route := range routes {
+		h := m.durations[route]
+		var cum uint64
+		for i, le := range latencyBuckets {
+			cum += h.counts[i]
+			fmt.Fprintf(&b, "nightrelcalc_http_request_duration_seconds_bucket{route=%q,le=%q} %d\n", route, strconv.FormatFloat(le, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(&b, "nightrelcalc_http_request_duration_seconds_bucket{route=%q,le=\"+Inf\"} %d\n", route, h.count)
+		fmt.Fprintf(&b, "nightrelcalc_http_request_duration_seconds_sum{route=%q} %g\n", route, h.sum)
+		fmt.Fprintf(&b, "nightrelcalc_http_request_duration_seconds_count{route=%q} %d\n", route, h.count)
+	}
+	m.mu.Unlock()
+
+	m.stats.mu.Lock()
+	total, errors := m.stats.total, m.stats.errors
+	m.stats.mu.Unlock()
+	b.WriteString("# HELP nightrelcalc_calculations_total Calculations performed (including failed ones).\n")
+	b.WriteString("# TYPE nightrelcalc_calculations_total counter\n")
+	fmt.Fprintf(&b, "nightrelcalc_calculations_total %d\n", total)
+	b.WriteString("# HELP nightrelcalc_calculation_errors_total Calculations rejected because of invalid input.\n")
+	b.WriteString("# TYPE nightrelcalc_calculation_errors_total counter\n")
+	fmt.Fprintf(&b, "nightrelcalc_calculation_errors_total %d\n", errors)
+
+	hits, misses, entries := m.cache.counts()
+	b.WriteString("# HELP nightrelcalc_result_cache_hits_total Page calculations served from the result cache.\n")
+	b.WriteString("# TYPE nightrelcalc_result_cache_hits_total counter\n")
+	fmt.Fprintf(&b, "nightrelcalc_result_cache_hits_total %d\n", hits)
+	b.WriteString("# HELP nightrelcalc_result_cache_misses_total Page calculations not in the result cache, computed and added.\n")
+	b.WriteString("# TYPE nightrelcalc_result_cache_misses_total counter\n")
+	fmt.Fprintf(&b, "nightrelcalc_result_cache_misses_total %d\n", misses)
+	b.WriteString("# HELP nightrelcalc_result_cache_entries Calculations in the result cache.\n")
+	b.WriteString("# TYPE nightrelcalc_result_cache_entries gauge\n")
+	fmt.Fprintf(&b, "nightrelcalc_result_cache_entries %d\n", entries)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,288 @@
+package nightrelcalc
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- /next: the next stored release ---------------- */
+
+// upcomingRelease is a release of the config file's feeds or rosters (see
+// FeedConfig and RosterConfig) with its plan.
+type upcomingRelease struct {
+	Feed   string `json:"feed,omitempty"`   // the feed it is a window of
+	Roster string `json:"roster,omitempty"` // or the roster and member
+	Member string `json:"member,omitempty"` // who is down for it
+
+	Start time.Time    `json:"start"`
+	End   time.Time    `json:"end"`
+	Plan  *calc.Result `json:"plan"`
+
+	profile string
+}
+
+// hasReleases reports whether the config stores any releases for /next:
+// feed windows or rostered releases.
+func (c Config) hasReleases() bool {
+	if len(c.Feeds) > 0 {
+		return true
+	}
+	for _, r := range c.Rosters {
+		for _, m := range r.Members {
+			if len(m.Releases) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upcomingReleases are the first n stored releases to start after now, in
+// order, without their plans. At the same start a feed goes before a
+// roster, and either by name.
+func (c Config) upcomingReleases(now time.Time, n int) ([]upcomingRelease, error) {
+	var out []upcomingRelease
+	for _, name := range slices.Sorted(maps.Keys(c.Feeds)) {
+		f := c.Feeds[name]
+		for _, w := range f.Windows {
+			sched, length, err := parseCronWindow(w)
+			if err != nil {
+				return nil, err
+			}
+			for _, at := range sched.next(now, n) {
+				out = append(out, upcomingRelease{Feed: name, Start: at, End: at.Add(length), profile: f.Profile})
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Rosters)) {
+		roster := c.Rosters[name]
+		for _, m := range roster.Members {
+			for _, r := range m.Releases {
+				if start, end := r.window(); start.After(now) {
+					out = append(out, upcomingRelease{Roster: name, Member: m.Name, Start: start, End: end, profile: roster.Profile})
+				}
+			}
+		}
+	}
+	slices.SortStableFunc(out, func(a, b upcomingRelease) int { return a.Start.Compare(b.Start) })
+	return out[:min(n, len(out))], nil
+}
+
+// nextRelease is the first stored release to start after now, computed
+// under its profile; nil when none is scheduled.
+func (c Config) nextRelease(now time.Time) (*upcomingRelease, error) {
+	upcoming, err := c.upcomingReleases(now, 1)
+	if err != nil || len(upcoming) == 0 {
+		return nil, err
+	}
+	next := &upcoming[0]
+	p, err := c.lookupProfile(next.profile)
+	if err != nil {
+		return nil, err
+	}
+	if next.Plan, err = c.request(p, next.Start.Format("15:04"), next.End.Sub(next.Start).Hours()).compute(); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// nextView is /next.json, and what the /next page shows.
+type nextView struct {
+	Now     time.Time        `json:"now"`
+	Release *upcomingRelease `json:"release"` // null when none is scheduled
+}
+
+// id identifies the release v shows, for the stream's event IDs: a page
+// showing another one is out of date.
+func (v nextView) id() string {
+	b, _ := json.Marshal(v.Release)
+	return strings.Trim(hashETag(string(b)), `"`)
+}
+
+type NextData struct {
+	nextView
+
+	Countdown string // to the start, e.g. "in 2h15m"
+	ID        string // see nextView.id
+	Error     string
+
+	Version  string
+	BasePath string
+	CSPNonce string
+	Brand    Brand
+}
+
+// nextHandler serves /next, a page for a dashboard tab counting down to the
+// next stored release and showing the next-day hours of its scenarios, and
+// with asJSON /next.json, the same as JSON. The page follows /next/events
+// to show the release after it once it starts.
+func nextHandler(pages pageRenderer, tpl *template.Template, cfg Config, asJSON bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if asJSON {
+				w.Header().Set("Allow", "GET")
+				writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+				return
+			}
+			pages.methodNotAllowed(w, r, "GET")
+			return
+		}
+		now := appClock.Now().Truncate(time.Second)
+		next, err := cfg.nextRelease(now)
+		if asJSON {
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, nextView{Now: now, Release: next})
+			return
+		}
+		data := NextData{
+			nextView: nextView{Now: now, Release: next},
+			Version:  appVersion,
+			BasePath: pages.basePath,
+			CSPNonce: cspNonce(r),
+			Brand:    pages.brand,
+		}
+		if err != nil {
+			data.Error = err.Error()
+			pages.render(w, r, tpl, http.StatusInternalServerError, data)
+			return
+		}
+		if next != nil {
+			data.Countdown = countdown(now, next.Start)
+		}
+		data.ID = data.id()
+		pages.render(w, r, tpl, http.StatusOK, data)
+	}
+}
+
+// nextKeepAlive is how often an idle stream sends a comment, so proxies
+// keep it open.
+const nextKeepAlive = 15 * time.Second
+
+// nextEventsHandler serves /next/events, a Server-Sent Events stream of
+// /next.json: an event "next" with it on connecting and whenever the next
+// release changes, as one starts and the one after it is next. The
+// event ID is the release's (see nextView.id); a client reconnecting with
+// the one it has is sent nothing until it changes.
+//
+// The stream is exempt from the server's write timeout and ends on a
+// restart, which is when the config file's releases change; EventSource
+// clients then reconnect, and a page or status board shows the new next
+// release without being reloaded.
+func nextEventsHandler(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+		rc := http.NewResponseController(w)
+		rc.SetWriteDeadline(time.Time{}) // none where unsupported, e.g. in tests
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		sent := r.Header.Get("Last-Event-ID")
+		for {
+			now := appClock.Now().Truncate(time.Second)
+			next, err := cfg.nextRelease(now)
+			if err != nil {
+				log.Printf("next release: %v", err)
+				return
+			}
+			v := nextView{Now: now, Release: next}
+			if id := v.id(); id != sent {
+				b, _ := json.Marshal(v)
+				_, err = fmt.Fprintf(w, "event: next\nid: %s\ndata: %s\n\n", id, b)
+				sent = id
+			} else {
+				_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			if err != nil || rc.Flush() != nil {
+				return
+			}
+
+			// Nothing changes before the next release starts; until then
+			// the stream only keeps itself open.
+			wait := nextKeepAlive
+			if next != nil {
+				wait = min(wait, next.Start.Sub(appClock.Now())+time.Second)
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+const nextHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Brand.Name}} - next release</title>
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 960px; box-sizing: border-box; }
+    h2 { margin-top: 0; font-weight: 600; }
+    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
+    .hint { color: #666; font-size: 0.9em; }
+    .countdown { font-size: 2.5em; font-weight: 600; margin: 12px 0; font-variant-numeric: tabular-nums; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { padding: 6px 8px; border: 1px solid #eee; text-align: left; vertical-align: top; }
+    footer { margin-top: 40px; color: #666; font-size: 0.9em; text-align: center; }
+  </style>
+</head>
+<body data-id="{{.ID}}" data-events="{{.BasePath}}/next/events">
+  <h2>Next release</h2>
+
+  {{if .Error}}<div class="err">{{.Error}}</div>{{end}}
+
+  {{with .Release}}
+    <div class="hint">{{if .Feed}}{{.Feed}}{{else}}{{.Member}} ({{.Roster}}){{end}}: {{.Start.Format "Mon 2 Jan 15:04"}} -> {{.End.Format "15:04"}} (len {{.Plan.ReleaseLen}})</div>
+    <div class="countdown" id="countdown" data-start="{{.Start.Format "2006-01-02T15:04:05Z07:00"}}">{{$.Countdown}}</div>
+    <table>
+      <tr><th>Scenario</th><th>Next day hours</th><th>Overtime</th></tr>
+      {{range .Plan.Scenarios}}<tr><td>{{.Title}}</td><td>{{.NextDayHours}}</td><td>{{.Overtime}}</td></tr>{{end}}
+    </table>
+    <p class="hint">Mandatory rest {{.Plan.MinRest}} after the release; the work day {{.Plan.NormalStart}} -> {{.Plan.NormalEnd}}.</p>
+  {{else}}{{if not .Error}}
+    <div class="hint">No release is scheduled.</div>
+  {{end}}{{end}}
+
+  <footer>{{with .Brand.Footer}}<div>{{.}}</div>{{end}}nightrelcalc v{{.Version}} · <a href="{{.BasePath}}/next.json">JSON</a></footer>
+
+  <script nonce="{{.CSPNonce}}">
+    (function () {
+      // A page showing another release than the stream's is reloaded.
+      if (window.EventSource) {
+        var events = new EventSource(document.body.dataset.events);
+        events.addEventListener("next", function (e) {
+          if (e.lastEventId !== document.body.dataset.id) location.reload();
+        });
+      }
+      var el = document.getElementById("countdown");
+      if (!el) return;
+      var start = Date.parse(el.dataset.start);
+      function pad(n) { return (n < 10 ? "0" : "") + n; }
+      function tick() {
+        var s = Math.round((start - Date.now()) / 1000);
+        if (s <= 0) { el.textContent = "now"; return; }
+        el.textContent = "in " + Math.floor(s / 3600) + "h" + pad(Math.floor(s / 60) % 60) + "m" + pad(s % 60) + "s";
+        setTimeout(tick, 1000);
+      }
+      tick();
+    })();
+  </script>
+</body>
+</html>`
@@ -0,0 +1,66 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- --max-consecutive-nights ---------------- */
+
+// nightLog is the release dates (YYYY-MM-DD) with night work: the
+// --night-history plus the releases checked so far. The copies of
+// calcOptions made per batch row or plan instance share it, so they count
+// each other's nights.
+type nightLog map[string]bool
+
+// parseNightDates checks dates such as 2026-10-12 and returns them as a log.
+func parseNightDates(dates []string) (nightLog, error) {
+	log := nightLog{}
+	for _, d := range dates {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", d)
+		}
+		log[d] = true
+	}
+	return log, nil
+}
+
+// run is the number of nights in a row around day, day included, and the
+// first of them.
+func (l nightLog) run(day time.Time) (n int, first time.Time) {
+	first = day
+	for d := day.AddDate(0, 0, -1); l[d.Format(time.DateOnly)]; d = d.AddDate(0, 0, -1) {
+		first = d
+	}
+	n = 1
+	for d := first.AddDate(0, 0, 1); d.Equal(day) || l[d.Format(time.DateOnly)]; d = d.AddDate(0, 0, 1) {
+		n++
+	}
+	return n, first
+}
+
+// checkNights warns on every scenario of r when its release is night work
+// (see calc.IsNightWork) and makes more than --max-consecutive-nights in a
+// row, then records it. Results without a date cannot be placed in the
+// history and are not checked.
+func (o *calcOptions) checkNights(r datedResult) error {
+	if o.MaxNights == 0 || r.Date == "" || len(r.Scenarios) == 0 || !calc.IsNightWork(r.Scenarios[0].Timeline) {
+		return nil
+	}
+	day, err := time.Parse(time.DateOnly, r.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", r.Date)
+	}
+	n, first := o.nights.run(day)
+	o.nights[r.Date] = true
+	if n <= o.MaxNights {
+		return nil
+	}
+	w := fmt.Sprintf("%d nights in a row from %s, more than the limit of %d consecutive nights", n, first.Format(time.DateOnly), o.MaxNights)
+	for i := range r.Scenarios {
+		r.Scenarios[i].Warnings = append(r.Scenarios[i].Warnings, w)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- notifications (Slack, Teams, Matrix, webhooks, email) ---------------- */
+
+// How long one notification may take before it is given up.
+const notifyTimeout = 10 * time.Second
+
+// NotifyOptions are where computed plans are posted: after a CLI
+// calculation, or for each plan submitted with the web form. Webhooks also
+// get the API and slash command calculations (see forMachines). A config
+// profile may set its own ("notify": {"teams": "..."}), so each team's
+// plans go to its channel.
+type NotifyOptions struct {
+	Slack string `json:"slack,omitempty"` // incoming webhook URL
+	Teams string `json:"teams,omitempty"` // incoming webhook URL (Adaptive Card)
+
+	MatrixHomeserver string `json:"matrix_homeserver,omitempty"` // e.g. https://matrix.example.org
+	MatrixToken      string `json:"matrix_token,omitempty"`      // access token of the posting user
+	MatrixRoom       string `json:"matrix_room,omitempty"`       // !id:server or #alias:server
+
+	Webhooks      []string `json:"webhooks,omitempty"`       // get a signed JSON webhookEvent
+	WebhookSecret string   `json:"webhook_secret,omitempty"` // HMAC key for webhookSignatureHeader
+
+	Email []string `json:"email,omitempty"` // release participants; sent through the config file's smtp
+
+	smtp *SMTPConfig
+}
+
+func (o *NotifyOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Slack, "notify-slack", "", "Post the computed plan to this Slack incoming webhook URL")
+	fs.StringVar(&o.Teams, "notify-teams", "", "Post the computed plan as an Adaptive Card to this Microsoft Teams incoming webhook URL")
+	fs.StringVar(&o.MatrixHomeserver, "notify-matrix-homeserver", "", "Post the computed plan to Matrix through this homeserver URL")
+	fs.StringVar(&o.MatrixToken, "notify-matrix-token", "", "Access token of the Matrix user that posts")
+	fs.StringVar(&o.MatrixRoom, "notify-matrix-room", "", "Matrix room to post in (!id:server or #alias:server; the user must have joined)")
+	fs.StringArrayVar(&o.Webhooks, "webhook", nil, "POST a JSON event for each calculation to this URL (repeatable)")
+	fs.StringVar(&o.WebhookSecret, "webhook-secret", "", "Sign --webhook deliveries with HMAC-SHA256 of this secret ("+webhookSignatureHeader+" header)")
+	fs.StringArrayVar(&o.Email, "email", nil, "Email the computed plan (HTML and an ICS attachment) to this address, repeatable; needs \"smtp\" in the config file")
+}
+
+func (o NotifyOptions) enabled() bool {
+	return o.Slack != "" || o.Teams != "" || o.matrix() || len(o.Webhooks) > 0 || len(o.Email) > 0
+}
+
+// forMachines keeps only the webhooks: chat channels hear about plans
+// people submit, not every API call.
+func (o NotifyOptions) forMachines() NotifyOptions {
+	return NotifyOptions{Webhooks: o.Webhooks, WebhookSecret: o.WebhookSecret}
+}
+
+// matrix reports whether any Matrix setting is given; post needs all three.
+func (o NotifyOptions) matrix() bool {
+	return o.MatrixHomeserver != "" || o.MatrixToken != "" || o.MatrixRoom != ""
+}
+
+// check rejects a partial Matrix setup, or email without SMTP settings, up
+// front rather than on the first post.
+func (o NotifyOptions) check() error {
+	if o.matrix() && (o.MatrixHomeserver == "" || o.MatrixToken == "" || o.MatrixRoom == "") {
+		return errors.New("matrix notifications need a homeserver, an access token and a room (--notify-matrix-*)")
+	}
+	if len(o.Email) > 0 && o.smtp == nil {
+		return errors.New("--email needs an \"smtp\" section in the config file")
+	}
+	for _, addr := range o.Email {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid email address %q", addr)
+		}
+	}
+	return nil
+}
+
+// orElse fills the targets o leaves unset from def.
+func (o NotifyOptions) orElse(def NotifyOptions) NotifyOptions {
+	o.Slack = orDefault(o.Slack, def.Slack)
+	o.Teams = orDefault(o.Teams, def.Teams)
+	o.MatrixHomeserver = orDefault(o.MatrixHomeserver, def.MatrixHomeserver)
+	o.MatrixToken = orDefault(o.MatrixToken, def.MatrixToken)
+	o.MatrixRoom = orDefault(o.MatrixRoom, def.MatrixRoom)
+	if len(o.Webhooks) == 0 {
+		o.Webhooks = def.Webhooks
+	}
+	o.WebhookSecret = orDefault(o.WebhookSecret, def.WebhookSecret)
+	if len(o.Email) == 0 {
+		o.Email = def.Email
+	}
+	if o.smtp == nil {
+		o.smtp = def.smtp
+	}
+	return o
+}
+
+// notice is a computed plan to post, with the link to its result page when
+// the web UI knows it.
+type notice struct {
+	Result *calc.Result
+	Link   string
+	Source string // cli, web, api or slack
+	Person string // the team member the plan is for, when it is one person's
+}
+
+// title is "Night release 22:00 -> 01:00", then " for Ann" when n is one
+// person's.
+func (n notice) title() string {
+	t := "Night release " + n.Result.ReleaseStart + " -> " + n.Result.ReleaseEnd
+	if n.Person != "" {
+		t += " for " + n.Person
+	}
+	return t
+}
+
+// post sends n to every configured target and returns their errors joined.
+func (o NotifyOptions) post(ctx context.Context, n notice) error {
+	var errs []error
+	if o.Slack != "" {
+		if err := postJSON(ctx, o.Slack, slackMessage(n)); err != nil {
+			errs = append(errs, fmt.Errorf("notify slack: %w", err))
+		}
+	}
+	if o.Teams != "" {
+		if err := postJSON(ctx, o.Teams, teamsMessage(n)); err != nil {
+			errs = append(errs, fmt.Errorf("notify teams: %w", err))
+		}
+	}
+	if o.matrix() {
+		if err := postMatrix(ctx, o, matrixMessage(n)); err != nil {
+			errs = append(errs, fmt.Errorf("notify matrix: %w", err))
+		}
+	}
+	for _, u := range o.Webhooks {
+		if err := postWebhook(ctx, u, o.WebhookSecret, n); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", u, err))
+		}
+	}
+	if len(o.Email) > 0 {
+		if err := postEmail(ctx, o, n); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postAsync posts ns, in order, in the background, so a slow chat service
+// never holds up the page; failures are only logged. The posts outlive the
+// request ctx is from (keeping its values), each within notifyTimeout.
+func (o NotifyOptions) postAsync(ctx context.Context, ns ...notice) {
+	if !o.enabled() || len(ns) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		for _, n := range ns {
+			ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+			if err := o.post(ctx, n); err != nil {
+				log.Print(err)
+			}
+			cancel()
+		}
+	}()
+}
+
+func postJSON(ctx context.Context, url string, v any) error {
+	return sendJSON(ctx, http.MethodPost, url, "", v, nil)
+}
+
+// sendJSON sends v (none if nil) with an optional bearer token and decodes
+// the answer into out (unless nil).
+func sendJSON(ctx context.Context, method, url, token string, v, out any) error {
+	var body io.Reader
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doJSON(req, out)
+}
+
+// doJSON sends req; a non-2xx answer is an error with the start of its body.
+func doJSON(req *http.Request, out any) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+/* ---------------- Slack Block Kit ---------------- */
+
+type slackText struct {
+	Type string `json:"type"` // plain_text or mrkdwn
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackPayload is an incoming webhook message or slash command answer; Text
+// is the notification fallback for clients that do not show blocks.
+type slackPayload struct {
+	ResponseType string       `json:"response_type,omitempty"` // slash commands: ephemeral or in_channel
+	Text         string       `json:"text"`
+	Blocks       []slackBlock `json:"blocks,omitempty"`
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as markup; the
+// arrows in "21:00 -> 01:00" would otherwise start a link.
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackMessage(n notice) slackPayload {
+	r := n.Result
+	title := n.title()
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{"plain_text", title}},
+		{Type: "context", Elements: []slackText{{"mrkdwn", slackEscape.Replace(fmt.Sprintf(
+			"Length %s · full day %s · min rest %s · max overtime %s", r.ReleaseLen, r.FullDay, r.MinRest, r.MaxOvertime))}}},
+	}
+	for _, s := range r.Scenarios {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{"mrkdwn", slackEscape.Replace(
+			"*" + s.Title + "*\n" +
+				"Work: " + s.WorkHours + "\n" +
+				"Total: " + s.TotalWork + "\n" +
+				"Overtime: " + s.Overtime + " (release included " + s.ReleaseIncluded + ")\n" +
+				"Next day: " + s.NextDayHours)}})
+	}
+	if n.Link != "" {
+		blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{{"mrkdwn", "<" + slackEscape.Replace(n.Link) + "|Open in nightrelcalc>"}}})
+	}
+	return slackPayload{Text: title + " (" + r.ReleaseLen + ")", Blocks: blocks}
+}
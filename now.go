@@ -0,0 +1,55 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- now: release starting at the current time ---------------- */
+
+func newNowCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts  calcOptions
+		round time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "now",
+		Short: "Calculate for a release starting right now",
+		Long: "Calculate for a release starting at the current wall-clock time, rounded\n" +
+			"up to --round, e.g. when the release starts late and you want to know what\n" +
+			"that does to tomorrow.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if round < 0 {
+				return fmt.Errorf("--round must be >= 0")
+			}
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("round") && opts.Granularity > 0 {
+				round = opts.Granularity
+			}
+			opts.Start = roundUp(appClock.Now(), round).Format("15:04")
+			return opts.run(cmd.Context(), cmd.Flags(), cfg, *profileName)
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	opts.addSingleFlags(cmd.Flags())
+	cmd.Flags().DurationVar(&round, "round", 5*time.Minute, "Round the current time up to a multiple of this (0 = to the minute; default --granularity if set)")
+	return cmd
+}
+
+// roundUp rounds t up to a multiple of step within its day (to the next
+// minute for step <= a minute). A release never starts earlier than now.
+func roundUp(t time.Time, step time.Duration) time.Time {
+	step = max(step, time.Minute)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	since := t.Sub(day)
+	if r := since % step; r != 0 {
+		since += step - r
+	}
+	return day.Add(since)
+}
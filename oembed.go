@@ -0,0 +1,98 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/* ---------------- oEmbed provider (https://oembed.com) ---------------- */
+
+type oEmbedResponse struct {
+	Type            string `json:"type"`
+	Version         string `json:"version"`
+	Title           string `json:"title"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	CacheAge        int    `json:"cache_age"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailWidth  int    `json:"thumbnail_width"`
+	ThumbnailHeight int    `json:"thumbnail_height"`
+}
+
+// oEmbedURL is the discovery link for a result page served at pageURL.
+func oEmbedURL(origin, basePath, pageURL string) string {
+	return origin + basePath + "/oembed?format=json&url=" + url.QueryEscape(pageURL)
+}
+
+// oEmbedHandler serves /oembed?url=<result page URL>. The embed is the summary
+// card linked to the page, so consumers need no iframe (frame-ancestors stays 'none').
+func oEmbedHandler(cfg Config, basePath string, trustForwarded bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if f := q.Get("format"); f != "" && f != "json" {
+			writeJSON(w, http.StatusNotImplemented, apiError{"only format=json is supported"})
+			return
+		}
+		u, err := url.Parse(q.Get("url"))
+		if err != nil || q.Get("url") == "" {
+			writeJSON(w, http.StatusBadRequest, apiError{"url is required"})
+			return
+		}
+		if p := strings.TrimSuffix(u.Path, "/"); p != basePath {
+			writeJSON(w, http.StatusNotFound, apiError{"not a nightrelcalc result URL"})
+			return
+		}
+		req, err := calcRequestFromQuery(u.Query())
+		if err == nil {
+			req, err = req.under(cfg)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+			return
+		}
+		res, err := req.compute()
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+			return
+		}
+
+		width, height := ogWidth, ogHeight
+		if mw, err := strconv.Atoi(q.Get("maxwidth")); err == nil && mw > 0 && mw < width {
+			width, height = mw, mw*ogHeight/ogWidth
+		}
+		if mh, err := strconv.Atoi(q.Get("maxheight")); err == nil && mh > 0 && mh < height {
+			width, height = mh*ogWidth/ogHeight, mh
+		}
+
+		origin := requestOrigin(r, trustForwarded)
+		share := req.shareQuery()
+		page := origin + basePath + "/?" + share
+		img := origin + basePath + "/og.png?" + share
+		desc := buildShareDescription(res)
+		esc := template.HTMLEscapeString
+
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		writeJSON(w, http.StatusOK, oEmbedResponse{
+			Type:         "rich",
+			Version:      "1.0",
+			Title:        "Release " + res.ReleaseStart + " -> " + res.ReleaseEnd,
+			ProviderName: "nightrelcalc",
+			ProviderURL:  origin + basePath + "/",
+			CacheAge:     86400,
+			HTML: fmt.Sprintf(`<a href="%s" title="%s"><img src="%s" width="%d" height="%d" alt="%s"></a>`,
+				esc(page), esc(desc), esc(img), width, height, esc(desc)),
+			Width:           width,
+			Height:          height,
+			ThumbnailURL:    img,
+			ThumbnailWidth:  ogWidth,
+			ThumbnailHeight: ogHeight,
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- Open Graph summary card (PNG) ---------------- */
+
+// Card size recommended by Slack/Teams/Twitter for large previews.
+const (
+	ogWidth  = 1200
+	ogHeight = 630
+	ogPad    = 60
+)
+
+var (
+	ogFontsOnce         sync.Once
+	ogTitleFace, ogFace font.Face
+	ogSmallFace         font.Face
+	ogFontsErr          error
+	ogBackground        = color.RGBA{0xfa, 0xfa, 0xfa, 0xff}
+	ogText              = color.RGBA{0x21, 0x21, 0x21, 0xff}
+	ogMuted             = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	ogAccent            = color.RGBA{0x19, 0x76, 0xd2, 0xff}
+)
+
+func loadOGFonts() error {
+	ogFontsOnce.Do(func() {
+		face := func(ttf []byte, size float64) font.Face {
+			if ogFontsErr != nil {
+				return nil
+			}
+			f, err := opentype.Parse(ttf)
+			if err != nil {
+				ogFontsErr = err
+				return nil
+			}
+			fc, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+			if err != nil {
+				ogFontsErr = err
+				return nil
+			}
+			return fc
+		}
+		ogTitleFace = face(gobold.TTF, 54)
+		ogFace = face(goregular.TTF, 38)
+		ogSmallFace = face(goregular.TTF, 26)
+	})
+	return ogFontsErr
+}
+
+func ogDrawText(img draw.Image, face font.Face, c color.Color, x, y int, s string) {
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+func hexColor(s string) color.RGBA {
+	var r, g, b uint8
+	_, _ = fmt.Sscanf(strings.TrimPrefix(s, "#"), "%02x%02x%02x", &r, &g, &b)
+	return color.RGBA{r, g, b, 0xff}
+}
+
+// renderOGImage draws the release window, the first scenario's work hours and
+// next-day start, plus its timeline bar, as a PNG card.
+func renderOGImage(res *calc.Result) ([]byte, error) {
+	if err := loadOGFonts(); err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, ogWidth, ogHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(ogBackground), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, ogWidth, 12), image.NewUniform(ogAccent), image.Point{}, draw.Src)
+
+	ogDrawText(img, ogTitleFace, ogText, ogPad, 110, "Release "+res.ReleaseStart+" -> "+res.ReleaseEnd)
+	ogDrawText(img, ogSmallFace, ogMuted, ogPad, 156, "Length "+res.ReleaseLen+" · full day "+res.FullDay+" · min rest "+res.MinRest+" · max OT "+res.MaxOvertime)
+
+	if len(res.Scenarios) > 0 {
+		s := res.Scenarios[0]
+		ogDrawText(img, ogFace, ogText, ogPad, 250, "Work "+s.WorkHours)
+		ogDrawText(img, ogFace, ogText, ogPad, 306, "Overtime "+s.Overtime+" · included "+s.ReleaseIncluded)
+		ogDrawText(img, ogFace, ogText, ogPad, 362, "Next day "+s.NextDayHours)
+
+		// Timeline bar, reusing the SVG layout scaled to the card width.
+		l := layoutTimeline(s.Timeline)
+		scale := float64(ogWidth-2*ogPad) / l.Width
+		laneH, top := 34, 420
+		for _, b := range l.Blocks {
+			if b.W <= 0 {
+				continue
+			}
+			x0 := ogPad + int(b.X*scale)
+			x1 := ogPad + int((b.X+b.W)*scale)
+			y0 := top + b.Lane*(laneH+10)
+			draw.Draw(img, image.Rect(x0, y0, x1, y0+laneH), image.NewUniform(hexColor(tlColors[b.Kind])), image.Point{}, draw.Src)
+		}
+		for _, t := range l.Ticks {
+			ogDrawText(img, ogSmallFace, ogMuted, ogPad+int(t.X*scale)-30, top+2*laneH+10+36, t.Label)
+		}
+	}
+
+	ogDrawText(img, ogSmallFace, ogMuted, ogPad, ogHeight-30, "nightrelcalc v"+appVersion)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ogImageHandler serves /og.png for the same query params as the result page;
+// private keeps it out of shared caches, for a site behind --auth.
+func ogImageHandler(pages pageRenderer, cfg Config, private bool) http.HandlerFunc {
+	cacheControl := "public, max-age=86400"
+	if private {
+		cacheControl = "private, max-age=86400"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := calcRequestFromQuery(r.URL.Query())
+		if err == nil {
+			req, err = req.under(cfg)
+		}
+		if err != nil {
+			pages.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		res, err := req.compute()
+		if err != nil {
+			pages.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		rules, _ := json.Marshal(req.rules)
+		etag := hashETag("og.png", appVersion, req.shareQuery(), string(rules))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", cacheControl)
+		if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		b, err := renderOGImage(res)
+		if err != nil {
+			pages.error(w, r, http.StatusInternalServerError, "Could not render the preview image.")
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(b)
+	}
+}
+
+// requestOrigin is "scheme://host" of the request, honoring X-Forwarded-Proto/Host
+// when the server runs behind a trusted proxy. Link previews need absolute URLs.
+func requestOrigin(r *http.Request, trustForwarded bool) string {
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if trustForwarded {
+		if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+			scheme = p
+		}
+		if h := r.Header.Get("X-Forwarded-Host"); h != "" {
+			host = h
+		}
+	}
+	return scheme + "://" + host
+}
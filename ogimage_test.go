@@ -0,0 +1,44 @@
+package nightrelcalc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nightrelcalc/calc"
+)
+
+// TestOGImageCaching checks that the preview's ETag follows the config's
+// rules, and that a site behind --auth keeps it out of shared caches.
+func TestOGImageCaching(t *testing.T) {
+	serve := func(opts WebOptions) *httptest.ResponseRecorder {
+		t.Helper()
+		h, err := NewHandler(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/og.png?start=22:00&length=3", nil)
+		req.SetBasicAuth("ops", "secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d", rec.Code)
+		}
+		return rec
+	}
+
+	opts := DefaultWebOptions()
+	plain := serve(opts)
+	if cc := plain.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public") {
+		t.Errorf("open site: Cache-Control %q, want public", cc)
+	}
+	opts.Config = Config{Scenarios: []calc.Rule{{Title: "Config rule"}}}
+	if ruled := serve(opts); ruled.Header().Get("ETag") == plain.Header().Get("ETag") {
+		t.Errorf("the ETag is the same with a config rule")
+	}
+	opts.Auth = "ops:secret"
+	if cc := serve(opts).Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private") {
+		t.Errorf("site behind --auth: Cache-Control %q, want private", cc)
+	}
+}
@@ -0,0 +1,110 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push opsgenie: maintenance for the release ---------------- */
+
+// OpsgenieConfig is the "opsgenie" section of the config file:
+//
+//	"opsgenie": {"token": "...", "integrations": ["2f6a..."]}
+//
+// The token is an API integration key with configuration access.
+type OpsgenieConfig struct {
+	Token        string   `json:"token"`
+	Integrations []string `json:"integrations,omitempty"` // integration ids used when --integration is not given
+	APIURL       string   `json:"api_url,omitempty"`      // default https://api.opsgenie.com; EU accounts https://api.eu.opsgenie.com
+}
+
+func (c *OpsgenieConfig) validate() error {
+	if c.Token == "" {
+		return errors.New("opsgenie: token is required")
+	}
+	return nil
+}
+
+type opsgenieRule struct {
+	State  string `json:"state"` // disabled
+	Entity struct {
+		ID   string `json:"id"`
+		Type string `json:"type"` // integration
+	} `json:"entity"`
+}
+
+type opsgenieMaintenance struct {
+	Description string `json:"description"`
+	Time        struct {
+		Type      string    `json:"type"` // schedule
+		StartDate time.Time `json:"startDate"`
+		EndDate   time.Time `json:"endDate"`
+	} `json:"time"`
+	Rules []opsgenieRule `json:"rules"`
+}
+
+func (c *OpsgenieConfig) defaultTargets() []string { return c.Integrations }
+
+// request disables the integrations for the window, so they create no
+// alerts from their incoming events.
+func (c *OpsgenieConfig) request(w silenceWindow) any {
+	m := opsgenieMaintenance{Description: w.Description}
+	m.Time.Type = "schedule"
+	m.Time.StartDate, m.Time.EndDate = w.Start.UTC(), w.End.UTC()
+	for _, id := range w.Targets {
+		var rule opsgenieRule
+		rule.State = "disabled"
+		rule.Entity.ID, rule.Entity.Type = id, "integration"
+		m.Rules = append(m.Rules, rule)
+	}
+	return m
+}
+
+func (c *OpsgenieConfig) create(ctx context.Context, body any) (string, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	api := strings.TrimRight(orDefault(c.APIURL, "https://api.opsgenie.com"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api+"/v1/maintenance", bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+c.Token)
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := doJSON(req, &created); err != nil {
+		return "", err
+	}
+	return "maintenance " + created.Data.ID, nil
+}
+
+func newPushOpsgenieCommand(configPath, profileName *string) *cobra.Command {
+	return newPushSilenceCommand(configPath, profileName, silencerSpec{
+		use:   "opsgenie",
+		short: "Create an Opsgenie maintenance for the release",
+		long: "Create a scheduled maintenance from the start to the end of the release\n" +
+			"that disables the given integrations, so they raise no alerts for exactly\n" +
+			"that period. Needs an \"opsgenie\" section in the config file.\n\n" +
+			"  nightrelcalc push opsgenie --integration 2f6a... --start 22:00 --length 3",
+		target:      "integration",
+		targetUsage: "Opsgenie integration id to disable (repeatable; default: opsgenie.integrations of the config)",
+		load: func(cfg Config) (alertSilencer, bool) {
+			if cfg.Opsgenie == nil {
+				return &OpsgenieConfig{}, false
+			}
+			return cfg.Opsgenie, true
+		},
+	})
+}
@@ -0,0 +1,201 @@
+package nightrelcalc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push outlook: Microsoft Graph calendar events ---------------- */
+
+// GraphConfig is the "graph" section of the config file. Either an app
+// registration with the Calendars.ReadWrite application permission (tenant,
+// client id and secret; user is then required) or a delegated access token,
+// e.g. from "az account get-access-token --resource-type ms-graph":
+//
+//	"graph": {"tenant_id": "...", "client_id": "...", "client_secret": "...", "user": "ops@example.com"}
+type GraphConfig struct {
+	TenantID     string `json:"tenant_id,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+
+	User     string `json:"user,omitempty"`     // mailbox (UPN or id); "" is the token's own user
+	Calendar string `json:"calendar,omitempty"` // calendar id; "" is the default calendar
+
+	// National clouds have their own hosts.
+	Endpoint      string `json:"endpoint,omitempty"`       // default https://graph.microsoft.com
+	LoginEndpoint string `json:"login_endpoint,omitempty"` // default https://login.microsoftonline.com
+}
+
+func (c *GraphConfig) validate() error {
+	app := c.TenantID != "" || c.ClientID != "" || c.ClientSecret != ""
+	switch {
+	case app && c.AccessToken != "":
+		return errors.New("graph: give either tenant_id/client_id/client_secret or access_token, not both")
+	case app && (c.TenantID == "" || c.ClientID == "" || c.ClientSecret == ""):
+		return errors.New("graph: tenant_id, client_id and client_secret go together")
+	case !app && c.AccessToken == "":
+		return errors.New("graph: needs tenant_id/client_id/client_secret or access_token")
+	}
+	return nil
+}
+
+// graphTime is a dateTimeTimeZone; events are sent in UTC and Outlook shows
+// them in each reader's zone.
+type graphTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type graphEvent struct {
+	Subject       string     `json:"subject"`
+	Body          *graphBody `json:"body,omitempty"`
+	Start         graphTime  `json:"start"`
+	End           graphTime  `json:"end"`
+	ShowAs        string     `json:"showAs"`
+	Categories    []string   `json:"categories"`
+	TransactionID string     `json:"transactionId"` // Graph drops a repeated create with the same id
+}
+
+// graphEvents are the plan's calendar events (see planEvents) for Graph.
+func graphEvents(r datedResult) ([]graphEvent, error) {
+	events, err := planEvents(r)
+	if err != nil {
+		return nil, err
+	}
+	const layout = "2006-01-02T15:04:05"
+	var out []graphEvent
+	for _, e := range events {
+		ge := graphEvent{
+			Subject:       e.Summary,
+			Start:         graphTime{e.Start.UTC().Format(layout), "UTC"},
+			End:           graphTime{e.End.UTC().Format(layout), "UTC"},
+			ShowAs:        "busy",
+			Categories:    []string{"nightrelcalc"},
+			TransactionID: eventUID(r, e),
+		}
+		if e.Summary == "Mandatory rest" {
+			ge.ShowAs = "oof"
+		}
+		if e.Description != "" {
+			ge.Body = &graphBody{"text", e.Description}
+		}
+		out = append(out, ge)
+	}
+	return out, nil
+}
+
+// graphToken returns the access token: the configured one, or one for the
+// app registration (client credentials flow).
+func graphToken(ctx context.Context, c *GraphConfig, endpoint string) (string, error) {
+	if c.AccessToken != "" {
+		return c.AccessToken, nil
+	}
+	login := strings.TrimRight(orDefault(c.LoginEndpoint, "https://login.microsoftonline.com"), "/")
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"scope":         {endpoint + "/.default"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, login+"/"+url.PathEscape(c.TenantID)+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &tok); err != nil {
+		return "", fmt.Errorf("token: %w", err)
+	}
+	return tok.AccessToken, nil
+}
+
+// graphEventsURL is where events of c's calendar are created.
+func graphEventsURL(c *GraphConfig, endpoint string) string {
+	u := endpoint + "/v1.0/me"
+	if c.User != "" {
+		u = endpoint + "/v1.0/users/" + url.PathEscape(c.User)
+	}
+	if c.Calendar != "" {
+		return u + "/calendars/" + url.PathEscape(c.Calendar) + "/events"
+	}
+	return u + "/calendar/events"
+}
+
+func newPushOutlookCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts           pushOptions
+		user, calendar string
+	)
+	cmd := &cobra.Command{
+		Use:   "outlook",
+		Short: "Create the plan's events in an Outlook / Microsoft 365 calendar",
+		Long: "Create the release, the work day, the mandatory rest and the next day as\n" +
+			"events in an Exchange Online calendar through Microsoft Graph, for calendars\n" +
+			"that cannot subscribe to an ICS feed. Needs a \"graph\" section in the config\n" +
+			"file. Pushing the same plan again does not duplicate its events.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			events, err := graphEvents(r)
+			if err != nil {
+				return err
+			}
+			if opts.calc.DryRun {
+				return writeIndentedJSON(os.Stdout, events)
+			}
+			if cfg.Graph == nil {
+				return errors.New(`push outlook needs a "graph" section in the config file`)
+			}
+			gc := *cfg.Graph
+			gc.User = orDefault(user, gc.User)
+			gc.Calendar = orDefault(calendar, gc.Calendar)
+			if gc.User == "" && gc.AccessToken == "" {
+				return errors.New("an app registration needs a mailbox: set \"user\" in the graph config or pass --user")
+			}
+
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			endpoint := strings.TrimRight(orDefault(gc.Endpoint, "https://graph.microsoft.com"), "/")
+			token, err := graphToken(ctx, &gc, endpoint)
+			if err != nil {
+				return fmt.Errorf("graph: %w", err)
+			}
+			for _, e := range events {
+				var created struct {
+					WebLink string `json:"webLink"`
+				}
+				if err := sendJSON(ctx, http.MethodPost, graphEventsURL(&gc, endpoint), token, e, &created); err != nil {
+					return fmt.Errorf("graph: %s: %w", e.Subject, err)
+				}
+				fmt.Printf("Created %s (%s UTC): %s\n", e.Subject, strings.Replace(e.Start.DateTime, "T", " ", 1)[:16], created.WebLink)
+			}
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&user, "user", "", "Mailbox to create the events in (UPN or id; default: graph.user of the config)")
+	cmd.Flags().StringVar(&calendar, "calendar", "", "Calendar id (default: graph.calendar of the config, else the default calendar)")
+	return cmd
+}
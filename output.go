@@ -0,0 +1,208 @@
+package nightrelcalc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- CLI output formats ---------------- */
+
+var outputFormats = []string{"text", "json", "ndjson", "csv", "tsv", "markdown"}
+
+func checkOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("invalid --output %q (have: %s)", format, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
+
+// datedResult is a result with the date of its batch row ("" if it has none)
+// and, for calendar imports, the event it came from.
+type datedResult struct {
+	Date  string `json:"date,omitempty"`
+	Event string `json:"event,omitempty"`
+	*calc.Result
+
+	req calcRequest // the inputs, for --dry-run
+}
+
+// writeResult writes one result; JSON is the same object as /api/v1/calc returns.
+func writeResult(w io.Writer, o calcOptions, res *calc.Result) error {
+	switch o.Output {
+	case "json":
+		return writeIndentedJSON(w, res)
+	case "ndjson":
+		return json.NewEncoder(w).Encode(res)
+	case "csv", "tsv":
+		return writeBatch(w, o, []datedResult{{Result: res}})
+	case "markdown":
+		writeMarkdown(w, datedResult{Result: res})
+		return nil
+	}
+	writeCLI(w, res)
+	return nil
+}
+
+// writeBatch writes the results of a batch run in the format of o (or with
+// --dry-run what they would be); JSON is an array.
+func writeBatch(w io.Writer, o calcOptions, rs []datedResult) error {
+	bw := newBatchWriter(w, o)
+	for _, r := range rs {
+		if err := bw.write(r); err != nil {
+			return err
+		}
+	}
+	return bw.close()
+}
+
+// batchWriter writes batch results one at a time, as they are computed, so
+// a long batch needs no more memory than its current row. NDJSON is one
+// object per line; JSON is still a single array, opened with the first item.
+type batchWriter struct {
+	w        io.Writer
+	format   string
+	dryRun   bool // write newDryRun reports instead of results; never csv, tsv or markdown
+	noHeader bool // csv and tsv: rows only
+	n        int
+	cw       *csv.Writer
+}
+
+func newBatchWriter(w io.Writer, o calcOptions) *batchWriter {
+	bw := &batchWriter{w: w, format: o.Output, dryRun: o.DryRun, noHeader: o.NoHeader}
+	if bw.format == "csv" {
+		bw.cw = csv.NewWriter(w)
+	}
+	return bw
+}
+
+func (bw *batchWriter) write(r datedResult) error {
+	defer func() { bw.n++ }()
+	var v any = r
+	if bw.dryRun {
+		v = newDryRun(r)
+	}
+	switch bw.format {
+	case "json":
+		b, err := json.MarshalIndent(v, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if bw.n == 0 {
+			sep = "[\n  "
+		}
+		_, err = io.WriteString(bw.w, sep+string(b))
+		return err
+	case "ndjson":
+		return json.NewEncoder(bw.w).Encode(v)
+	case "csv", "tsv":
+		if bw.n == 0 && !bw.noHeader {
+			if err := bw.record(tableHeader); err != nil {
+				return err
+			}
+		}
+		for _, rec := range tableRows(r) {
+			if err := bw.record(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "markdown":
+		if bw.n > 0 {
+			fmt.Fprintln(bw.w)
+		}
+		writeMarkdown(bw.w, r)
+		return nil
+	}
+	if bw.n > 0 {
+		fmt.Fprintln(bw.w)
+	}
+	if label := strings.TrimSpace(r.Date + " " + r.Event); label != "" {
+		fmt.Fprintf(bw.w, "== %s ==\n", label)
+	}
+	if bw.dryRun {
+		writeDryRun(bw.w, v.(dryRun))
+		return nil
+	}
+	writeCLI(bw.w, r.Result)
+	return nil
+}
+
+// record writes one csv or tsv line.
+func (bw *batchWriter) record(rec []string) error {
+	if bw.format == "tsv" {
+		_, err := io.WriteString(bw.w, tsvLine(rec))
+		return err
+	}
+	_ = bw.cw.Write(rec)
+	bw.cw.Flush()
+	return bw.cw.Error()
+}
+
+// close ends the output: it closes the JSON array, and an empty csv or tsv
+// batch still gets its header.
+func (bw *batchWriter) close() error {
+	switch bw.format {
+	case "json":
+		end := "\n]\n"
+		if bw.n == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(bw.w, end)
+		return err
+	case "csv", "tsv":
+		if bw.n == 0 && !bw.noHeader {
+			return bw.record(tableHeader)
+		}
+	}
+	return nil
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// tableHeader is the column order of csv and tsv output. Scripts cut by
+// position, so columns are only ever added at the end.
+var tableHeader = []string{"date", "event", "release_start", "release_end", "release_len", "full_day", "min_rest", "max_overtime",
+	"scenario", "work_hours", "release_window", "total_work", "release_included", "overtime", "next_day_hours"}
+
+// tableRows are one row per scenario, with the release repeated on each.
+func tableRows(r datedResult) [][]string {
+	rows := make([][]string, 0, len(r.Scenarios))
+	for _, s := range r.Scenarios {
+		rows = append(rows, []string{r.Date, r.Event, r.ReleaseStart, r.ReleaseEnd, r.ReleaseLen, r.FullDay, r.MinRest, r.MaxOvertime,
+			s.Title, s.WorkHours, s.ReleaseWindow, s.TotalWork, s.ReleaseIncluded, s.Overtime, s.NextDayHours})
+	}
+	return rows
+}
+
+// writeCSV writes rs as csv, with the header.
+func writeCSV(w io.Writer, rs []datedResult) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(tableHeader)
+	for _, r := range rs {
+		_ = cw.WriteAll(tableRows(r))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tsvLine joins rec with tabs. Nothing is quoted: tabs and line breaks in a
+// value (an event summary, say) become spaces so each row stays one line.
+func tsvLine(rec []string) string {
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	out := make([]string, len(rec))
+	for i, v := range rec {
+		out[i] = clean.Replace(v)
+	}
+	return strings.Join(out, "\t") + "\n"
+}
@@ -0,0 +1,104 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push pagerduty: maintenance window for the release ---------------- */
+
+// PagerDutyConfig is the "pagerduty" section of the config file:
+//
+//	"pagerduty": {"token": "...", "from": "ops@example.com", "services": ["PABC123"]}
+//
+// The token is a REST API key; account keys also need From, the email of a
+// PagerDuty user the change is made as.
+type PagerDutyConfig struct {
+	Token    string   `json:"token"`
+	From     string   `json:"from,omitempty"`
+	Services []string `json:"services,omitempty"` // service ids used when --service is not given
+	APIURL   string   `json:"api_url,omitempty"`  // default https://api.pagerduty.com; EU accounts https://api.eu.pagerduty.com
+}
+
+func (c *PagerDutyConfig) validate() error {
+	if c.Token == "" {
+		return errors.New("pagerduty: token is required")
+	}
+	return nil
+}
+
+type pagerdutyRef struct {
+	ID   string `json:"id"`
+	Type string `json:"type"` // service_reference
+}
+
+type pagerdutyWindow struct {
+	Type        string         `json:"type"` // maintenance_window
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     time.Time      `json:"end_time"`
+	Description string         `json:"description"`
+	Services    []pagerdutyRef `json:"services"`
+}
+
+func (c *PagerDutyConfig) defaultTargets() []string { return c.Services }
+
+func (c *PagerDutyConfig) request(w silenceWindow) any {
+	win := pagerdutyWindow{Type: "maintenance_window", StartTime: w.Start, EndTime: w.End, Description: w.Description}
+	for _, id := range w.Targets {
+		win.Services = append(win.Services, pagerdutyRef{id, "service_reference"})
+	}
+	return map[string]pagerdutyWindow{"maintenance_window": win}
+}
+
+func (c *PagerDutyConfig) create(ctx context.Context, body any) (string, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	api := strings.TrimRight(orDefault(c.APIURL, "https://api.pagerduty.com"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api+"/maintenance_windows", bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	req.Header.Set("Authorization", "Token token="+c.Token)
+	if c.From != "" {
+		req.Header.Set("From", c.From)
+	}
+	var created struct {
+		MaintenanceWindow struct {
+			HTMLURL string `json:"html_url"`
+		} `json:"maintenance_window"`
+	}
+	if err := doJSON(req, &created); err != nil {
+		return "", err
+	}
+	return created.MaintenanceWindow.HTMLURL, nil
+}
+
+func newPushPagerDutyCommand(configPath, profileName *string) *cobra.Command {
+	return newPushSilenceCommand(configPath, profileName, silencerSpec{
+		use:   "pagerduty",
+		short: "Create a PagerDuty maintenance window for the release",
+		long: "Create a maintenance window from the start to the end of the release for\n" +
+			"the given services, so their alerts are suppressed for exactly that period.\n" +
+			"Needs a \"pagerduty\" section in the config file.\n\n" +
+			"  nightrelcalc push pagerduty --service PABC123 --start 22:00 --length 3",
+		target:      "service",
+		targetUsage: "PagerDuty service id to silence (repeatable; default: pagerduty.services of the config)",
+		load: func(cfg Config) (alertSilencer, bool) {
+			if cfg.PagerDuty == nil {
+				return &PagerDutyConfig{}, false
+			}
+			return cfg.PagerDuty, true
+		},
+	})
+}
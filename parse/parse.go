@@ -0,0 +1,345 @@
+// Package parse reads the times, durations and numbers of a calculation as
+// typed on the command line, in the web form and in API requests. Like calc
+// it does no I/O, so it also builds for GOOS=js GOARCH=wasm.
+//
+// Accepted are:
+//
+//	times      21:30, 9:30, 2130, 9pm, 9:30 pm, 12am (00:00)
+//	numbers    4.5, 4,5 (decimal comma), .5, -1
+//	hours      a number (4.5, 4,5) or a duration such as 4h30m, 90m, 4,5h
+//
+// Errors are *Error, with the position of the problem in the input.
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error is input that does not parse: what is wrong at which byte of it.
+type Error struct {
+	What  string // what was read: "time", "time range", "number" or "duration"
+	Input string // all of it, as given
+	Pos   int    // byte offset into Input of the problem; len(Input) at its end
+	Msg   string // e.g. "hour out of range, 0-23"
+	Want  string // the syntax expected, e.g. "HH:MM"
+}
+
+// Error is e.g. `invalid time "25:00" at column 1: hour out of range, 0-23`,
+// columns counted from 1.
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s %q at column %d: %s", e.What, e.Input, e.Pos+1, e.Msg)
+}
+
+// reader scans one input, between i and end, failing with its Error.
+type reader struct {
+	in         string
+	i, end     int
+	what, want string
+}
+
+// newReader reads s without its leading and trailing blanks; err is set
+// when there is nothing else.
+func newReader(s, what, want string) (r *reader, err error) {
+	r = &reader{in: s, end: len(strings.TrimRight(s, " \t")), what: what, want: want}
+	r.space()
+	if r.done() {
+		return r, r.fail(r.i, "expected %s", want)
+	}
+	return r, nil
+}
+
+func (r *reader) fail(pos int, format string, args ...any) error {
+	return &Error{What: r.what, Input: r.in, Pos: pos, Msg: fmt.Sprintf(format, args...), Want: r.want}
+}
+
+// unexpected fails at i on what is there, or on the end of the input.
+func (r *reader) unexpected(expected string) error {
+	if r.done() {
+		return r.fail(r.i, "expected %s", expected)
+	}
+	c, _ := utf8.DecodeRuneInString(r.in[r.i:])
+	return r.fail(r.i, "expected %s, got %q", expected, c)
+}
+
+func (r *reader) done() bool { return r.i >= r.end }
+
+func (r *reader) peek() byte {
+	if r.done() {
+		return 0
+	}
+	return r.in[r.i]
+}
+
+func (r *reader) space() {
+	for !r.done() && (r.in[r.i] == ' ' || r.in[r.i] == '\t') {
+		r.i++
+	}
+}
+
+// digits reads a run of ASCII digits, possibly empty.
+func (r *reader) digits() string {
+	from := r.i
+	for !r.done() && '0' <= r.in[r.i] && r.in[r.i] <= '9' {
+		r.i++
+	}
+	return r.in[from:r.i]
+}
+
+/* ---------------- times of day ---------------- */
+
+const clockSyntax = "HH:MM, HHMM or 9:30pm"
+
+// Clock reads a time of day, in 24h (21:30, 2130) or 12h (9:30pm, 9 PM)
+// form, as the time since midnight.
+func Clock(s string) (time.Duration, error) {
+	r, err := newReader(s, "time", clockSyntax)
+	if err != nil {
+		return 0, err
+	}
+	d, err := r.clock()
+	if err == nil && !r.done() {
+		return 0, r.unexpected("the end of the time")
+	}
+	return d, err
+}
+
+// ClockRange reads two times of day, "HH:MM-HH:MM", e.g. 10:00-15:00 or
+// 9am-5pm. It does not order them.
+func ClockRange(s string) (start, end time.Duration, err error) {
+	r, err := newReader(s, "time range", "HH:MM-HH:MM")
+	if err != nil {
+		return 0, 0, err
+	}
+	if start, err = r.clock(); err != nil {
+		return 0, 0, err
+	}
+	r.space()
+	if r.peek() != '-' {
+		return 0, 0, r.unexpected(`"-" between the times`)
+	}
+	r.i++
+	r.space()
+	if end, err = r.clock(); err != nil {
+		return 0, 0, err
+	}
+	if !r.done() {
+		return 0, 0, r.unexpected("the end of the range")
+	}
+	return start, end, nil
+}
+
+// LeadingClock reads a time of day at the start of s and returns what
+// follows it, e.g. "customer call" of "10:00 customer call".
+func LeadingClock(s string) (at time.Duration, rest string, err error) {
+	r, err := newReader(s, "time", clockSyntax)
+	if err != nil {
+		return 0, "", err
+	}
+	if at, err = r.clock(); err != nil {
+		return 0, "", err
+	}
+	if !r.done() && r.peek() != ' ' && r.peek() != '\t' {
+		return 0, "", r.unexpected("a space after the time")
+	}
+	return at, strings.TrimSpace(r.in[r.i:]), nil
+}
+
+// clock reads H:MM, HH:MM, HHMM or 9pm at i, each with an optional am/pm.
+func (r *reader) clock() (time.Duration, error) {
+	from := r.i
+	hs := r.digits()
+	var h, m int
+	minutes := false
+	minPos := r.i
+	switch {
+	case hs == "":
+		return 0, r.unexpected("a digit")
+	case r.peek() == ':':
+		if len(hs) > 2 {
+			return 0, r.fail(from, "hour has more than two digits")
+		}
+		r.i++
+		minPos = r.i
+		ms := r.digits()
+		if ms == "" {
+			return 0, r.unexpected("minutes")
+		}
+		if len(ms) > 2 {
+			return 0, r.fail(minPos, "minutes have more than two digits")
+		}
+		h, _ = strconv.Atoi(hs)
+		m, _ = strconv.Atoi(ms)
+		minutes = true
+	case len(hs) == 4:
+		h, _ = strconv.Atoi(hs[:2])
+		m, _ = strconv.Atoi(hs[2:])
+		minPos = from + 2
+		minutes = true
+	case len(hs) <= 2:
+		h, _ = strconv.Atoi(hs)
+	default:
+		return 0, r.fail(from, "%d digits, expected HH:MM or HHMM", len(hs))
+	}
+
+	beforeSuffix := r.i
+	r.space()
+	if pm, ok := r.meridiem(); ok {
+		if h < 1 || h > 12 {
+			return 0, r.fail(from, "hour out of range, 1-12 with am/pm")
+		}
+		h %= 12
+		if pm {
+			h += 12
+		}
+	} else {
+		r.i = beforeSuffix
+		if !minutes {
+			return 0, r.unexpected(`":" and minutes, or am/pm`)
+		}
+		if h > 23 {
+			return 0, r.fail(from, "hour out of range, 0-23")
+		}
+	}
+	if m > 59 {
+		return 0, r.fail(minPos, "minute out of range, 0-59")
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// meridiem reads am, pm, a.m. or p.m. (any case) as a whole word.
+func (r *reader) meridiem() (pm, ok bool) {
+	for _, w := range []string{"a.m.", "p.m.", "am", "pm"} {
+		j := r.i + len(w)
+		if j > r.end || !strings.EqualFold(r.in[r.i:j], w) {
+			continue
+		}
+		if j < r.end && isLetter(r.in[j]) {
+			continue
+		}
+		r.i = j
+		return w[0] == 'p' || w[0] == 'P', true
+	}
+	return false, false
+}
+
+func isLetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+/* ---------------- numbers and durations ---------------- */
+
+// Number reads a decimal number, with a point or a comma before the
+// fraction: 4.5, 4,5, .5, -1. There is no exponent, no Inf and no NaN.
+func Number(s string) (float64, error) {
+	r, err := newReader(s, "number", "a number such as 4.5 or 4,5")
+	if err != nil {
+		return 0, err
+	}
+	v, err := r.number(true)
+	if err == nil && !r.done() {
+		return 0, r.unexpected("the end of the number")
+	}
+	return v, err
+}
+
+// Hours reads a length as decimal hours (4.5, 4,5) or as a duration of
+// hours, minutes and seconds in time.ParseDuration style (4h30m, 90m,
+// 4,5h), in hours.
+func Hours(s string) (float64, error) {
+	r, err := newReader(s, "duration", "hours such as 4.5 or 4h30m")
+	if err != nil {
+		return 0, err
+	}
+	from := r.i
+	neg := false
+	if c := r.peek(); c == '+' || c == '-' {
+		neg = c == '-'
+		r.i++
+	}
+	v, err := r.number(false)
+	if err != nil {
+		return 0, err
+	}
+	if r.done() {
+		if neg {
+			v = -v
+		}
+		return v, nil
+	}
+	// A duration: the number just read is the first part's.
+	var sec float64
+	for {
+		unit, err := r.unit()
+		if err != nil {
+			return 0, err
+		}
+		sec += v * unit
+		if r.done() {
+			break
+		}
+		if v, err = r.number(false); err != nil {
+			return 0, err
+		}
+	}
+	if sec > maxSeconds {
+		return 0, r.fail(from, "out of range")
+	}
+	h := sec / 3600
+	if neg {
+		h = -h
+	}
+	return h, nil
+}
+
+// maxSeconds keeps a duration within time.Duration, as time.ParseDuration
+// does.
+const maxSeconds = float64(1<<63-1) / float64(time.Second)
+
+// unit reads h, m or s, as seconds.
+func (r *reader) unit() (float64, error) {
+	from := r.i
+	for !r.done() && isLetter(r.in[r.i]) {
+		r.i++
+	}
+	switch r.in[from:r.i] {
+	case "h":
+		return 3600, nil
+	case "m":
+		return 60, nil
+	case "s":
+		return 1, nil
+	case "":
+		r.i = from
+		return 0, r.unexpected("a unit (h, m or s)")
+	}
+	return 0, r.fail(from, "unknown unit %q, expected h, m or s", r.in[from:r.i])
+}
+
+// number reads digits with an optional fraction after a point or a comma,
+// and a sign before them when signed.
+func (r *reader) number(signed bool) (float64, error) {
+	from := r.i
+	sign := ""
+	if c := r.peek(); signed && (c == '+' || c == '-') {
+		sign = string(c)
+		r.i++
+	}
+	whole := r.digits()
+	frac := ""
+	if c := r.peek(); c == '.' || c == ',' {
+		r.i++
+		if frac = r.digits(); whole == "" && frac == "" {
+			r.i--
+		}
+	}
+	if whole == "" && frac == "" {
+		return 0, r.unexpected("a digit")
+	}
+	v, err := strconv.ParseFloat(sign+whole+"."+frac+"0", 64)
+	if err != nil {
+		return 0, r.fail(from, "out of range")
+	}
+	return v, nil
+}
@@ -0,0 +1,203 @@
+package parse_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"nightrelcalc/parse"
+)
+
+func TestClock(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Duration
+	}{
+		{"21:30", 21*time.Hour + 30*time.Minute},
+		{"9:30", 9*time.Hour + 30*time.Minute},
+		{"00:00", 0},
+		{"23:59", 23*time.Hour + 59*time.Minute},
+		{"2130", 21*time.Hour + 30*time.Minute},
+		{"0930", 9*time.Hour + 30*time.Minute},
+		{"9pm", 21 * time.Hour},
+		{"9:30 pm", 21*time.Hour + 30*time.Minute},
+		{"9 P.M.", 21 * time.Hour},
+		{"0930am", 9*time.Hour + 30*time.Minute},
+		{"12am", 0},
+		{"12:15am", 15 * time.Minute},
+		{"12pm", 12 * time.Hour},
+		{" 21:30\t", 21*time.Hour + 30*time.Minute},
+	} {
+		got, err := parse.Clock(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("Clock(%q) = %s, %v; want %s", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestClockRange(t *testing.T) {
+	for _, tc := range []struct {
+		in         string
+		start, end time.Duration
+	}{
+		{"10:00-15:00", 10 * time.Hour, 15 * time.Hour},
+		{"9am - 5pm", 9 * time.Hour, 17 * time.Hour},
+		{"2200-0100", 22 * time.Hour, time.Hour},
+	} {
+		start, end, err := parse.ClockRange(tc.in)
+		if err != nil || start != tc.start || end != tc.end {
+			t.Errorf("ClockRange(%q) = %s, %s, %v; want %s, %s", tc.in, start, end, err, tc.start, tc.end)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want float64
+	}{
+		{"4.5", 4.5},
+		{"7,5", 7.5},
+		{".5", 0.5},
+		{",5", 0.5},
+		{"7,", 7},
+		{"-1", -1},
+		{"+2", 2},
+		{" 3 ", 3},
+	} {
+		got, err := parse.Number(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("Number(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestHours(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want float64
+	}{
+		{"4.5", 4.5},
+		{"7,5", 7.5},
+		{"7h30m", 7.5},
+		{"90m", 1.5},
+		{"4,5h", 4.5},
+		{"1h30m36s", 1.51},
+		{"-1h", -1},
+		{"-2", -2},
+		{"0", 0},
+	} {
+		got, err := parse.Hours(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("Hours(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+// TestErrors checks where each error points, as the form and the CLI
+// underline it there.
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		fn  string
+		in  string
+		pos int
+		msg string
+	}{
+		{"clock", "", 0, "expected HH:MM, HHMM or 9:30pm"},
+		{"clock", "   ", 0, "expected HH:MM, HHMM or 9:30pm"},
+		{"clock", "25:00", 0, "hour out of range, 0-23"},
+		{"clock", " 25:00", 1, "hour out of range, 0-23"},
+		{"clock", "21:60", 3, "minute out of range, 0-59"},
+		{"clock", "2160", 2, "minute out of range, 0-59"},
+		{"clock", "213", 0, "3 digits, expected HH:MM or HHMM"},
+		{"clock", "123:00", 0, "hour has more than two digits"},
+		{"clock", "21:300", 3, "minutes have more than two digits"},
+		{"clock", "21:", 3, "expected minutes"},
+		{"clock", "9", 1, `expected ":" and minutes, or am/pm`},
+		{"clock", "13pm", 0, "hour out of range, 1-12 with am/pm"},
+		{"clock", "21:30x", 5, `expected the end of the time, got 'x'`},
+		{"clock", "x", 0, `expected a digit, got 'x'`},
+		{"clock", "9pmx", 1, `expected ":" and minutes, or am/pm, got 'p'`},
+		{"range", "10:00 15:00", 6, `expected "-" between the times, got '1'`},
+		{"range", "10:00-", 6, "expected a digit"},
+		{"number", "7,5,5", 3, `expected the end of the number, got ','`},
+		{"number", "1e5", 1, `expected the end of the number, got 'e'`},
+		{"number", "-", 1, "expected a digit"},
+		{"number", ".", 0, `expected a digit, got '.'`},
+		{"hours", "7x", 1, `unknown unit "x", expected h, m or s`},
+		{"hours", "7h30", 4, "expected a unit (h, m or s)"},
+		{"hours", "1h 30m", 2, `expected a digit, got ' '`},
+		{"hours", "h", 0, `expected a digit, got 'h'`},
+		{"hours", "99999999999h", 0, "out of range"},
+	} {
+		var err error
+		switch tc.fn {
+		case "clock":
+			_, err = parse.Clock(tc.in)
+		case "range":
+			_, _, err = parse.ClockRange(tc.in)
+		case "number":
+			_, err = parse.Number(tc.in)
+		case "hours":
+			_, err = parse.Hours(tc.in)
+		}
+		var pe *parse.Error
+		if !errors.As(err, &pe) {
+			t.Errorf("%s(%q): error %v, want a *parse.Error", tc.fn, tc.in, err)
+			continue
+		}
+		if pe.Pos != tc.pos || pe.Msg != tc.msg || pe.Input != tc.in {
+			t.Errorf("%s(%q): at %d %q (input %q), want at %d %q", tc.fn, tc.in, pe.Pos, pe.Msg, pe.Input, tc.pos, tc.msg)
+		}
+	}
+}
+
+// checkError fails unless err is nil or a *parse.Error of in pointing
+// into it, or at its end.
+func checkError(t *testing.T, in string, err error) {
+	t.Helper()
+	if err == nil {
+		return
+	}
+	var pe *parse.Error
+	if !errors.As(err, &pe) {
+		t.Fatalf("%q: error %v (%T), want a *parse.Error", in, err, err)
+	}
+	if pe.Input != in || pe.Pos < 0 || pe.Pos > len(in) {
+		t.Fatalf("%q: error at %d of %q", in, pe.Pos, pe.Input)
+	}
+	_ = pe.Error()
+}
+
+func FuzzClock(f *testing.F) {
+	for _, s := range []string{"21:30", "9:30", "2130", "9pm", "9:30 p.m.", "12am", "25:00", "21:", " 9 PM "} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		d, err := parse.Clock(in)
+		checkError(t, in, err)
+		if err == nil && (d < 0 || d >= 24*time.Hour) {
+			t.Fatalf("%q: %s, not a time of day", in, d)
+		}
+	})
+}
+
+func FuzzHours(f *testing.F) {
+	for _, s := range []string{"4.5", "7,5", "7h30m", "90m", "4,5h", "-1h", "1h30", "7x", ".h"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		_, err := parse.Hours(in)
+		checkError(t, in, err)
+	})
+}
+
+func FuzzNumber(f *testing.F) {
+	for _, s := range []string{"4.5", "7,5", ".5", "-1", "+2", "7,5,5", "1e5", "-"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		_, err := parse.Number(in)
+		checkError(t, in, err)
+	})
+}
@@ -0,0 +1,78 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- time picker without JavaScript ---------------- */
+
+// With JavaScript off the clock buttons submit the form: pick=<field>
+// re-renders it with this picker for the field, and picked=<field> (with
+// pick_hour / pick_minute) writes the chosen time back before calculating.
+
+var timeFieldLabels = map[string]string{
+	"start":        "Release start",
+	"normal_start": "Normal work start",
+	"normal_end":   "Normal work end",
+}
+
+type pickOption struct {
+	Value    string
+	Selected bool
+}
+
+// timePicker is the server-rendered hour/minute picker for one time field.
+type timePicker struct {
+	Field, Label   string
+	Hours, Minutes []pickOption
+}
+
+// newTimePicker offers the minutes in steps of the granularity.
+func newTimePicker(field, current string, granularity time.Duration) *timePicker {
+	label, ok := timeFieldLabels[field]
+	if !ok {
+		return nil
+	}
+	clock, err := calc.ParseClock(current)
+	if err != nil {
+		clock = 0
+	}
+	cur := int(time.Duration(clock) / time.Minute)
+	p := &timePicker{Field: field, Label: label}
+	for h := 0; h < 24; h++ {
+		p.Hours = append(p.Hours, pickOption{fmt.Sprintf("%02d", h), h == cur/60})
+	}
+	step, err := gridMinutes(granularity)
+	if err != nil {
+		step = 1
+	}
+	for m := 0; m < 60; m += step {
+		p.Minutes = append(p.Minutes, pickOption{fmt.Sprintf("%02d", m), m == cur%60})
+	}
+	return p
+}
+
+// applyPickedTime sets form[picked] from pick_hour / pick_minute; it reports
+// false for a picked field or time that is not valid.
+func applyPickedTime(form url.Values) bool {
+	field := form.Get("picked")
+	if field == "" {
+		return true
+	}
+	if _, ok := timeFieldLabels[field]; !ok {
+		return false
+	}
+	h, errH := strconv.Atoi(strings.TrimSpace(form.Get("pick_hour")))
+	m, errM := strconv.Atoi(strings.TrimSpace(form.Get("pick_minute")))
+	if errH != nil || errM != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return false
+	}
+	form.Set(field, fmt.Sprintf("%02d:%02d", h, m))
+	return true
+}
@@ -0,0 +1,123 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- input policy: --granularity and --strict ---------------- */
+
+// InputPolicy is how a server checks calculation inputs: their time step
+// and whether inputs that would be adjusted to fit are rejected.
+type InputPolicy struct {
+	Granularity time.Duration
+	Strict      bool
+}
+
+// strictAdjustments fails for a result whose inputs were adjusted to fit,
+// e.g. a combine longer than the release.
+func strictAdjustments(res *calc.Result) error {
+	if len(res.Adjustments) == 0 {
+		return nil
+	}
+	return fmt.Errorf("strict: %s", strings.Join(res.Adjustments, "; "))
+}
+
+// gridMinutes is step in minutes, checked against calc.Granularities; 0 is
+// any minute.
+func gridMinutes(step time.Duration) (int, error) {
+	if step == 0 {
+		return 1, nil
+	}
+	if step%time.Minute != 0 {
+		return 0, fmt.Errorf("granularity must be whole minutes, got %s", step)
+	}
+	m := int(step / time.Minute)
+	return m, calc.CheckGranularity(m)
+}
+
+// gridValue is one input the granularity applies to: a clock time (HH:MM)
+// or, when clock is "", hours.
+type gridValue struct {
+	field string
+	clock string
+	hours float64
+}
+
+// offGrid returns the field and the error of the first of vals that is not
+// a multiple of step.
+func offGrid(step time.Duration, vals ...gridValue) (string, error) {
+	stepMin, err := gridMinutes(step)
+	if err != nil {
+		return "granularity", err
+	}
+	for _, v := range vals {
+		if v.clock != "" {
+			err = calc.ClockOnGrid(v.clock, stepMin)
+		} else {
+			err = calc.HoursOnGrid(v.hours, stepMin)
+		}
+		if err != nil {
+			return v.field, err
+		}
+	}
+	return "", nil
+}
+
+// gridValues are the inputs of req that the granularity applies to, by
+// their API and form field names; call after withDefaults.
+func (req calcRequest) gridValues() []gridValue {
+	vals := []gridValue{
+		{field: "start", clock: req.Start},
+		{field: "length", hours: req.Length},
+		{field: "normal_start", clock: req.NormalStart},
+		{field: "normal_end", clock: req.NormalEnd},
+		{field: "min_rest", hours: *req.MinRest},
+		{field: "commute", hours: *req.Commute},
+		{field: "max_overtime", hours: *req.MaxOvertime},
+	}
+	if req.Combine != nil {
+		vals = append(vals, gridValue{field: "combine", hours: *req.Combine})
+	}
+	if req.Full > 0 {
+		vals = append(vals, gridValue{field: "full", hours: req.Full})
+	}
+	return vals
+}
+
+// checkGrid reports the first input of req that is not a multiple of step.
+func (req calcRequest) checkGrid(step time.Duration) error {
+	if field, err := offGrid(step, req.withDefaults().gridValues()...); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return nil
+}
+
+// flagName is the CLI flag of an API field, e.g. --normal-start.
+func flagName(field string) string {
+	return "--" + strings.ReplaceAll(field, "_", "-")
+}
+
+// checkGrid marks the first field of in that is not a multiple of step;
+// nil when all are.
+func (in formInput) checkGrid(step time.Duration) fieldErrors {
+	req := calcRequest{
+		Start:       in.Start,
+		Length:      in.LengthH,
+		NormalStart: in.NormalStart,
+		NormalEnd:   in.NormalEnd,
+		MinRest:     &in.MinRestH,
+		Commute:     &in.CommuteH,
+		MaxOvertime: &in.MaxOvertimeH,
+	}
+	if in.CombineH >= 0 {
+		req.Combine = &in.CombineH
+	}
+	if field, err := offGrid(step, req.gridValues()...); err != nil {
+		return fieldErrors{field: err.Error()}
+	}
+	return nil
+}
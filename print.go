@@ -0,0 +1,98 @@
+package nightrelcalc
+
+import (
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// printHandler serves /print: the result for the same query params as the
+// main page, rendered without the form for paper. With download set it serves
+// /export.html instead: the same self-contained page (inline styles and SVG,
+// no external requests) as an attachment, for archiving in the change record.
+func printHandler(pages pageRenderer, tpl *template.Template, cfg Config, trustForwarded, download bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := calcRequestFromQuery(r.URL.Query())
+		if err == nil {
+			req, err = req.under(cfg)
+		}
+		if err != nil {
+			pages.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		res, err := req.compute()
+		if err != nil {
+			pages.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		data := PageData{
+			Result:   res,
+			Version:  appVersion,
+			BasePath: pages.basePath,
+			CSPNonce: cspNonce(r),
+			PageURL:  requestOrigin(r, trustForwarded) + pages.basePath + "/?" + req.shareQuery(),
+			Brand:    pages.brand,
+		}
+		if download {
+			name := "nightrelcalc-" + strings.ReplaceAll(req.Start, ":", "") + "-" + fmtFloat(req.Length) + "h.html"
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		}
+		pages.render(w, r, tpl, http.StatusOK, data)
+	}
+}
+
+const printHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Brand.Name}} - release plan</title>
+  <meta name="robots" content="noindex">
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 12mm; color: #000; background: #fff; font-size: 11pt; }
+    h1 { font-size: 14pt; margin: 0 0 6pt 0; }
+    h2 { font-size: 12pt; margin: 0 0 4pt 0; }
+    .mono { font-family: ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, "Liberation Mono", "Courier New", monospace; }
+    table { border-collapse: collapse; width: 100%; margin: 4pt 0 0 0; }
+    td { padding: 2pt 6pt; border: 1px solid #000; vertical-align: top; }
+    .k { width: 45%; }
+    .notes, .warnings { margin: 4pt 0 0 0; padding-left: 14pt; font-size: 9pt; }
+    .warnings { font-weight: bold; }
+    .scenario { page-break-before: always; break-before: page; }
+    .timeline { display: block; margin: 6pt 0; filter: grayscale(1); }
+    footer { margin-top: 12pt; font-size: 9pt; }
+    @media screen { body { max-width: 800px; } }
+  </style>
+</head>
+<body>
+  {{with .Result}}
+    <h1>{{with $.Brand.Title}}{{.}}: {{end}}Night release plan</h1>
+    <table>
+      <tr><td class="k">Release window</td><td class="mono">{{.ReleaseStart}} -> {{.ReleaseEnd}} ({{.ReleaseLen}})</td></tr>
+      <tr><td class="k">Normal day</td><td class="mono">{{.NormalStart}} -> {{.NormalEnd}} ({{.NormalLen}})</td></tr>
+      <tr><td class="k">Full day used</td><td class="mono">{{.FullDay}}</td></tr>
+      <tr><td class="k">Min rest / max overtime (cap)</td><td class="mono">{{.MinRest}}{{with .Commute}} (+ {{.}} commute each way){{end}} / {{.MaxOvertime}}</td></tr>
+      {{with .PartTime}}<tr><td class="k">Part time</td><td class="mono">{{.}}</td></tr>{{end}}
+    </table>
+
+    {{range .Scenarios}}
+      <div class="scenario">
+        <h2>{{.Title}}</h2>
+        {{timeline .}}
+        <table>
+          <tr><td class="k">Work Hours</td><td class="mono">{{.WorkHours}}</td></tr>
+          <tr><td class="k">Release Window</td><td class="mono">{{.ReleaseWindow}}</td></tr>
+          <tr><td class="k">Total Work</td><td class="mono">{{.TotalWork}}</td></tr>
+          <tr><td class="k">Release Hours Included in Full</td><td class="mono">{{.ReleaseIncluded}}</td></tr>
+          <tr><td class="k">Overtime</td><td class="mono">{{.Overtime}}</td></tr>
+          <tr><td class="k">Next Day Hours</td><td class="mono">{{.NextDayHours}}</td></tr>
+          {{with .TOIL}}<tr><td class="k">TOIL Balance</td><td class="mono">{{.}}</td></tr>{{end}}
+        </table>
+        {{with .Notes}}<ol class="notes">{{range .}}<li>{{.}}</li>{{end}}</ol>{{end}}
+        {{with .Warnings}}<ul class="warnings">{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+      </div>
+    {{end}}
+  {{end}}
+  <footer>{{if .PageURL}}<div class="mono">{{.PageURL}}</div>{{end}}{{with .Brand.Footer}}<div>{{.}}</div>{{end}}nightrelcalc v{{.Version}}</footer>
+</body>
+</html>`
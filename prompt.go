@@ -0,0 +1,80 @@
+package nightrelcalc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- interactive prompts for missing flags ---------------- */
+
+// stdinIsTerminal reports whether stdin is a TTY (not a pipe or file), so
+// prompting makes sense.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// prompter asks for values on out and reads the answers from in.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask shows "label [def]: " until check accepts the answer; an empty answer
+// takes def.
+func (p *prompter) ask(label, def string, check func(string) error) (string, error) {
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+		line, err := p.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			fmt.Fprintln(p.out)
+			return "", fmt.Errorf("%s: no answer", strings.ToLower(label))
+		}
+		v := orDefault(line, def)
+		if err := check(v); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return v, nil
+	}
+}
+
+// promptRelease asks for the release start and/or length when they were not
+// given, with the web form defaults.
+func promptRelease(p *prompter, startStr *string, lengthH *float64) error {
+	if strings.TrimSpace(*startStr) == "" {
+		v, err := p.ask("Release start (HH:MM)", webDefaultStart, func(s string) error {
+			if _, err := calc.ParseClock(s); err != nil {
+				return errors.New("expected a time such as 21:30, 2130 or 9:30pm")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		*startStr = v
+	}
+	if *lengthH <= 0 {
+		v, err := p.ask("Release length (hours)", webDefaultLength, func(s string) error {
+			if h, err := calc.ParseHours(s); err != nil || h <= 0 {
+				return errors.New("must be > 0 hours, e.g. 4, 3.5 or 3h30m")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		*lengthH, _ = calc.ParseHours(v)
+	}
+	return nil
+}
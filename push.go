@@ -0,0 +1,78 @@
+package nightrelcalc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+/* ---------------- push: send a plan to another system ---------------- */
+
+// How long one push may take, token requests included.
+const pushTimeout = 30 * time.Second
+
+// pushOptions are the flags every push subcommand shares: the calculation
+// and the day the release runs. With --dry-run the subcommand prints what it
+// would send instead.
+type pushOptions struct {
+	calc calcOptions
+	date string
+}
+
+func (o *pushOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.calc.Start, "start", "", "Release start HH:MM")
+	o.calc.addFlags(fs)
+	fs.StringVar(&o.date, "date", "", "Day of the release, YYYY-MM-DD (default: today, or tomorrow once today's window is over)")
+	// What is pushed has a fixed format.
+	_ = fs.MarkHidden("output")
+	_ = fs.MarkHidden("no-header")
+}
+
+// plan computes the release the flags describe, dated.
+func (o *pushOptions) plan(fs *pflag.FlagSet, cfg Config, profileName string) (datedResult, error) {
+	if err := o.calc.prepare(fs, cfg, profileName); err != nil {
+		return datedResult{}, err
+	}
+	if o.calc.Start == "" {
+		return datedResult{}, fmt.Errorf("--start is required")
+	}
+	res, err := o.calc.compute()
+	if err != nil {
+		return datedResult{}, err
+	}
+	r := datedResult{Date: o.date, Result: res, req: o.calc.request()}
+	if r.Date == "" {
+		r.Date = releaseDay(appClock.Now(), res.Scenarios[0].Timeline).Date().Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, r.Date); err != nil {
+		return r, fmt.Errorf("invalid --date %q, expected YYYY-MM-DD", r.Date)
+	}
+	return r, o.calc.checkNights(r)
+}
+
+func newPushCommand(configPath, profileName *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Send a computed plan to a calendar or tracker",
+		Long: "Compute a release like the root command does and send the plan to\n" +
+			"another system. The credentials come from the config file (see --config).",
+	}
+	cmd.AddCommand(newPushOutlookCommand(configPath, profileName))
+	cmd.AddCommand(newPushGitHubCommand(configPath, profileName))
+	cmd.AddCommand(newPushGitLabCommand(configPath, profileName))
+	cmd.AddCommand(newPushJiraCommand(configPath, profileName))
+	cmd.AddCommand(newPushConfluenceCommand(configPath, profileName))
+	cmd.AddCommand(newPushServiceNowCommand(configPath, profileName))
+	cmd.AddCommand(newPushPagerDutyCommand(configPath, profileName))
+	cmd.AddCommand(newPushOpsgenieCommand(configPath, profileName))
+	cmd.AddCommand(newPushStatuspageCommand(configPath, profileName))
+	return cmd
+}
+
+// pushContext bounds one push by pushTimeout on top of the command's own
+// context, so the push also stops when the command is cancelled.
+func pushContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, pushTimeout)
+}
@@ -0,0 +1,44 @@
+package nightrelcalc
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+/* ---------------- installable web app (manifest, icons, service worker) ---------------- */
+
+// The offline compute core (calc/wasm) is not checked in; run go generate
+// before go build to include it. Without it the form simply needs the server.
+//go:generate sh -c "GOOS=js GOARCH=wasm go build -trimpath -ldflags=-s -o pwa/calc.wasm ./calc/wasm && cp \"$(go env GOROOT)/lib/wasm/wasm_exec.js\" pwa/"
+
+//go:embed pwa
+var pwaFS embed.FS
+
+// pwaHandler serves pwa/<name> at /<name>. The service worker is versioned by
+// appVersion (a new release drops the old offline cache) and never cached by HTTP.
+func pwaHandler(name string) http.Handler {
+	b, err := pwaFS.ReadFile("pwa/" + name)
+	if err != nil {
+		panic(err) // embedded at build time
+	}
+	cache := "public, max-age=604800"
+	if name == "sw.js" {
+		b = []byte(strings.ReplaceAll(string(b), "{{VERSION}}", appVersion))
+		cache = "no-cache"
+	}
+	return newEmbeddedFile(name, b, cache)
+}
+
+// registerPWA adds the manifest, icons and service worker routes.
+func registerPWA(mux *http.ServeMux) {
+	for _, name := range []string{"manifest.webmanifest", "sw.js", "icons/icon-192.png", "icons/icon-512.png"} {
+		mux.Handle("/"+name, pwaHandler(name))
+	}
+	for _, name := range []string{"calc.wasm", "wasm_exec.js"} {
+		if _, err := fs.Stat(pwaFS, "pwa/"+name); err == nil {
+			mux.Handle("/"+name, pwaHandler(name))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// nightrelcalc service worker: keeps the app shell available offline.
+// URLs are relative to the registration scope, so it works under --base-path.
+var CACHE = 'nightrelcalc-{{VERSION}}';
+var SHELL = ['./', 'manifest.webmanifest', 'icons/icon-192.png', 'icons/icon-512.png', 'assets/app.css', 'assets/app.js'];
+// Offline compute core; only present when the binary was built after go generate.
+var OPTIONAL = ['calc.wasm', 'wasm_exec.js'];
+
+function scoped(path) {
+  return new URL(path, self.registration.scope).toString();
+}
+
+self.addEventListener('install', function(e) {
+  e.waitUntil(caches.open(CACHE).then(function(c) {
+    return c.addAll(SHELL.map(scoped)).then(function() {
+      return Promise.all(OPTIONAL.map(function(p) {
+        return c.add(scoped(p)).catch(function() {});
+      }));
+    });
+  }).then(function() { return self.skipWaiting(); }));
+});
+
+self.addEventListener('activate', function(e) {
+  e.waitUntil(caches.keys().then(function(keys) {
+    return Promise.all(keys.filter(function(k) {
+      return k.indexOf('nightrelcalc-') === 0 && k !== CACHE;
+    }).map(function(k) { return caches.delete(k); }));
+  }).then(function() { return self.clients.claim(); }));
+});
+
+self.addEventListener('fetch', function(e) {
+  var req = e.request;
+  if (req.method !== 'GET') return;
+
+  // Pages: network first, falling back to the cached page or the bare shell.
+  if (req.mode === 'navigate') {
+    e.respondWith(fetch(req).then(function(resp) {
+      if (resp.ok) {
+        var copy = resp.clone();
+        caches.open(CACHE).then(function(c) { c.put(req, copy); });
+      }
+      return resp;
+    }).catch(function() {
+      return caches.match(req).then(function(hit) {
+        return hit || caches.match(scoped('./'));
+      });
+    }));
+    return;
+  }
+
+  // Shell assets: cache first (asset URLs carry a ?v= content hash).
+  e.respondWith(caches.match(req, { ignoreSearch: true }).then(function(hit) {
+    return hit || fetch(req);
+  }));
+});
@@ -0,0 +1,143 @@
+package nightrelcalc
+
+import (
+	"html/template"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Idle clients are forgotten after this long (their bucket is full again anyway).
+const rateLimitSweepEvery = time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a per-client-IP token bucket: perMinute tokens refill per
+// minute, up to burst tokens; each request takes one.
+type rateLimiter struct {
+	mu        sync.Mutex
+	perSec    float64
+	burst     float64
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+
+	trustForwarded bool
+}
+
+func newRateLimiter(perMinute, burst int, trustForwarded bool) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		perSec:         float64(perMinute) / 60,
+		burst:          float64(burst),
+		clients:        map[string]*tokenBucket{},
+		lastSweep:      time.Now(),
+		trustForwarded: trustForwarded,
+	}
+}
+
+// allow takes a token for ip; when none is left it returns how long until one is.
+func (l *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitSweepEvery {
+		for k, b := range l.clients {
+			if now.Sub(b.last) > rateLimitSweepEvery {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b := l.clients[ip]
+	if b == nil {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.clients[ip] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSec)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.perSec * float64(time.Second))
+	return false, wait
+}
+
+// clientIP is the remote IP, or, behind a trusted reverse proxy, the last
+// X-Forwarded-For hop: the address the proxy itself appended. Entries to
+// its left come from the client and can be anything.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.trustForwarded {
+		if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+			last := fwd[len(fwd)-1]
+			if i := strings.LastIndexByte(last, ','); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := strings.TrimSpace(last); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+var tooManyTpl = template.Must(template.New("429").Parse(tooManyHTML))
+
+// limit rejects over-limit clients with 429 and a Retry-After header. /metrics is exempt
+// so scrapes never gap.
+func (l *rateLimiter) limit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		ok, wait := l.allow(l.clientIP(r), time.Now())
+		if ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		secs := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		if err := writeTemplate(w, tooManyTpl, http.StatusTooManyRequests, struct {
+			Seconds  int
+			CSPNonce string
+		}{secs, cspNonce(r)}); err != nil {
+			log.Printf("render 429 page: %v", err)
+			http.Error(w, "Too many requests, retry in "+strconv.Itoa(secs)+"s.", http.StatusTooManyRequests)
+		}
+	})
+}
+
+const tooManyHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>nightrelcalc - slow down</title>
+  <style nonce="{{.CSPNonce}}">
+    body { font-family: system-ui, sans-serif; margin: 0; padding: 24px; max-width: 960px; box-sizing: border-box; }
+    .err { color: #b00020; margin: 12px 0; padding: 10px; background: #ffebee; border-radius: 6px; }
+  </style>
+</head>
+<body>
+  <div class="err">Too many requests from your address. Please wait {{.Seconds}}s and try again.</div>
+</body>
+</html>`
@@ -0,0 +1,277 @@
+package nightrelcalc
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- rosters: who shares the releases ---------------- */
+
+// RosterConfig is one entry of the config file's "rosters": the people who
+// share a team's releases, the profile their rest and night rules come from,
+// and the releases they worked or are down for:
+//
+//	"rosters": {"ops": {"profile": "infra", "members": [
+//	  {"name": "Ann", "releases": [{"date": "2026-10-12", "start": "22:00", "length": 3}]},
+//	  {"name": "Bob", "feed": "<long random string>", "notify": {"email": ["bob@example.com"]}}
+//	]}}
+//
+// A member's feed token serves a calendar of only their releases and the
+// work around them at /feed/{token}.ics, like the config's feeds. Their
+// notify targets get their own part of the team calculations submitted
+// with the web form, matched by name against the team field. Someone on
+// leave (their absences, or any event of their absence_calendar, an ICS
+// file or URL) cannot take a release. A member's rules, in the team field
+// names (see teamFields), are their own over the roster's profile here and
+// over the form in team mode:
+//
+//	{"name": "Cy", "rules": {"max_day": "10", "max_overtime": "1"}}
+type RosterConfig struct {
+	Profile string         `json:"profile,omitempty"`
+	Members []RosterMember `json:"members"`
+}
+
+// RosterMember is one person of a RosterConfig.
+type RosterMember struct {
+	Name     string          `json:"name"`
+	Releases []StoredRelease `json:"releases,omitempty"`
+	Feed     string          `json:"feed,omitempty"` // token of their calendar
+	Notify   NotifyOptions   `json:"notify,omitzero"`
+
+	Rules map[string]string `json:"rules,omitempty"` // by team field, e.g. "max_day": "10"
+
+	Absences        []Absence `json:"absences,omitempty"`
+	AbsenceCalendar string    `json:"absence_calendar,omitempty"` // ICS file or http(s) URL
+}
+
+// StoredRelease is a release someone worked or is assigned to, in the
+// server's time zone.
+type StoredRelease struct {
+	Date   string  `json:"date"`   // YYYY-MM-DD
+	Start  string  `json:"start"`  // HH:MM
+	Length float64 `json:"length"` // hours
+}
+
+func (r StoredRelease) validate() error {
+	if _, err := time.Parse(time.DateOnly, r.Date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", r.Date)
+	}
+	if _, err := calc.ParseClock(r.Start); err != nil {
+		return fmt.Errorf("%s: %w", r.Date, err)
+	}
+	if r.Length <= 0 {
+		return fmt.Errorf("%s: length must be > 0", r.Date)
+	}
+	return nil
+}
+
+// window is when r runs.
+func (r StoredRelease) window() (start, end time.Time) {
+	day, _ := time.ParseInLocation(time.DateOnly, r.Date, time.Local)
+	rs, _ := calc.ParseClock(r.Start)
+	start = calc.DayOf(day).Time(rs)
+	return start, start.Add(calc.Hours(r.Length))
+}
+
+func (c RosterConfig) validate(cfg Config) error {
+	if len(c.Members) == 0 {
+		return errors.New("members are required")
+	}
+	seen := map[string]bool{}
+	for _, m := range c.Members {
+		if m.Name == "" {
+			return errors.New("every member needs a name")
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("member %q is listed twice", m.Name)
+		}
+		seen[m.Name] = true
+		if m.Feed != "" {
+			if err := checkFeedToken(m.Feed); err != nil {
+				return fmt.Errorf("member %q: feed %w", m.Name, err)
+			}
+		}
+		for _, r := range m.Releases {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("member %q: %w", m.Name, err)
+			}
+		}
+		if err := checkPersonRules(m.Rules); err != nil {
+			return fmt.Errorf("member %q: rules: %w", m.Name, err)
+		}
+		for _, a := range m.Absences {
+			if err := a.validate(); err != nil {
+				return fmt.Errorf("member %q: %w", m.Name, err)
+			}
+		}
+	}
+	_, err := cfg.lookupProfile(c.Profile)
+	return err
+}
+
+// plans are m's releases in date order, each computed under the roster's
+// profile.
+func (c RosterConfig) plans(cfg Config, m RosterMember) ([]datedResult, error) {
+	p, err := cfg.lookupProfile(c.Profile)
+	if err != nil {
+		return nil, err
+	}
+	releases := slices.SortedFunc(slices.Values(m.Releases), func(a, b StoredRelease) int {
+		return cmp.Or(strings.Compare(a.Date, b.Date), strings.Compare(a.Start, b.Start))
+	})
+	var rs []datedResult
+	for _, r := range releases {
+		req := cfg.request(p, r.Start, r.Length)
+		res, err := req.compute()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Date, err)
+		}
+		rs = append(rs, datedResult{Date: r.Date, Event: m.Name, Result: res, req: req})
+	}
+	return rs, nil
+}
+
+// contact is the notify targets of the roster member called name, from the
+// first roster (by name) that has them.
+func (c Config) contact(name string) (NotifyOptions, bool) {
+	for _, roster := range slices.Sorted(maps.Keys(c.Rosters)) {
+		for _, m := range c.Rosters[roster].Members {
+			if m.Name == name && m.Notify.enabled() {
+				return m.Notify, true
+			}
+		}
+	}
+	return NotifyOptions{}, false
+}
+
+// checkPersonRules checks a member's rules as their line of the team field
+// would be.
+func checkPersonRules(rules map[string]string) error {
+	for k := range rules {
+		if !slices.Contains(teamFields, k) {
+			return fmt.Errorf("unknown field %q, expected one of %s", k, strings.Join(teamFields, ", "))
+		}
+	}
+	base, _ := validateForm(webDefaultStart, webDefaultLength, "", "", "", "", "", "", "", "", "", "")
+	_, _, err := memberInput(base, rules, InputPolicy{})
+	return err
+}
+
+// personRules are the rules of the roster members who have them, by name,
+// from the first roster (by name) that lists them; team mode puts them
+// under each person's line.
+func (c Config) personRules() map[string]map[string]string {
+	out := map[string]map[string]string{}
+	for _, roster := range slices.Sorted(maps.Keys(c.Rosters)) {
+		for _, m := range c.Rosters[roster].Members {
+			if _, ok := out[m.Name]; !ok && len(m.Rules) > 0 {
+				out[m.Name] = m.Rules
+			}
+		}
+	}
+	return out
+}
+
+// restRules are the limits a release must keep to with the others of the
+// same person, from the roster's profile and then their own rules.
+type restRules struct {
+	MinRest, Commute time.Duration // the commute is each way, around the rest
+	MaxNights        int           // consecutive nights with night work; 0 for no limit
+	MaxDay           time.Duration // the longest release they may take; 0 for no limit
+}
+
+func (c RosterConfig) rules(cfg Config) restRules {
+	p, _ := cfg.lookupProfile(c.Profile)
+	hours := func(f *float64, def float64) time.Duration {
+		if f != nil {
+			def = *f
+		}
+		return calc.Hours(def)
+	}
+	return restRules{
+		MinRest:   hours(p.MinRest, 11),
+		Commute:   hours(p.Commute, 0),
+		MaxNights: p.MaxConsecutiveNights,
+	}
+}
+
+// rest is the min rest plus the commute both ways.
+func (r restRules) rest() time.Duration {
+	return r.MinRest + 2*r.Commute
+}
+
+// forMember is r with m's own min_rest, commute and max_day.
+func (r restRules) forMember(m RosterMember) restRules {
+	for k, d := range map[string]*time.Duration{"min_rest": &r.MinRest, "commute": &r.Commute, "max_day": &r.MaxDay} {
+		if h, err := calc.ParseHours(m.Rules[k]); m.Rules[k] != "" && err == nil {
+			*d = calc.Hours(h)
+		}
+	}
+	return r
+}
+
+// eligibility is whether a person can take a release, and if not why.
+type eligibility struct {
+	OK       bool
+	Assigned bool   // already down for a release that day
+	Reason   string // why not, e.g. "rest after the 2026-10-13 release until 12:00"
+}
+
+// eligible checks a release from start for length against m's releases:
+// one of theirs the same day, leave during it or the rest after it (the
+// morning after), the rest around each of their releases, and the run of
+// consecutive nights it would make. When their absence calendar cannot be
+// read (in time for ctx) they are not eligible either.
+func (m RosterMember) eligible(ctx context.Context, start time.Time, length time.Duration, rules restRules) eligibility {
+	end := start.Add(length)
+	date := start.Format(time.DateOnly)
+	nights := nightLog{}
+	for _, r := range m.Releases {
+		if r.Date == date {
+			return eligibility{Assigned: true, Reason: fmt.Sprintf("down for the %s release", r.Start)}
+		}
+	}
+	rules = rules.forMember(m)
+	if rules.MaxDay > 0 && length > rules.MaxDay {
+		return eligibility{Reason: fmt.Sprintf("the release is longer than their %s day limit", calc.FmtDuration(rules.MaxDay))}
+	}
+	away, err := m.away(ctx)
+	if err != nil {
+		return eligibility{Reason: err.Error()}
+	}
+	if l, ok := awayDuring(away, start, end.Add(rules.rest())); ok {
+		return eligibility{Reason: fmt.Sprintf("%s until %s", l.What, l.End.Format("Mon 02 Jan 15:04"))}
+	}
+	for _, r := range m.Releases {
+		rs, re := r.window()
+		switch {
+		case rs.Before(end) && start.Before(re.Add(rules.rest())):
+			return eligibility{Reason: fmt.Sprintf("rest after the %s release until %s", r.Date, re.Add(rules.rest()).Format("Mon 15:04"))}
+		case start.Before(rs) && rs.Before(end.Add(rules.rest())):
+			return eligibility{Reason: fmt.Sprintf("no %s rest before the %s release", calc.FmtDuration(rules.rest()), r.Date)}
+		}
+		if isNightWork(rs, re) {
+			nights[r.Date] = true
+		}
+	}
+	if rules.MaxNights > 0 && isNightWork(start, end) {
+		if n, first := nights.run(start); n > rules.MaxNights {
+			return eligibility{Reason: fmt.Sprintf("%d nights in a row from %s, more than %d", n, first.Format(time.DateOnly), rules.MaxNights)}
+		}
+	}
+	return eligibility{OK: true}
+}
+
+// isNightWork is calc.IsNightWork for a release from start to end.
+func isNightWork(start, end time.Time) bool {
+	rs := calc.DayOf(start).Offset(start)
+	return calc.IsNightWork(calc.Timeline{ReleaseStart: rs, ReleaseEnd: rs.Add(end.Sub(start))})
+}
@@ -0,0 +1,102 @@
+package nightrelcalc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- scenario selection (--scenarios 1,3) ---------------- */
+
+// maxScenarioPick is the highest scenario number that can be picked; share
+// tokens keep the selection as a bitmask.
+const maxScenarioPick = 64
+
+// parseScenarioList reads scenario numbers such as "1,3" (1 is the first
+// scenario of the full result). "" picks none, which means all.
+func parseScenarioList(s string) ([]int, error) {
+	var picks []int
+	for f := range strings.SplitSeq(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario %q, expected numbers such as 1,3", f)
+		}
+		picks = append(picks, n)
+	}
+	return checkScenarioList(picks)
+}
+
+// checkScenarioList returns picks sorted and without repeats.
+func checkScenarioList(picks []int) ([]int, error) {
+	for _, n := range picks {
+		if n < 1 || n > maxScenarioPick {
+			return nil, fmt.Errorf("invalid scenario %d, scenarios are numbered from 1 to %d", n, maxScenarioPick)
+		}
+	}
+	picks = slices.Sorted(slices.Values(picks))
+	return slices.Compact(picks), nil
+}
+
+// formatScenarioList is the inverse of parseScenarioList.
+func formatScenarioList(picks []int) string {
+	s := make([]string, len(picks))
+	for i, n := range picks {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// scenarioNumbers are 1 to n.
+func scenarioNumbers(n int) []int {
+	picks := make([]int, n)
+	for i := range picks {
+		picks[i] = i + 1
+	}
+	return picks
+}
+
+// selectScenarios keeps only the picked scenarios of res, in their order;
+// no picks keep them all.
+func selectScenarios(res *calc.Result, picks []int) error {
+	if len(picks) == 0 {
+		return nil
+	}
+	if last := picks[len(picks)-1]; last > len(res.Scenarios) {
+		if len(res.Scenarios) == 0 {
+			return errors.New("there are no scenarios to pick from")
+		}
+		return fmt.Errorf("there is no scenario %d, only 1 to %d", last, len(res.Scenarios))
+	}
+	kept := make([]calc.Scenario, 0, len(picks))
+	for _, n := range picks {
+		kept = append(kept, res.Scenarios[n-1])
+	}
+	res.Scenarios = kept
+	return nil
+}
+
+// scenarioChoice is one checkbox of the web form's scenario selection.
+type scenarioChoice struct {
+	N       int
+	Title   string
+	Checked bool
+}
+
+// scenarioChoices are the checkboxes for the scenarios of res before
+// selectScenarios; with no picks all are checked.
+func scenarioChoices(res *calc.Result, picks []int) []scenarioChoice {
+	choices := make([]scenarioChoice, len(res.Scenarios))
+	for i, s := range res.Scenarios {
+		n := i + 1
+		choices[i] = scenarioChoice{N: n, Title: s.Title, Checked: len(picks) == 0 || slices.Contains(picks, n)}
+	}
+	return choices
+}
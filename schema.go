@@ -0,0 +1,220 @@
+package nightrelcalc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nightrelcalc/calc"
+	"nightrelcalc/parse"
+)
+
+/* ---------------- JSON Schema of the API (/schema.json) ---------------- */
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// jsonSchema is the part of JSON Schema that schema.json uses; other
+// keywords (title, description, ...) are documentation only.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	Minimum              *float64               `json:"minimum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	Pattern              string                 `json:"pattern"`
+	Format               string                 `json:"format"`
+
+	re *regexp.Regexp
+}
+
+// schemaFormats check the formats of schema.json with the parsers the
+// inputs go through, so a value is refused with where it goes wrong.
+var schemaFormats = map[string]func(string) error{
+	"clock": func(s string) error {
+		_, err := parse.Clock(s)
+		return err
+	},
+	"clock-range": func(s string) error {
+		_, _, err := calc.ParseClockRange(s)
+		return err
+	},
+	"commitment": func(s string) error {
+		_, _, err := calc.ParseCommitment(s)
+		return err
+	},
+	"part-time": func(s string) error {
+		_, _, err := calc.ParsePartTime(s)
+		return err
+	},
+}
+
+// apiSchema is schema.json, parsed once; its patterns are compiled up front
+// so a broken schema fails at startup rather than on a request.
+var apiSchema = func() *jsonSchema {
+	var s jsonSchema
+	if err := json.Unmarshal(schemaJSON, &s); err != nil {
+		panic("schema.json: " + err.Error()) // embedded at build time
+	}
+	var compile func(*jsonSchema)
+	compile = func(s *jsonSchema) {
+		if s == nil {
+			return
+		}
+		if s.Pattern != "" {
+			s.re = regexp.MustCompile(s.Pattern)
+		}
+		if _, ok := schemaFormats[s.Format]; s.Format != "" && !ok {
+			panic("schema.json: unknown format " + s.Format)
+		}
+		for _, sub := range s.Defs {
+			compile(sub)
+		}
+		for _, sub := range s.Properties {
+			compile(sub)
+		}
+		compile(s.Items)
+	}
+	compile(&s)
+	return &s
+}()
+
+// schemaError is one violation: the JSON Pointer of the value and what is wrong with it.
+type schemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e schemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// schemaErrors are all violations of one document: missing fields first,
+// then the others by key.
+type schemaErrors []schemaError
+
+func (es schemaErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validateJSON checks data against the named $defs entry of schema.json.
+// Paths are JSON Pointers under prefix, e.g. "/3/length" for prefix "/3".
+func validateJSON(def string, data []byte, prefix string) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var errs schemaErrors
+	apiSchema.validate(apiSchema.Defs[def], v, prefix, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (root *jsonSchema) validate(s *jsonSchema, v any, path string, errs *schemaErrors) {
+	if s.Ref != "" {
+		s = root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, schemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.Type != "" && jsonType(v, s.Type) != s.Type {
+		fail("expected %s, got %s", s.Type, jsonType(v, s.Type))
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, schemaError{Path: path + "/" + pointerToken(name), Message: "is required"})
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			sub, ok := s.Properties[name]
+			switch {
+			case ok:
+				root.validate(sub, v[name], path+"/"+pointerToken(name), errs)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*errs = append(*errs, schemaError{Path: path + "/" + pointerToken(name), Message: "unknown field"})
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(v))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				root.validate(s.Items, item, path+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %s", fmtFloat(*s.Minimum))
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			fail("must be more than %s", fmtFloat(*s.ExclusiveMinimum))
+		}
+	case string:
+		if s.re != nil && !s.re.MatchString(v) {
+			fail("%q does not match %s", v, s.Pattern)
+		}
+		if check := schemaFormats[s.Format]; check != nil {
+			if err := check(v); err != nil {
+				fail("%v", err)
+			}
+		}
+	}
+}
+
+// jsonType is the JSON Schema type of v; want decides whether a whole
+// number is reported as an integer or a number.
+func jsonType(v any, want string) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if want == "integer" && v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+// pointerToken escapes a key for a JSON Pointer (RFC 6901).
+func pointerToken(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// schemaHandler serves /schema.json.
+func schemaHandler() http.Handler {
+	f := newEmbeddedFile("schema.json", schemaJSON, "public, max-age=86400")
+	f.contentType = "application/schema+json"
+	return f
+}
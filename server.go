@@ -0,0 +1,314 @@
+package nightrelcalc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// How long in-flight requests get to finish after SIGINT/SIGTERM.
+const shutdownTimeout = 15 * time.Second
+
+// listenOn opens the web UI listener. addr is either a TCP "host:port"
+// (":8484", "127.0.0.1:0") or "unix:/path/to.sock".
+func listenOn(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if path == "" {
+			return nil, fmt.Errorf("invalid --listen %q: missing socket path", addr)
+		}
+		// A socket file left over from an unclean exit would make Listen fail.
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(path)
+		}
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("listen %s: %w", addr, err)
+		}
+		return ln, nil
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid --listen %q, expected host:port or unix:/path", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", addr, err)
+	}
+	return ln, nil
+}
+
+func printListenAddrs(addr net.Addr, basePath string) {
+	fmt.Println("Listening on:")
+
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		fmt.Printf("  %s:%s (path %s/)\n\n", addr.Network(), addr.String(), basePath)
+		return
+	}
+	if !tcp.IP.IsUnspecified() {
+		fmt.Printf("  http://%s%s/\n\n", tcp.String(), basePath)
+		return
+	}
+
+	fmt.Printf("  http://127.0.0.1:%d%s/\n", tcp.Port, basePath)
+
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			ip, _, err := net.ParseCIDR(a.String())
+			if err != nil || ip == nil || ip.IsLoopback() || ip.To4() == nil {
+				continue
+			}
+			fmt.Printf("  http://%s:%d%s/\n", ip.String(), tcp.Port, basePath)
+		}
+	}
+	fmt.Println()
+}
+
+// WebOptions configure the web UI server (see NewHandler); start from
+// DefaultWebOptions.
+type WebOptions struct {
+	Server   ServerOptions
+	Headers  HeaderOptions
+	Branding BrandingOptions
+
+	BasePath string // e.g. "/nightrelcalc" when hosted under a subpath
+
+	TemplatesDir string // overrides for the built-in HTML templates
+	StaticDir    string // extra files served at /static/
+
+	CookieSecret string
+	Auth         string // user:pass for the whole site
+	AuthFile     string // htpasswd file for the whole site
+	AdminAuth    string
+	Metrics      bool
+	MetricsToken string // bearer token for /metrics, which then skips --auth
+
+	APIKeys     []string // keys accepted by /api/ (open when none)
+	APIKeysFile string
+	APIBatchMax int // requests per /api/v1/calc/batch call
+
+	// ResultCache is how many calculations the page keeps, with their
+	// rendered results partial, for the next view of the same URL; 0 for
+	// none. A --templates-dir results partial is cached too, so it may only
+	// show the calculation, not per-request fields such as CSRFToken.
+	ResultCache int
+
+	SlackSigningSecret string // enables /slack/command
+
+	RateLimit      int // requests per minute per client IP, 0 = off
+	RateBurst      int
+	TrustForwarded bool
+
+	Config Config        // from --config; its profiles are offered in the form
+	Notify NotifyOptions // the --notify-* flags; each plan submitted with the form is posted
+
+	Policy InputPolicy // --granularity and --strict for the form and the API
+}
+
+func (o *WebOptions) addFlags(fs *pflag.FlagSet) {
+	o.Server.addFlags(fs)
+	o.Headers.addFlags(fs)
+	o.Branding.addFlags(fs)
+	fs.StringVar(&o.TemplatesDir, "templates-dir", "", "Directory with page.html/error.html overriding the built-in templates")
+	fs.StringVar(&o.StaticDir, "static-dir", "", "Directory served at /static/ (a style.css there is applied to the page)")
+	fs.StringVar(&o.BasePath, "base-path", "", "Serve under this URL path prefix (e.g. /nightrelcalc) behind a reverse proxy")
+	fs.StringVar(&o.CookieSecret, "cookie-secret", "", "Secret for signing the remembered-defaults cookie (random per run if empty)")
+	fs.StringVar(&o.Auth, "auth", "", "Require basic auth (user:pass) for the web UI and API")
+	fs.StringVar(&o.AuthFile, "auth-file", "", "Require basic auth using an htpasswd file (bcrypt, {SHA} or plain entries)")
+	fs.StringVar(&o.AdminAuth, "admin-auth", "", "Enable /admin statistics behind basic auth (user:pass)")
+	fs.StringArrayVar(&o.APIKeys, "api-key", nil, "API key required for /api/ endpoints (repeatable)")
+	fs.StringVar(&o.APIKeysFile, "api-keys-file", "", "File with API keys for /api/ endpoints, one per line")
+	fs.IntVar(&o.APIBatchMax, "api-batch-max", 100, "Max requests in one /api/v1/calc/batch call")
+	fs.IntVar(&o.ResultCache, "result-cache", 1000, "Keep this many page calculations, rendered, for the next view of the same URL (0 = off)")
+	fs.StringVar(&o.SlackSigningSecret, "slack-signing-secret", "", "Serve the Slack slash command at /slack/command, verifying requests with this app signing secret")
+	fs.BoolVar(&o.Metrics, "metrics", true, "Serve Prometheus metrics at /metrics (behind --auth/--auth-file unless --metrics-token is set)")
+	fs.StringVar(&o.MetricsToken, "metrics-token", "", "Require this bearer token for /metrics instead of --auth, for a scraper")
+	fs.IntVar(&o.RateLimit, "rate-limit", 0, "Max requests per minute per client IP (0 = unlimited)")
+	fs.IntVar(&o.RateBurst, "rate-burst", 20, "Requests a client may burst above --rate-limit")
+	fs.BoolVar(&o.TrustForwarded, "trust-forwarded", false, "Take the client IP from X-Forwarded-For/X-Real-IP (only behind a reverse proxy)")
+}
+
+// DefaultWebOptions are the options of a plain --listen: the flag defaults.
+func DefaultWebOptions() WebOptions {
+	var o WebOptions
+	o.addFlags(pflag.NewFlagSet("web", pflag.ContinueOnError))
+	return o
+}
+
+// LoadConfig reads a --config file into o: the profiles, rosters and feeds,
+// and the smtp settings email notifications are sent with.
+func (o *WebOptions) LoadConfig(path string) error {
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		return err
+	}
+	o.Config = cfg
+	o.Notify.smtp = cfg.SMTP
+	return nil
+}
+
+// NewHandler returns the web UI and API for another server to mount:
+//
+//	opts := nightrelcalc.DefaultWebOptions()
+//	opts.BasePath = "/nightrelcalc"
+//	h, err := nightrelcalc.NewHandler(opts)
+//	...
+//	mux.Handle("/nightrelcalc/", h)
+//
+// It is what --listen serves, with its request body limit; the timeouts of
+// opts.Server are the embedding server's to set.
+func NewHandler(opts WebOptions) (http.Handler, error) {
+	opts.BasePath = normalizeBasePath(opts.BasePath)
+	h, err := newWebHandler(opts)
+	if err != nil {
+		return nil, err
+	}
+	return limitBody(opts.Server.MaxBodyBytes, h), nil
+}
+
+// normalizeBasePath returns "" for the root or "/prefix" without a trailing slash.
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// withBasePath serves h under base: the prefix is stripped before routing and
+// the bare prefix redirects to "prefix/". Requests outside the prefix get 404.
+func withBasePath(base string, h http.Handler) http.Handler {
+	if base == "" {
+		return h
+	}
+	stripped := http.StripPrefix(base, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == base {
+			http.Redirect(w, r, base+"/", http.StatusMovedPermanently)
+			return
+		}
+		stripped.ServeHTTP(w, r)
+	})
+}
+
+// ServerOptions are the http.Server timeouts and request size limits.
+type ServerOptions struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+}
+
+func (o *ServerOptions) addFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.ReadHeaderTimeout, "read-header-timeout", 5*time.Second, "Max time to read request headers")
+	fs.DurationVar(&o.ReadTimeout, "read-timeout", 15*time.Second, "Max time to read a full request")
+	fs.DurationVar(&o.WriteTimeout, "write-timeout", 30*time.Second, "Max time to write a response")
+	fs.DurationVar(&o.IdleTimeout, "idle-timeout", 60*time.Second, "Max keep-alive idle time between requests")
+	fs.IntVar(&o.MaxHeaderBytes, "max-header-bytes", 16<<10, "Max size of request headers in bytes")
+	fs.Int64Var(&o.MaxBodyBytes, "max-body-bytes", 64<<10, "Max size of a request body in bytes")
+}
+
+// withDeadline gives each request's context the deadline d after it starts,
+// when its response could no longer be written anyway; 0 for none. A route
+// that streams opts out with withoutDeadline.
+func withDeadline(d time.Duration, h http.Handler) http.Handler {
+	if d <= 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), undeadlinedKey{}, r.Context()), d)
+		defer cancel()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// undeadlinedKey is the request context before withDeadline.
+type undeadlinedKey struct{}
+
+// withoutDeadline serves h without the deadline of withDeadline: its
+// requests end only when the client goes or the server shuts down. They
+// keep the values the handlers in between set.
+func withoutDeadline(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent, ok := r.Context().Value(undeadlinedKey{}).(context.Context)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		defer cancel()
+		defer context.AfterFunc(parent, cancel)()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// limitBody caps request bodies at max bytes; reads past it fail and the form parse returns 400.
+func limitBody(max int64, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if max > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// runServer serves h on ln until SIGINT/SIGTERM or ctx is cancelled, then
+// stops accepting connections and waits for in-flight requests before
+// returning. Those still running after shutdownTimeout have their contexts
+// cancelled, as does every request at its write timeout, so long batches
+// and outbound calls stop instead of running on unseen; streams are exempt
+// (see withoutDeadline).
+func runServer(ctx context.Context, ln net.Listener, opts ServerOptions, h http.Handler) error {
+	base, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+	srv := &http.Server{
+		Handler:           limitBody(opts.MaxBodyBytes, withDeadline(opts.WriteTimeout, h)),
+		BaseContext:       func(net.Listener) context.Context { return base },
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process immediately
+
+	fmt.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		cancelRequests()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package nightrelcalc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWithoutDeadline checks that a route opting out of withDeadline keeps
+// its request going, with the context values set on the way to it, and
+// that the others are cancelled.
+func TestWithoutDeadline(t *testing.T) {
+	type key struct{}
+	var err error
+	var value any
+	serve := func(h http.Handler) {
+		t.Helper()
+		set := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key{}, "set")))
+		})
+		withDeadline(10*time.Millisecond, set).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	wait := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		err, value = r.Context().Err(), r.Context().Value(key{})
+	})
+
+	serve(wait)
+	if err == nil {
+		t.Errorf("a request past the deadline is not cancelled")
+	}
+	serve(withoutDeadline(wait))
+	if err != nil || value != "set" {
+		t.Errorf("a request without the deadline: %v, value %v", err, value)
+	}
+}
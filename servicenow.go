@@ -0,0 +1,181 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push servicenow: attach the plan to a change request ---------------- */
+
+// ServiceNowConfig is the "servicenow" section of the config file; either a
+// user (basic auth) or an OAuth access token:
+//
+//	"servicenow": {"instance": "https://acme.service-now.com", "username": "nightrelcalc", "password": "..."}
+type ServiceNowConfig struct {
+	Instance string `json:"instance"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+func (c *ServiceNowConfig) validate() error {
+	if c.Instance == "" {
+		return errors.New("servicenow: instance is required")
+	}
+	if (c.Username == "") == (c.Token == "") {
+		return errors.New("servicenow: give either username/password or token")
+	}
+	return nil
+}
+
+// servicenowChange is a change request number such as CHG0030001.
+var servicenowChange = regexp.MustCompile(`^CHG[0-9]+$`)
+
+// send sends body (none if nil) as contentType and decodes the "result" of
+// the answer into out (unless nil).
+func (c *ServiceNowConfig) send(ctx context.Context, method, path, contentType string, body []byte, out any) error {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.Instance, "/")+path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	if out == nil {
+		return doJSON(req, nil)
+	}
+	var wrapped struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := doJSON(req, &wrapped); err != nil {
+		return err
+	}
+	return json.Unmarshal(wrapped.Result, out)
+}
+
+// servicenowPlanned are the change request's planned start and end: GMT,
+// as the Table API takes them without display values.
+func servicenowPlanned(r datedResult) (map[string]string, error) {
+	start, end, err := releaseWindow(r)
+	if err != nil {
+		return nil, err
+	}
+	const layout = "2006-01-02 15:04:05"
+	return map[string]string{"start_date": start.UTC().Format(layout), "end_date": end.UTC().Format(layout)}, nil
+}
+
+func newPushServiceNowCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts     pushOptions
+		change   string
+		noFields bool
+	)
+	cmd := &cobra.Command{
+		Use:   "servicenow",
+		Short: "Attach the plan to a ServiceNow change request and set its planned dates",
+		Long: "Attach the plan (as text and as an ICS calendar) to a change request and\n" +
+			"set its planned start and end dates to the release window. Needs a\n" +
+			"\"servicenow\" section in the config file.\n\n" +
+			"  nightrelcalc push servicenow --change CHG0030001 --start 22:00 --length 3",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			if !servicenowChange.MatchString(change) {
+				return fmt.Errorf("--change must be a change request number such as CHG0030001, got %q", change)
+			}
+			var fields map[string]string
+			if !noFields {
+				if fields, err = servicenowPlanned(r); err != nil {
+					return err
+				}
+			}
+			var text, ics bytes.Buffer
+			writeCLI(&text, r.Result)
+			if err := writeICS(&ics, []datedResult{r}, appClock.Now()); err != nil {
+				return err
+			}
+			name := "nightrelcalc-plan-" + r.Date
+			if opts.calc.DryRun {
+				fmt.Printf("Would attach %s.txt and %s.ics to %s:\n\n%s", name, name, change, text.String())
+				if fields != nil {
+					return writeIndentedJSON(os.Stdout, fields)
+				}
+				return nil
+			}
+			if cfg.ServiceNow == nil {
+				return errors.New(`push servicenow needs a "servicenow" section in the config file`)
+			}
+			sn := cfg.ServiceNow
+
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			var found []struct {
+				SysID string `json:"sys_id"`
+			}
+			q := url.Values{"sysparm_query": {"number=" + change}, "sysparm_fields": {"sys_id"}, "sysparm_limit": {"1"}}
+			if err := sn.send(ctx, http.MethodGet, "/api/now/table/change_request?"+q.Encode(), "", nil, &found); err != nil {
+				return fmt.Errorf("servicenow: find %s: %w", change, err)
+			}
+			if len(found) == 0 {
+				return fmt.Errorf("servicenow: no change request %s", change)
+			}
+			id := found[0].SysID
+
+			for _, att := range []struct {
+				file, contentType string
+				data              []byte
+			}{
+				{name + ".txt", "text/plain", text.Bytes()},
+				{name + ".ics", "text/calendar", ics.Bytes()},
+			} {
+				q := url.Values{"table_name": {"change_request"}, "table_sys_id": {id}, "file_name": {att.file}}
+				if err := sn.send(ctx, http.MethodPost, "/api/now/attachment/file?"+q.Encode(), att.contentType, att.data, nil); err != nil {
+					return fmt.Errorf("servicenow: attach %s: %w", att.file, err)
+				}
+			}
+			if fields != nil {
+				b, err := json.Marshal(fields)
+				if err != nil {
+					return err
+				}
+				if err := sn.send(ctx, http.MethodPatch, "/api/now/table/change_request/"+url.PathEscape(id), "application/json", b, nil); err != nil {
+					return fmt.Errorf("servicenow: planned dates: %w", err)
+				}
+			}
+			fmt.Printf("%s/change_request.do?sys_id=%s\n", strings.TrimRight(sn.Instance, "/"), url.QueryEscape(id))
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&change, "change", "", "Change request number, e.g. CHG0030001")
+	cmd.Flags().BoolVar(&noFields, "no-fields", false, "Only attach the plan; leave the planned start and end dates alone")
+	return cmd
+}
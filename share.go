@@ -0,0 +1,161 @@
+package nightrelcalc
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- compact share tokens (/?s=...) ---------------- */
+
+// A state token packs the calculation params into a few bytes so a share
+// link survives ticketing systems that mangle long query strings. It is
+// stateless: version byte, presence flags, then uvarint minutes for start,
+// length and each present optional field (the scenario picks as a bitmask),
+// base64url encoded.
+const stateTokenVersion = 1
+
+// Optional fields in token order; the bit is their position.
+var stateTokenFields = []string{"combine", "full", "normal_start", "normal_end", "min_rest", "max_overtime", "scenarios", "commute"}
+
+var errStateToken = errors.New("invalid share token")
+
+// Clock fields are HH:MM, the rest hours.
+func isClockField(name string) bool {
+	return name == "start" || name == "normal_start" || name == "normal_end"
+}
+
+func tokenMinutes(name, v string) (uint64, error) {
+	if name == "scenarios" {
+		picks, err := parseScenarioList(v)
+		if err != nil {
+			return 0, err
+		}
+		var mask uint64
+		for _, n := range picks {
+			mask |= 1 << (n - 1)
+		}
+		return mask, nil
+	}
+	if isClockField(name) {
+		c, err := calc.ParseClock(v)
+		return uint64(time.Duration(c) / time.Minute), err
+	}
+	h, err := calc.ParseHours(v)
+	if err != nil || h < 0 || math.IsInf(h, 0) || math.IsNaN(h) {
+		return 0, errStateToken
+	}
+	return uint64(math.Round(h * 60)), nil
+}
+
+func tokenValue(name string, m uint64) (string, error) {
+	if name == "scenarios" {
+		var picks []int
+		for n := 1; n <= maxScenarioPick; n++ {
+			if m&(1<<(n-1)) != 0 {
+				picks = append(picks, n)
+			}
+		}
+		return formatScenarioList(picks), nil
+	}
+	if isClockField(name) {
+		if m >= 1440 {
+			return "", errStateToken
+		}
+		return calc.Offset(time.Duration(m) * time.Minute).String(), nil
+	}
+	return strconv.FormatFloat(math.Round(float64(m)/60*1e4)/1e4, 'f', -1, 64), nil
+}
+
+// encodeStateToken packs the calculation params of q; start and length are
+// required, and any other param than the token fields cannot be packed.
+func encodeStateToken(q url.Values) (string, error) {
+	for name := range q {
+		if name != "start" && name != "length" && !slices.Contains(stateTokenFields, name) {
+			return "", fmt.Errorf("%s cannot be packed in a share token", name)
+		}
+	}
+	b := []byte{stateTokenVersion, 0}
+	for _, name := range []string{"start", "length"} {
+		m, err := tokenMinutes(name, q.Get(name))
+		if err != nil {
+			return "", err
+		}
+		b = binary.AppendUvarint(b, m)
+	}
+	for i, name := range stateTokenFields {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		m, err := tokenMinutes(name, v)
+		if err != nil {
+			return "", err
+		}
+		b[1] |= 1 << i
+		b = binary.AppendUvarint(b, m)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeStateToken is the inverse of encodeStateToken.
+func decodeStateToken(s string) (url.Values, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) < 2 || b[0] != stateTokenVersion || b[1]>>len(stateTokenFields) != 0 {
+		return nil, errStateToken
+	}
+	flags, b := b[1], b[2:]
+	q := url.Values{}
+	next := func(name string) error {
+		m, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errStateToken
+		}
+		b = b[n:]
+		v, err := tokenValue(name, m)
+		if err != nil {
+			return err
+		}
+		q.Set(name, v)
+		return nil
+	}
+	for _, name := range []string{"start", "length"} {
+		if err := next(name); err != nil {
+			return nil, err
+		}
+	}
+	for i, name := range stateTokenFields {
+		if flags&(1<<i) == 0 {
+			continue
+		}
+		if err := next(name); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) != 0 {
+		return nil, errStateToken
+	}
+	return q, nil
+}
+
+// expandStateToken replaces a query carrying s=<token> with the params it
+// encodes, and the profile param next to it, which tokens leave out.
+func expandStateToken(q url.Values) (url.Values, error) {
+	s := q.Get("s")
+	if s == "" {
+		return q, nil
+	}
+	v, err := decodeStateToken(s)
+	if err == nil && q.Get("profile") != "" {
+		v.Set("profile", q.Get("profile"))
+	}
+	return v, err
+}
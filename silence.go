@@ -0,0 +1,111 @@
+package nightrelcalc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push pagerduty/opsgenie: silence alerts for the release ---------------- */
+
+// silenceWindow is the period to keep alerts quiet for and what to silence:
+// services, integrations, ..., whatever the paging service calls them.
+type silenceWindow struct {
+	Start, End  time.Time
+	Description string
+	Targets     []string
+}
+
+// alertSilencer is a paging service that can suppress alerts for a window.
+type alertSilencer interface {
+	defaultTargets() []string
+	// request is the API body for w, also what --dry-run prints.
+	request(w silenceWindow) any
+	// create sends the request and returns a link to (or the id of) the window.
+	create(ctx context.Context, body any) (string, error)
+}
+
+// silencerSpec describes one push subcommand built on an alertSilencer.
+type silencerSpec struct {
+	use, short, long string
+	target           string // flag name and noun, e.g. "service"
+	targetUsage      string
+	// load returns the silencer of cfg; false when cfg has no section for
+	// it, and then s is an empty one that only serves --dry-run.
+	load func(cfg Config) (s alertSilencer, ok bool)
+}
+
+// releaseWindow is when the release of r runs, on its date.
+func releaseWindow(r datedResult) (start, end time.Time, err error) {
+	events, err := planEvents(r)
+	if err != nil {
+		return start, end, err
+	}
+	if len(events) == 0 {
+		return start, end, errors.New("the plan has no scenarios")
+	}
+	return events[0].Start, events[0].End, nil
+}
+
+// releaseTitle names the release of r for a maintenance notice.
+func releaseTitle(r datedResult) string {
+	return fmt.Sprintf("Night release %s %s -> %s (%s)", r.Date, r.ReleaseStart, r.ReleaseEnd, r.ReleaseLen)
+}
+
+func newPushSilenceCommand(configPath, profileName *string, spec silencerSpec) *cobra.Command {
+	var (
+		opts    pushOptions
+		targets []string
+	)
+	cmd := &cobra.Command{
+		Use:   spec.use,
+		Short: spec.short,
+		Long:  spec.long,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			start, end, err := releaseWindow(r)
+			if err != nil {
+				return err
+			}
+			s, configured := spec.load(cfg)
+			if len(targets) == 0 {
+				targets = s.defaultTargets()
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("--%s is required (or set %s.%ss in the config)", spec.target, spec.use, spec.target)
+			}
+			body := s.request(silenceWindow{Start: start, End: end, Description: releaseTitle(r), Targets: targets})
+			if opts.calc.DryRun {
+				return writeIndentedJSON(os.Stdout, body)
+			}
+			if !configured {
+				return fmt.Errorf("push %s needs a %q section with a token in the config file", spec.use, spec.use)
+			}
+
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			link, err := s.create(ctx, body)
+			if err != nil {
+				return fmt.Errorf("%s: %w", spec.use, err)
+			}
+			fmt.Printf("Maintenance window %s -> %s for %d %s(s): %s\n",
+				start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), len(targets), spec.target, link)
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringArrayVar(&targets, spec.target, nil, spec.targetUsage)
+	return cmd
+}
@@ -0,0 +1,145 @@
+package nightrelcalc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- Slack slash command (/slack/command) ---------------- */
+
+// Slack signs each request; older ones are replays and refused.
+const slackMaxSkew = 5 * time.Minute
+
+const slackUsage = "Usage: `/nightrel <start> <length> [combine] [public]`, e.g. `/nightrel 22:00 3h` or `/nightrel 21:30 4.5 2 public`. " +
+	"The answer is only shown to you unless you add `public`."
+
+// verifySlackSignature checks X-Slack-Signature: v0=hex(HMAC-SHA256(secret,
+// "v0:<timestamp>:<body>")), see https://api.slack.com/authentication/verifying-requests-from-slack.
+func verifySlackSignature(secret string, h http.Header, body []byte, now time.Time) bool {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > slackMaxSkew || d < -slackMaxSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(h.Get("X-Slack-Signature")), []byte(want))
+}
+
+// parseSlashLength reads a release length as hours ("3", "3,5") or a
+// duration ("3h", "3h30m", "90m").
+func parseSlashLength(s string) (float64, error) {
+	h, err := calc.ParseHours(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid length %q, expected hours (e.g. 3, 3.5 or 3h30m)", s)
+	}
+	return h, nil
+}
+
+// parseSlashCommand reads the text after the command: start, length, an
+// optional combine (in hours, like length) and "public" to answer in the channel.
+func parseSlashCommand(text string) (req calcRequest, public bool, err error) {
+	var args []string
+	for _, f := range strings.Fields(text) {
+		if strings.EqualFold(f, "public") {
+			public = true
+			continue
+		}
+		args = append(args, f)
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return req, public, fmt.Errorf("expected a start and a length")
+	}
+	req.Start = args[0]
+	if req.Length, err = parseSlashLength(args[1]); err != nil {
+		return req, public, err
+	}
+	if len(args) == 3 {
+		c, err := parseSlashLength(args[2])
+		if err != nil {
+			return req, public, fmt.Errorf("invalid combine %q, expected hours", args[2])
+		}
+		req.Combine = &c
+	}
+	return req, public, nil
+}
+
+// slackCommandHandler answers the slash command with the scenarios in Block
+// Kit, ephemeral unless asked for "public", and a link to the result page.
+// Only requests signed with secret are served. Errors are answered as an
+// ephemeral message so the user sees what to fix.
+func slackCommandHandler(cfg Config, secret, basePath string, trustForwarded bool, stats *webStats, hooks NotifyOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if !verifySlackSignature(secret, r.Header, body, time.Now()) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		text := strings.TrimSpace(form.Get("text"))
+		if text == "" || strings.EqualFold(text, "help") {
+			writeSlackJSON(w, slackPayload{ResponseType: "ephemeral", Text: slackEscape.Replace(slackUsage)})
+			return
+		}
+		req, public, err := parseSlashCommand(text)
+		if err == nil {
+			req, err = req.under(cfg)
+		}
+		if err != nil {
+			writeSlackJSON(w, slackPayload{ResponseType: "ephemeral", Text: slackEscape.Replace(err.Error() + "\n" + slackUsage)})
+			return
+		}
+		res, err := req.compute()
+		if err != nil {
+			stats.record("", true)
+			writeSlackJSON(w, slackPayload{ResponseType: "ephemeral", Text: slackEscape.Replace(err.Error() + "\n" + slackUsage)})
+			return
+		}
+		stats.record(req.shareQuery(), false)
+
+		n := notice{Result: res, Link: requestOrigin(r, trustForwarded) + basePath + "/?" + req.shareQuery(), Source: "slack"}
+		hooks.postAsync(r.Context(), n)
+		msg := slackMessage(n)
+		msg.ResponseType = "ephemeral"
+		if public {
+			msg.ResponseType = "in_channel"
+		}
+		writeSlackJSON(w, msg)
+	}
+}
+
+// writeSlackJSON answers Slack, which shows any non-200 as a generic failure.
+func writeSlackJSON(w http.ResponseWriter, p slackPayload) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,59 @@
+package nightrelcalc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySlackSignature(t *testing.T) {
+	const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+	body := []byte("command=%2Fnightrel&text=22%3A00+3h&user_id=U2CERLKJA")
+	now := time.Unix(1_800_000_000, 0)
+	sign := func(secret, ts string, body []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + ts + ":"))
+		mac.Write(body)
+		return "v0=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	at := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+
+	for _, tc := range []struct {
+		name    string
+		ts, sig string // headers; "" leaves one out
+		body    []byte // what arrives, when not what was signed
+		want    bool
+	}{
+		{"valid", at(0), sign(secret, at(0), body), nil, true},
+		{"valid, 4 min old", at(-4 * time.Minute), sign(secret, at(-4*time.Minute), body), nil, true},
+		{"valid, clock 4 min behind", at(4 * time.Minute), sign(secret, at(4*time.Minute), body), nil, true},
+		{"tampered body", at(0), sign(secret, at(0), body), []byte("command=%2Fnightrel&text=23%3A00+3h&user_id=U2CERLKJA"), false},
+		{"tampered timestamp", at(-time.Minute), sign(secret, at(0), body), nil, false},
+		{"other secret", at(0), sign("another secret", at(0), body), nil, false},
+		{"v1 signature", at(0), "v1=" + sign(secret, at(0), body)[3:], nil, false},
+		{"expired, replayed", at(-6 * time.Minute), sign(secret, at(-6*time.Minute), body), nil, false},
+		{"too far ahead", at(6 * time.Minute), sign(secret, at(6*time.Minute), body), nil, false},
+		{"missing signature", at(0), "", nil, false},
+		{"missing timestamp", "", sign(secret, "", body), nil, false},
+		{"malformed timestamp", "soon", sign(secret, "soon", body), nil, false},
+	} {
+		h := http.Header{}
+		if tc.ts != "" {
+			h.Set("X-Slack-Request-Timestamp", tc.ts)
+		}
+		if tc.sig != "" {
+			h.Set("X-Slack-Signature", tc.sig)
+		}
+		got := body
+		if tc.body != nil {
+			got = tc.body
+		}
+		if ok := verifySlackSignature(secret, h, got, now); ok != tc.want {
+			t.Errorf("%s: verified %v, want %v", tc.name, ok, tc.want)
+		}
+	}
+}
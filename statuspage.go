@@ -0,0 +1,132 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/* ---------------- push statuspage: scheduled maintenance ---------------- */
+
+// StatuspageConfig is the "statuspage" section of the config file:
+//
+//	"statuspage": {"token": "...", "page_id": "abc123", "components": ["cmp1"]}
+type StatuspageConfig struct {
+	Token      string   `json:"token"`
+	PageID     string   `json:"page_id"`
+	Components []string `json:"components,omitempty"` // component ids used when --component is not given
+	APIURL     string   `json:"api_url,omitempty"`    // default https://api.statuspage.io
+}
+
+func (c *StatuspageConfig) validate() error {
+	if c.Token == "" || c.PageID == "" {
+		return errors.New("statuspage: token and page_id are required")
+	}
+	return nil
+}
+
+// statuspageIncident is a scheduled maintenance; Statuspage moves it to in
+// progress and completed on its own at the window's start and end.
+type statuspageIncident struct {
+	Name                 string    `json:"name"`
+	Status               string    `json:"status"` // scheduled
+	Body                 string    `json:"body,omitempty"`
+	ScheduledFor         time.Time `json:"scheduled_for"`
+	ScheduledUntil       time.Time `json:"scheduled_until"`
+	RemindPrior          bool      `json:"scheduled_remind_prior"`
+	AutoInProgress       bool      `json:"scheduled_auto_in_progress"`
+	AutoCompleted        bool      `json:"scheduled_auto_completed"`
+	ComponentIDs         []string  `json:"component_ids,omitempty"`
+	DeliverNotifications bool      `json:"deliver_notifications"`
+}
+
+func newPushStatuspageCommand(configPath, profileName *string) *cobra.Command {
+	var (
+		opts        pushOptions
+		components  []string
+		title, body string
+		noNotify    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "statuspage",
+		Short: "Create a Statuspage scheduled maintenance for the release window",
+		Long: "Create a scheduled maintenance on a Statuspage page from the start to the\n" +
+			"end of the release, for the given components. Needs a \"statuspage\" section\n" +
+			"in the config file.\n\n" +
+			"  nightrelcalc push statuspage --component cmp1 --start 22:00 --length 3",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			r, err := opts.plan(cmd.Flags(), cfg, *profileName)
+			if err != nil {
+				return err
+			}
+			start, end, err := releaseWindow(r)
+			if err != nil {
+				return err
+			}
+			if len(components) == 0 && cfg.Statuspage != nil {
+				components = cfg.Statuspage.Components
+			}
+			inc := statuspageIncident{
+				Name:                 orDefault(title, "Scheduled maintenance "+start.Format("2006-01-02 15:04")+" - "+end.Format("15:04")),
+				Status:               "scheduled",
+				Body:                 body,
+				ScheduledFor:         start,
+				ScheduledUntil:       end,
+				RemindPrior:          true,
+				AutoInProgress:       true,
+				AutoCompleted:        true,
+				ComponentIDs:         components,
+				DeliverNotifications: !noNotify,
+			}
+			payload := map[string]statuspageIncident{"incident": inc}
+			if opts.calc.DryRun {
+				return writeIndentedJSON(os.Stdout, payload)
+			}
+			if cfg.Statuspage == nil {
+				return errors.New(`push statuspage needs a "statuspage" section with a token and page_id in the config file`)
+			}
+			sp := cfg.Statuspage
+
+			b, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := pushContext(cmd.Context())
+			defer cancel()
+			api := strings.TrimRight(orDefault(sp.APIURL, "https://api.statuspage.io"), "/")
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, api+"/v1/pages/"+url.PathEscape(sp.PageID)+"/incidents", bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "OAuth "+sp.Token)
+			var created struct {
+				Shortlink string `json:"shortlink"`
+			}
+			if err := doJSON(req, &created); err != nil {
+				return fmt.Errorf("statuspage: %w", err)
+			}
+			fmt.Printf("Scheduled maintenance %q: %s\n", inc.Name, created.Shortlink)
+			return nil
+		},
+	}
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().StringArrayVar(&components, "component", nil, "Affected component id (repeatable; default: statuspage.components of the config)")
+	cmd.Flags().StringVar(&title, "title", "", "Maintenance title (default: \"Scheduled maintenance\" and the window)")
+	cmd.Flags().StringVar(&body, "message", "", "Message shown with the maintenance")
+	cmd.Flags().BoolVar(&noNotify, "no-notify", false, "Do not notify the page's subscribers")
+	return cmd
+}
@@ -0,0 +1,255 @@
+package nightrelcalc
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- team mode: one result per person ---------------- */
+
+// maxTeamMembers keeps a team calculation (and its URL) a reasonable size.
+const maxTeamMembers = 20
+
+// teamFields are the form fields a team member can set for themselves; the
+// others are the release and the same for everyone. max_day, the longest
+// day of work and release they may have, has no form field.
+var teamFields = []string{"normal_start", "normal_end", "min_rest", "commute", "max_overtime", "part_time", "core_hours", "commitment", "max_day"}
+
+// teamMember is one line of the team field: a name, then the person's own
+// normal hours and limits, e.g.
+//
+//	Bob, 08:00-16:30, max_overtime=2, part_time=80%
+//
+// A bare HH:MM-HH:MM is the normal work day and tz=Europe/Berlin the time
+// zone of those hours for follow the sun; omitted fields are the form's.
+type teamMember struct {
+	Name   string
+	Fields map[string]string // by form field name
+	TZ     string            // IANA name; "" for the release's
+}
+
+// parseTeam reads the team field, one person per line; blank lines are
+// skipped.
+func parseTeam(s string) ([]teamMember, error) {
+	var team []teamMember
+	for i, line := range strings.Split(s, "\n") {
+		parts := strings.Split(line, ",")
+		name := strings.TrimSpace(parts[0])
+		if name == "" {
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("line %d: a name is required first", i+1)
+			}
+			continue
+		}
+		m := teamMember{Name: name, Fields: map[string]string{}}
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			key, val, ok := strings.Cut(p, "=")
+			if !ok {
+				start, end, err := calc.ParseClockRange(p)
+				if err != nil {
+					return nil, fmt.Errorf("line %d (%s): expected field=value or normal hours such as 08:00-16:30, got %q", i+1, name, p)
+				}
+				m.Fields["normal_start"], m.Fields["normal_end"] = start.String(), end.String()
+				continue
+			}
+			key = strings.TrimSpace(key)
+			if key == "tz" {
+				m.TZ = strings.TrimSpace(val)
+				if _, err := time.LoadLocation(m.TZ); err != nil {
+					return nil, fmt.Errorf("line %d (%s): unknown time zone %q, expected a name such as Europe/Berlin", i+1, name, m.TZ)
+				}
+				continue
+			}
+			if !slices.Contains(teamFields, key) {
+				return nil, fmt.Errorf("line %d (%s): unknown field %q, expected tz or one of %s", i+1, name, key, strings.Join(teamFields, ", "))
+			}
+			m.Fields[key] = strings.TrimSpace(val)
+		}
+		team = append(team, m)
+	}
+	if len(team) > maxTeamMembers {
+		return nil, fmt.Errorf("at most %d people, got %d", maxTeamMembers, len(team))
+	}
+	return team, nil
+}
+
+// teamResult is one person's result for the release of the form; Error is
+// set instead when their calculation fails. Under follow the sun, Segment
+// is their part of the release (nil when the others cover all of it).
+type teamResult struct {
+	Name    string
+	Zone    string // their time zone and its offset, e.g. "Asia/Kolkata, UTC+05:30"
+	Result  *calc.Result
+	Views   []scenarioView // the scenarios of Result, with UTC times
+	Error   string
+	Segment *handoffSegment
+
+	in formInput // their calculation: their fields, and their start and length
+}
+
+// teamResults computes the team field text for every person on top of the
+// form in; nil without a team. stored are the people's own rules by name
+// (see Config.personRules), under what their line sets. The release
+// starts in time zone tz ("" for the server's) and each person's result is
+// in their own. With handoff the release is split between them (see
+// handoffPlan). Problems come back as field errors, as one stops the whole
+// page.
+func teamResults(in formInput, text string, handoff bool, tz string, now time.Time, stored map[string]map[string]string, rules []calc.Rule, policy InputPolicy) ([]teamResult, []handoffSegment, fieldErrors) {
+	team, err := parseTeam(text)
+	if err != nil {
+		return nil, nil, fieldErrors{"team": err.Error()}
+	}
+	for _, m := range team {
+		for k, v := range stored[m.Name] {
+			if _, ok := m.Fields[k]; !ok {
+				m.Fields[k] = v
+			}
+		}
+	}
+	if handoff && len(team) == 0 {
+		return nil, nil, fieldErrors{"handoff": "needs a team to hand the release around, one person per line"}
+	}
+	loc, err := loadZone(tz)
+	if err != nil {
+		return nil, nil, fieldErrors{"tz": err.Error()}
+	}
+	members := make([]handoffMember, len(team))
+	for i, m := range team {
+		if members[i], err = newHandoffMember(m, in, loc); err != nil {
+			return nil, nil, fieldErrors{"team": err.Error()}
+		}
+	}
+	start, length := releaseInstant(now, in, loc)
+	if !handoff {
+		var out []teamResult
+		for i, m := range team {
+			r, err := computeMember(in, m, start.In(members[i].Loc), in.LengthH, rules, policy)
+			if err != nil {
+				return nil, nil, fieldErrors{"team": err.Error()}
+			}
+			out = append(out, r)
+		}
+		return out, nil, nil
+	}
+	segs := handoffPlan(members, start, length)
+	out := make([]teamResult, len(team))
+	for i, m := range team {
+		out[i] = teamResult{Name: m.Name, Zone: zoneLabel(start.In(members[i].Loc))}
+	}
+	for i, seg := range segs {
+		r, err := computeMember(in, team[seg.member], seg.From, seg.To.Sub(seg.From).Hours(), rules, policy)
+		if err != nil {
+			return nil, nil, fieldErrors{"team": err.Error()}
+		}
+		r.Segment = &segs[i]
+		out[seg.member] = r
+	}
+	return out, segs, nil
+}
+
+// computeMember runs the form in for m, with their own fields over those of
+// in and the release from start (in m's time zone) for lengthH hours: the
+// form's own in team mode, their part under follow the sun. An invalid
+// field of theirs is an error; a calculation that fails is the result's
+// Error.
+func computeMember(in formInput, m teamMember, start time.Time, lengthH float64, rules []calc.Rule, policy InputPolicy) (teamResult, error) {
+	mi, maxDayH, err := memberInput(in, m.Fields, policy)
+	if err != nil {
+		return teamResult{}, fmt.Errorf("%s: %w", m.Name, err)
+	}
+	mi.Start, mi.LengthH = start.Format("15:04"), lengthH
+	if lengthH != in.LengthH {
+		mi.Length = calc.FmtDuration(calc.Hours(lengthH))
+	}
+	r := teamResult{Name: m.Name, Zone: zoneLabel(start), in: mi}
+	res, err := mi.compute(rules)
+	if err == nil && policy.Strict {
+		err = strictAdjustments(res)
+	}
+	if err == nil && maxDayH > 0 {
+		err = calc.CheckMaxDay(res, maxDayH)
+	}
+	if err == nil {
+		err = selectScenarios(res, in.Picks)
+	}
+	if err != nil {
+		r.Error = err.Error()
+		return r, nil
+	}
+	r.Result = res
+	for _, s := range res.Scenarios {
+		r.Views = append(r.Views, scenarioView{Scenario: s, UTC: scenarioInUTC(s.Timeline, calc.DayOf(start))})
+	}
+	return r, nil
+}
+
+// memberInput is in with fields, a team member's (see teamFields), over
+// it, checked as the form is; maxDayH is their max_day, 0 for none.
+func memberInput(in formInput, fields map[string]string, policy InputPolicy) (mi formInput, maxDayH float64, err error) {
+	f := map[string]string{
+		"normal_start": in.NormalStart,
+		"normal_end":   in.NormalEnd,
+		"min_rest":     in.MinRest,
+		"commute":      in.Commute,
+		"max_overtime": in.MaxOvertime,
+		"part_time":    in.PartTime,
+		"core_hours":   in.CoreHours,
+		"commitment":   in.Commitment,
+	}
+	for k, v := range fields {
+		f[k] = v
+	}
+	mi, ferr := validateForm(in.Start, in.Length, in.Combine, f["normal_start"], f["normal_end"], f["min_rest"], f["commute"], f["max_overtime"], f["part_time"], f["core_hours"], f["commitment"], in.Scenarios)
+	if ferr == nil {
+		ferr = mi.checkGrid(policy.Granularity)
+	}
+	if ferr != nil {
+		bad := slices.Sorted(maps.Keys(ferr))
+		return mi, 0, fmt.Errorf("%s %s", strings.ToLower(fieldLabels[bad[0]]), ferr[bad[0]])
+	}
+	if s := f["max_day"]; s != "" {
+		if maxDayH, err = calc.ParseHours(s); err != nil || maxDayH <= 0 {
+			return mi, 0, errors.New("max day must be > 0 hours, e.g. 10 or 9h30m")
+		}
+	}
+	return mi, maxDayH, nil
+}
+
+// zoneLabel is the time zone of t and its offset then, e.g.
+// "Asia/Kolkata, UTC+05:30".
+func zoneLabel(t time.Time) string {
+	return t.Location().String() + ", UTC" + t.Format("-07:00")
+}
+
+// scenarioView is a scenario as the page shows it; UTC is set for a team
+// member, whose times are in their own time zone.
+type scenarioView struct {
+	calc.Scenario
+	UTC *scenarioUTC
+}
+
+// scenarioUTC are a scenario's blocks in UTC, as "Tue 20:00 → Wed 02:00";
+// WorkHours is "" when there is no work before the release.
+type scenarioUTC struct {
+	WorkHours, ReleaseWindow, NextDayHours string
+}
+
+// scenarioInUTC places tl on the (local) release day day.
+func scenarioInUTC(tl calc.Timeline, day calc.Anchor) *scenarioUTC {
+	at := func(o calc.Offset) time.Time { return day.Time(o).UTC() }
+	u := &scenarioUTC{
+		ReleaseWindow: fmtSpan(at(tl.ReleaseStart), at(tl.ReleaseEnd)),
+		NextDayHours:  fmtSpan(at(tl.NextStart), at(tl.NextEnd)),
+	}
+	if tl.WorkEnd > tl.WorkStart {
+		u.WorkHours = fmtSpan(at(tl.WorkStart), at(tl.WorkEnd))
+	}
+	return u
+}
@@ -0,0 +1,77 @@
+package nightrelcalc
+
+/* ---------------- Microsoft Teams Adaptive Card ---------------- */
+
+// teamsPayload is an incoming webhook message carrying one Adaptive Card.
+type teamsPayload struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+}
+
+// teamsElement is a TextBlock or a FactSet.
+type teamsElement struct {
+	Type      string      `json:"type"`
+	Text      string      `json:"text,omitempty"`
+	Size      string      `json:"size,omitempty"`
+	Weight    string      `json:"weight,omitempty"`
+	IsSubtle  bool        `json:"isSubtle,omitempty"`
+	Wrap      bool        `json:"wrap,omitempty"`
+	Separator bool        `json:"separator,omitempty"`
+	Facts     []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func teamsMessage(n notice) teamsPayload {
+	r := n.Result
+	body := []teamsElement{
+		{Type: "TextBlock", Text: n.title(), Size: "Large", Weight: "Bolder", Wrap: true},
+		{Type: "TextBlock", Text: "Length " + r.ReleaseLen + " · full day " + r.FullDay + " · min rest " + r.MinRest + " · max overtime " + r.MaxOvertime,
+			IsSubtle: true, Wrap: true},
+	}
+	for _, s := range r.Scenarios {
+		body = append(body,
+			teamsElement{Type: "TextBlock", Text: s.Title, Weight: "Bolder", Wrap: true, Separator: true},
+			teamsElement{Type: "FactSet", Facts: []teamsFact{
+				{"Work", s.WorkHours},
+				{"Total", s.TotalWork},
+				{"Overtime", s.Overtime + " (release included " + s.ReleaseIncluded + ")"},
+				{"Next day", s.NextDayHours},
+			}})
+	}
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    body,
+	}
+	if n.Link != "" {
+		card.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "Open in nightrelcalc", URL: n.Link}}
+	}
+	return teamsPayload{
+		Type:        "message",
+		Attachments: []teamsAttachment{{ContentType: "application/vnd.microsoft.card.adaptive", Content: card}},
+	}
+}
@@ -0,0 +1,80 @@
+package nightrelcalc
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Operator overrides: --templates-dir may hold page.html, print.html,
+// compare.html, bulk.html, availability.html, next.html and error.html that replace the built-in templates (same
+// PageData / CompareData / BulkData / AvailabilityData / NextData / ErrorData fields), and --static-dir is served at /static/; its style.css, if any, is linked after
+// the built-in styles.
+const (
+	pageTemplateFile         = "page.html"
+	errorTemplateFile        = "error.html"
+	printTemplateFile        = "print.html"
+	compareTemplateFile      = "compare.html"
+	bulkTemplateFile         = "bulk.html"
+	availabilityTemplateFile = "availability.html"
+	nextTemplateFile         = "next.html"
+	customCSSFile            = "style.css"
+
+	// page.html defines this partial for /results (live recalculation).
+	resultsTemplateName = "results"
+)
+
+// loadTemplate parses file from dir when it exists, else the built-in source.
+func loadTemplate(dir, file, builtin string) (*template.Template, error) {
+	src := builtin
+	if dir != "" {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		switch {
+		case err == nil:
+			src = string(b)
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+	tpl, err := template.New(file).Funcs(templateFuncs).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", file, err)
+	}
+	return tpl, nil
+}
+
+// customCSSURL returns the link to the operator stylesheet, or "" when the static dir has none.
+func customCSSURL(staticDir, basePath string) string {
+	if staticDir == "" {
+		return ""
+	}
+	if fi, err := os.Stat(filepath.Join(staticDir, customCSSFile)); err != nil || fi.IsDir() {
+		return ""
+	}
+	return basePath + "/static/" + customCSSFile
+}
+
+// filesOnly hides directories so the static file server never lists them.
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := file.Stat(); err == nil && fi.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return file, nil
+}
+
+func staticHandler(dir string) http.Handler {
+	return http.StripPrefix("/static/", http.FileServer(filesOnly{http.Dir(dir)}))
+}
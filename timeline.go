@@ -0,0 +1,148 @@
+package nightrelcalc
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- scenario timeline (inline SVG) ---------------- */
+
+// Timeline drawing size in SVG user units; the SVG scales to its container.
+const (
+	tlWidth     = 900
+	tlLaneH     = 26
+	tlLaneGap   = 10
+	tlPadX      = 8
+	tlPadTop    = 8
+	tlAxisH     = 22
+	tlMinLabelW = 46 // blocks narrower than this get no label
+)
+
+// Block colors; kept as attributes so the SVG stands alone (no CSS needed).
+var tlColors = map[string]string{
+	"work":    "#1976d2",
+	"release": "#e65100",
+	"rest":    "#bdbdbd",
+	"next":    "#2e7d32",
+}
+
+type tlBlock struct {
+	Kind     string // work, release, rest, next
+	Label    string
+	Lane     int
+	From, To calc.Offset
+	X, W     float64
+}
+
+type tlTick struct {
+	X     float64
+	Label string
+}
+
+type timelineLayout struct {
+	Width, Height float64
+	Blocks        []tlBlock
+	Ticks         []tlTick
+}
+
+// layoutTimeline places the work, release, rest and next-day blocks on two
+// lanes (what you work / what happens around the release) over a shared hour axis.
+func layoutTimeline(t calc.Timeline) timelineLayout {
+	blocks := []tlBlock{
+		{Kind: "work", Label: "Work", Lane: 0, From: t.WorkStart, To: t.WorkEnd},
+		{Kind: "next", Label: "Next day", Lane: 0, From: t.NextStart, To: t.NextEnd},
+		{Kind: "release", Label: "Release", Lane: 1, From: t.ReleaseStart, To: t.ReleaseEnd},
+		{Kind: "rest", Label: "Rest", Lane: 1, From: t.ReleaseEnd, To: t.NextStart},
+	}
+
+	// Whole hours around the blocks.
+	hour := func(o calc.Offset) calc.Offset {
+		return o.Midnight().Add(time.Duration(o.Clock()).Truncate(time.Hour))
+	}
+	from := hour(min(t.WorkStart, t.ReleaseStart))
+	to := -hour(-max(t.NextEnd, t.ReleaseEnd))
+	span := max(to.Sub(from), time.Hour)
+
+	minutes := func(d time.Duration) float64 { return float64(d / time.Minute) }
+	scale := float64(tlWidth-2*tlPadX) / minutes(span)
+	x := func(o calc.Offset) float64 { return tlPadX + minutes(o.Sub(from))*scale }
+
+	for i := range blocks {
+		b := &blocks[i]
+		b.X = x(b.From)
+		b.W = minutes(b.To.Sub(b.From)) * scale
+	}
+
+	step := time.Hour
+	for _, s := range []time.Duration{1, 2, 3, 4, 6} {
+		step = s * time.Hour
+		if span/step <= 12 {
+			break
+		}
+	}
+	var ticks []tlTick
+	for o := from; o <= to; o = o.Add(step) {
+		ticks = append(ticks, tlTick{X: x(o), Label: o.Clock().String()})
+	}
+
+	return timelineLayout{
+		Width:  tlWidth,
+		Height: tlPadTop + 2*tlLaneH + tlLaneGap + tlAxisH,
+		Blocks: blocks,
+		Ticks:  ticks,
+	}
+}
+
+// timelineSVG renders a scenario's timeline as inline SVG for the templates.
+func timelineSVG(s calc.Scenario) template.HTML {
+	l := layoutTimeline(s.Timeline)
+	esc := template.HTMLEscapeString
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="timeline" viewBox="0 0 %.0f %.0f" width="100%%" role="img" aria-label="%s" xmlns="http://www.w3.org/2000/svg" font-family="system-ui, sans-serif" font-size="11">`,
+		l.Width, l.Height, esc("Timeline: "+s.Title))
+
+	axisY := float64(tlPadTop + 2*tlLaneH + tlLaneGap)
+	for _, t := range l.Ticks {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.0f" stroke="#e0e0e0"/>`, t.X, tlPadTop, t.X, axisY+4)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" text-anchor="middle" fill="#666">%s</text>`, t.X, axisY+16, esc(t.Label))
+	}
+
+	for _, blk := range l.Blocks {
+		if blk.W <= 0 {
+			continue
+		}
+		y := tlPadTop + blk.Lane*(tlLaneH+tlLaneGap)
+		tip := fmt.Sprintf("%s: %s", blk.Label, calc.FmtRange(blk.From, blk.To))
+		fmt.Fprintf(&b, `<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" rx="4" fill="%s"/>`,
+			esc(tip), blk.X, y, blk.W, tlLaneH, tlColors[blk.Kind])
+		if blk.W >= tlMinLabelW {
+			fill := "#fff"
+			if blk.Kind == "rest" {
+				fill = "#333"
+			}
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="%s">%s</text>`,
+				blk.X+blk.W/2, y+tlLaneH/2+4, fill, esc(blk.Label))
+		}
+		b.WriteString(`</g>`)
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// templateFuncs are available to the built-in and operator templates.
+var templateFuncs = template.FuncMap{
+	"timeline":  timelineSVG,
+	"assetHash": assetHash,
+	"fieldLabel": func(name string) string {
+		return fieldLabels[name]
+	},
+	"view": func(s calc.Scenario) scenarioView {
+		return scenarioView{Scenario: s}
+	},
+}
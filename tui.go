@@ -0,0 +1,252 @@
+package nightrelcalc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- full-screen terminal UI ---------------- */
+
+// tuiFields are the form fields in tab order, as on the web form.
+var tuiFields = []string{"start", "length", "combine", "normal_start", "normal_end", "min_rest", "max_overtime"}
+
+const tuiPanelWidth = 44
+
+var (
+	tuiTitleStyle  = lipgloss.NewStyle().Bold(true)
+	tuiLabelStyle  = lipgloss.NewStyle().Width(20)
+	tuiErrorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	tuiMutedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiPanelStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1).Width(tuiPanelWidth)
+	tuiActivePanel = tuiPanelStyle.BorderForeground(lipgloss.Color("4"))
+)
+
+func newTUICommand(configPath, profileName *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Full-screen terminal form with live results",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFlag(*configPath)
+			if err != nil {
+				return err
+			}
+			p, err := cfg.lookupProfile(*profileName)
+			if err != nil {
+				return err
+			}
+			_, err = tea.NewProgram(newTUIModel(p, cfg.rules(p)), tea.WithAltScreen()).Run()
+			return err
+		},
+	}
+}
+
+type tuiModel struct {
+	inputs []textinput.Model
+	focus  int
+
+	res      *calc.Result
+	errs     fieldErrors
+	err      string
+	selected int // scenario panel the copy key takes
+
+	status string
+	width  int
+
+	rules []calc.Rule // the config's extra scenarios
+}
+
+func newTUIModel(p Profile, rules []calc.Rule) tuiModel {
+	normalStart, normalEnd, minRest, _, maxOvertime := p.formDefaults()
+	values := map[string]string{
+		"start":        webDefaultStart,
+		"length":       webDefaultLength,
+		"normal_start": normalStart,
+		"normal_end":   normalEnd,
+		"min_rest":     minRest,
+		"max_overtime": maxOvertime,
+	}
+	m := tuiModel{width: 80, rules: rules}
+	for i, name := range tuiFields {
+		in := textinput.New()
+		in.Prompt = ""
+		in.CharLimit = 8
+		in.Width = 10
+		in.SetValue(values[name])
+		if name == "combine" {
+			in.Placeholder = "optional"
+		}
+		if i == 0 {
+			in.Focus()
+		}
+		m.inputs = append(m.inputs, in)
+	}
+	m.recompute()
+	return m
+}
+
+func (m tuiModel) value(name string) string {
+	for i, f := range tuiFields {
+		if f == name {
+			return strings.TrimSpace(m.inputs[i].Value())
+		}
+	}
+	return ""
+}
+
+// recompute runs the calculation for the current fields, with the same
+// validation as the web form.
+func (m *tuiModel) recompute() {
+	m.res, m.err = nil, ""
+	in, errs := validateForm(m.value("start"), m.value("length"), m.value("combine"),
+		m.value("normal_start"), m.value("normal_end"), m.value("min_rest"), "", m.value("max_overtime"), "", "", "", "")
+	m.errs = errs
+	if errs != nil {
+		return
+	}
+	res, err := in.compute(m.rules)
+	if err != nil {
+		m.err = err.Error()
+		return
+	}
+	m.res = res
+	m.selected = max(min(m.selected, len(res.Scenarios)-1), 0)
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+		case "tab", "down", "enter":
+			return m, m.setFocus((m.focus + 1) % len(m.inputs))
+		case "shift+tab", "up":
+			return m, m.setFocus((m.focus + len(m.inputs) - 1) % len(m.inputs))
+		case "ctrl+n", "pgdown":
+			if m.res != nil && len(m.res.Scenarios) > 0 {
+				m.selected = (m.selected + 1) % len(m.res.Scenarios)
+			}
+			return m, nil
+		case "ctrl+p", "pgup":
+			if m.res != nil && len(m.res.Scenarios) > 0 {
+				m.selected = (m.selected + len(m.res.Scenarios) - 1) % len(m.res.Scenarios)
+			}
+			return m, nil
+		case "ctrl+y":
+			if m.res == nil {
+				m.status = "Nothing to copy, fix the fields first."
+				return m, nil
+			}
+			var b strings.Builder
+			writeCLI(&b, m.res)
+			m.status = "Copied all scenarios."
+			return m, copyToClipboard(b.String())
+		case "ctrl+e":
+			if m.res == nil || len(m.res.Scenarios) == 0 {
+				m.status = "Nothing to copy, fix the fields first."
+				return m, nil
+			}
+			var b strings.Builder
+			writeScenario(&b, m.res.Scenarios[m.selected])
+			m.status = "Copied " + m.res.Scenarios[m.selected].Title + "."
+			return m, copyToClipboard(b.String())
+		}
+	}
+
+	var cmd tea.Cmd
+	before := m.inputs[m.focus].Value()
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	if m.inputs[m.focus].Value() != before {
+		m.status = ""
+		m.recompute()
+	}
+	return m, cmd
+}
+
+func (m *tuiModel) setFocus(i int) tea.Cmd {
+	m.inputs[m.focus].Blur()
+	m.focus = i
+	return m.inputs[i].Focus()
+}
+
+// copyToClipboard sets the terminal clipboard with OSC 52, which works over
+// SSH and, with set-clipboard on, inside tmux.
+func copyToClipboard(s string) tea.Cmd {
+	return func() tea.Msg {
+		fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(s)))
+		return nil
+	}
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+	b.WriteString(tuiTitleStyle.Render("nightrelcalc v"+appVersion) + "\n\n")
+	for i, name := range tuiFields {
+		line := tuiLabelStyle.Render(fieldLabels[name]) + m.inputs[i].View()
+		if msg := m.errs[name]; msg != "" {
+			line += "  " + tuiErrorStyle.Render(msg)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+
+	switch {
+	case m.err != "":
+		b.WriteString(tuiErrorStyle.Render(m.err) + "\n")
+	case m.res != nil:
+		r := m.res
+		fmt.Fprintf(&b, "Release %s -> %s (len %s) · full day %s · min rest %s · max OT %s\n",
+			r.ReleaseStart, r.ReleaseEnd, r.ReleaseLen, r.FullDay, r.MinRest, r.MaxOvertime)
+		b.WriteString(m.panels() + "\n")
+	}
+
+	b.WriteString("\n" + tuiMutedStyle.Render("tab/↑↓ move · ctrl+n/p select scenario · ctrl+e copy scenario · ctrl+y copy all · esc quit"))
+	if m.status != "" {
+		b.WriteString("\n" + m.status)
+	}
+	return b.String()
+}
+
+// panels lays the scenarios out side by side, as many per row as fit.
+func (m tuiModel) panels() string {
+	perRow := max(m.width/(tuiPanelWidth+4), 1)
+	var rows, row []string
+	for i, s := range m.res.Scenarios {
+		style := tuiPanelStyle
+		if i == m.selected {
+			style = tuiActivePanel
+		}
+		row = append(row, style.Render(tuiTitleStyle.Render(s.Title)+"\n"+
+			"Work      "+s.WorkHours+"\n"+
+			"Release   "+s.ReleaseWindow+"\n"+
+			"Total     "+s.TotalWork+"\n"+
+			"Included  "+s.ReleaseIncluded+"\n"+
+			"Overtime  "+s.Overtime+"\n"+
+			"Next day  "+s.NextDayHours))
+		if len(row) == perRow {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
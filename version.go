@@ -0,0 +1,90 @@
+package nightrelcalc
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+/* ---------------- build metadata (--version, /version) ---------------- */
+
+// buildCommit and buildDate are set by the release build, e.g.
+//
+//	go build -ldflags "-X nightrelcalc.buildCommit=$(git rev-parse HEAD) -X nightrelcalc.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/nightrelcalc
+//
+// Without them they come from the VCS stamp go build records in a git
+// checkout; a go install of a module version has neither.
+var (
+	buildCommit string
+	buildDate   string
+)
+
+// buildInfo identifies the exact build, for bug reports.
+type buildInfo struct {
+	Version  string `json:"version"`
+	Commit   string `json:"commit,omitempty"`
+	Modified bool   `json:"modified,omitempty"` // built from a checkout with uncommitted changes
+	Date     string `json:"date,omitempty"`     // of the build, or else of the commit; RFC 3339
+	Go       string `json:"go"`                 // e.g. go1.26.0
+	Platform string `json:"platform"`           // e.g. linux/amd64
+}
+
+var appBuild = sync.OnceValue(func() buildInfo {
+	b := buildInfo{Version: appVersion, Commit: buildCommit, Date: buildDate, Go: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if b.Commit == "" {
+				b.Commit = s.Value
+			}
+		case "vcs.time":
+			if b.Date == "" {
+				b.Date = s.Value
+			}
+		case "vcs.modified":
+			b.Modified = s.Value == "true" && buildCommit == ""
+		}
+	}
+	return b
+})
+
+// String is the --version line, e.g.
+// "nightrelcalc v0.1.11 (commit 1a2b3c4d5e6f, built 2026-10-14T13:08:19Z, go1.26.0 linux/amd64)".
+func (b buildInfo) String() string {
+	var parts []string
+	if c := b.Commit; c != "" {
+		if len(c) > 12 {
+			c = c[:12]
+		}
+		if b.Modified {
+			c += "-modified"
+		}
+		parts = append(parts, "commit "+c)
+	}
+	switch {
+	case buildDate != "":
+		parts = append(parts, "built "+b.Date)
+	case b.Date != "":
+		parts = append(parts, "committed "+b.Date)
+	}
+	parts = append(parts, b.Go+" "+b.Platform)
+	return "nightrelcalc v" + b.Version + " (" + strings.Join(parts, ", ") + ")"
+}
+
+// versionHandler serves GET /version: the build as JSON (see buildInfo).
+func versionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET")
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, appBuild())
+	}
+}
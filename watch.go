@@ -0,0 +1,96 @@
+package nightrelcalc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- --watch countdown ---------------- */
+
+// releaseDay is the day the release runs: today, or tomorrow when today's
+// window is already over (a 01:00 start seen at 20:00).
+func releaseDay(now time.Time, tl calc.Timeline) calc.Anchor {
+	day := calc.DayOf(now)
+	if now.After(day.Time(tl.ReleaseEnd)) {
+		day = calc.DayOf(day.Date().AddDate(0, 0, 1))
+	}
+	return day
+}
+
+// countdown is "in 2h15m", "2h15m ago" or "now", to the minute.
+func countdown(now, at time.Time) string {
+	d := at.Truncate(time.Minute).Sub(now.Truncate(time.Minute))
+	switch {
+	case d > 0:
+		return "in " + calc.FmtDuration(d)
+	case d < 0:
+		return calc.FmtDuration(d) + " ago"
+	}
+	return "now"
+}
+
+// writeWatch writes one countdown screen for res at now.
+func writeWatch(w io.Writer, res *calc.Result, day calc.Anchor, now time.Time) {
+	at := day.Time
+	tl := res.Scenarios[0].Timeline
+
+	fmt.Fprintf(w, "Now %s, release %s -> %s (len %s)\n\n", now.Format("15:04"), res.ReleaseStart, res.ReleaseEnd, res.ReleaseLen)
+	fmt.Fprintf(w, "  Release starts:          %s\n", countdown(now, at(tl.ReleaseStart)))
+	fmt.Fprintf(w, "  Release ends:            %s\n", countdown(now, at(tl.ReleaseEnd)))
+	fmt.Fprintf(w, "  Mandatory rest ends:     %s, at %s\n\n", countdown(now, at(tl.RestEnd)), tl.RestEnd)
+	for _, s := range res.Scenarios {
+		fmt.Fprintln(w, s.Title)
+		fmt.Fprintf(w, "  Work starts:             %s, at %s\n", countdown(now, at(s.Timeline.WorkStart)), s.Timeline.WorkStart)
+		fmt.Fprintf(w, "  Next day work starts:    %s, at %s\n\n", countdown(now, at(s.Timeline.NextStart)), s.Timeline.NextStart)
+	}
+}
+
+// watchRelease redraws the countdown at every minute until the mandatory rest
+// is over or the user interrupts. On a terminal the screen is cleared between
+// updates; otherwise the updates follow each other. It also ends, quietly,
+// when ctx is cancelled.
+func watchRelease(ctx context.Context, res *calc.Result) error {
+	if len(res.Scenarios) == 0 {
+		return fmt.Errorf("nothing to watch: no scenarios")
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	fi, _ := os.Stdout.Stat()
+	tty := fi != nil && fi.Mode()&os.ModeCharDevice != 0
+	day := releaseDay(appClock.Now(), res.Scenarios[0].Timeline)
+	restEnd := day.Time(res.Scenarios[0].Timeline.RestEnd)
+
+	for {
+		now := appClock.Now()
+		var b strings.Builder
+		if tty {
+			b.WriteString("\x1b[H\x1b[2J")
+		}
+		writeWatch(&b, res, day, now)
+		if !now.Before(restEnd) {
+			b.WriteString("Rest is over, nothing left to count down.\n")
+			_, err := io.WriteString(os.Stdout, b.String())
+			return err
+		}
+		if tty {
+			b.WriteString("Ctrl+C to stop\n")
+		}
+		if _, err := io.WriteString(os.Stdout, b.String()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(now.Truncate(time.Minute).Add(time.Minute).Sub(now)):
+		}
+	}
+}
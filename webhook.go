@@ -0,0 +1,56 @@
+package nightrelcalc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+/* ---------------- generic outbound webhooks ---------------- */
+
+// webhookSignatureHeader carries "sha256=" + hex(HMAC-SHA256(secret, body)).
+// The body holds the time of the calculation, so receivers can also refuse
+// old deliveries.
+const webhookSignatureHeader = "X-Nightrelcalc-Signature-256"
+
+// webhookEvent is the JSON body POSTed to each --webhook URL.
+type webhookEvent struct {
+	Event  string       `json:"event"`  // "calculation"
+	Source string       `json:"source"` // cli, web, api or slack
+	Time   time.Time    `json:"time"`
+	Link   string       `json:"link,omitempty"`   // result page, when the web UI knows it
+	Person string       `json:"person,omitempty"` // the team member it is for, in team mode
+	Result *calc.Result `json:"result"`
+}
+
+// webhookSignature signs body for webhookSignatureHeader.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook delivers n to url, signed when secret is set.
+func postWebhook(ctx context.Context, url, secret string, n notice) error {
+	body, err := json.Marshal(webhookEvent{Event: "calculation", Source: n.Source, Time: time.Now().UTC(), Link: n.Link, Person: n.Person, Result: n.Result})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nightrelcalc/"+appVersion)
+	if secret != "" {
+		req.Header.Set(webhookSignatureHeader, webhookSignature(secret, body))
+	}
+	return doJSON(req, nil)
+}
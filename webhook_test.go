@@ -0,0 +1,95 @@
+package nightrelcalc
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nightrelcalc/calc"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	// The well-known HMAC-SHA256 example, as a receiver would compute it
+	const want = "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got := webhookSignature("key", []byte("The quick brown fox jumps over the lazy dog")); got != want {
+		t.Errorf("webhookSignature = %s, want %s", got, want)
+	}
+}
+
+// TestPostWebhook checks what a receiver gets: a signature over the exact
+// body it reads, none without a secret, and the time to refuse old
+// deliveries by.
+func TestPostWebhook(t *testing.T) {
+	res, err := calc.Compute("22:00", 3, 0, 0, "09:00", "17:30", 11, 0, 4, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan delivery, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got <- delivery{r.Header, b}
+	}))
+	defer srv.Close()
+	deliver := func(secret string) delivery {
+		t.Helper()
+		if err := postWebhook(context.Background(), srv.URL, secret, notice{Result: res, Source: "cli", Person: "Ann"}); err != nil {
+			t.Fatal(err)
+		}
+		return <-got
+	}
+
+	d := deliver("s3cret")
+	sig := d.header.Get(webhookSignatureHeader)
+	tampered := append([]byte(nil), d.body...)
+	tampered[len(tampered)-2] ^= 1
+	for _, tc := range []struct {
+		name   string
+		secret string
+		body   []byte
+		sig    string
+		want   bool
+	}{
+		{"valid", "s3cret", d.body, sig, true},
+		{"tampered body", "s3cret", tampered, sig, false},
+		{"other secret", "other", d.body, sig, false},
+		{"missing signature", "s3cret", d.body, "", false},
+	} {
+		if ok := hmac.Equal([]byte(tc.sig), []byte(webhookSignature(tc.secret, tc.body))); ok != tc.want {
+			t.Errorf("%s: verified %v, want %v", tc.name, ok, tc.want)
+		}
+	}
+
+	var ev webhookEvent
+	if err := json.Unmarshal(d.body, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Event != "calculation" || ev.Source != "cli" || ev.Person != "Ann" || ev.Result == nil {
+		t.Errorf("event %+v", ev)
+	}
+	if age := time.Since(ev.Time); age < 0 || age > time.Minute {
+		t.Errorf("event time %v is %v old, expired for a receiver", ev.Time, age)
+	}
+
+	if d := deliver(""); d.header.Get(webhookSignatureHeader) != "" {
+		t.Errorf("signed without a secret: %q", d.header.Get(webhookSignatureHeader))
+	}
+}
+
+func TestPostWebhookRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	if err := postWebhook(context.Background(), srv.URL, "s3cret", notice{Source: "cli"}); err == nil {
+		t.Errorf("a refused delivery reports no error")
+	}
+}